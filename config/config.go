@@ -2,9 +2,13 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
+	"log"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
@@ -12,8 +16,9 @@ import (
 
 type Config struct {
 	App struct {
-		Name string
-		Port string
+		Name        string
+		Port        string
+		FrontendURL string
 	}
 	Logger struct {
 		Formatter logrus.Formatter
@@ -29,10 +34,86 @@ type Config struct {
 	AES struct {
 		SecretKey string
 	}
+	Encryption struct {
+		Version               string
+		SecretKey             string
+		ActiveKeyID           string
+		Keys                  map[string]string
+		ReencryptionInterval  time.Duration
+		ReencryptionBatchSize int
+		KeyManager            string
+		ActiveKeyFileID       string
+		KeyFiles              map[string]string
+		DataKeyCacheTTL       time.Duration
+		DataKeyCacheSize      int
+		BlindIndexKey         string
+	}
+	Vault struct {
+		Address      string
+		Token        string
+		TransitMount string
+		TransitKey   string
+	}
 	BasicAuth struct {
 		Username string
 		Password string
 	}
+	JWT struct {
+		Algorithm       string
+		PrivateKeyFile  string
+		PublicKeyFile   string
+		KeyDirectory    string
+		Issuer          string
+		Audience        string
+		Leeway          time.Duration
+		AccessTokenTTL  time.Duration
+		RefreshTokenTTL time.Duration
+	}
+	Mailer struct {
+		From      string
+		LocalFile string
+	}
+	Account struct {
+		PasswordResetTTL                time.Duration
+		EmailVerificationTTL            time.Duration
+		EmailVerificationResendInterval time.Duration
+		RequireVerifiedEmailToLogin     bool
+		RequireVerifiedEmailToPublish   bool
+		MFAChallengeTTL                 time.Duration
+		TOTPIssuer                      string
+		EmailChangeUndoTTL              time.Duration
+		DeletionGracePeriod             time.Duration
+		DeletionPurgeInterval           time.Duration
+		EncryptPersonalData             bool
+	}
+	LoginThrottle struct {
+		FreeAttempts           int64
+		BaseDelay              time.Duration
+		MaxDelay               time.Duration
+		LockoutThreshold       int64
+		LockoutDuration        time.Duration
+		Window                 time.Duration
+		ClientIPAttemptsFactor int64
+	}
+	Authz struct {
+		PermissionCacheTTL time.Duration
+	}
+	OIDC struct {
+		Issuer       string
+		ClientID     string
+		ClientSecret string
+		RedirectURL  string
+		Scopes       []string
+		StateTTL     time.Duration
+		Leeway       time.Duration
+	}
+	PasswordHasher struct {
+		Algorithm     string
+		BcryptCost    int
+		Argon2Time    uint32
+		Argon2Memory  uint32
+		Argon2Threads uint8
+	}
 	GlobalIV string
 }
 
@@ -42,7 +123,16 @@ func New() *Config {
 	c.loadMariadb()
 	c.loadRedis()
 	c.loadAes()
+	c.loadEncryption()
+	c.loadVault()
 	c.loadBasicAuth()
+	c.loadJWT()
+	c.loadMailer()
+	c.loadAccount()
+	c.loadLoginThrottle()
+	c.loadAuthz()
+	c.loadOIDC()
+	c.loadPasswordHasher()
 	c.loadGlobalIV()
 
 	return c
@@ -54,6 +144,7 @@ func (c *Config) loadApp() *Config {
 
 	c.App.Name = name
 	c.App.Port = port
+	c.App.FrontendURL = os.Getenv("APP_FRONTEND_URL")
 
 	return c
 }
@@ -70,6 +161,8 @@ func (c *Config) loadMariadb() *Config {
 	connVal := url.Values{}
 	connVal.Add("parseTime", "1")
 	connVal.Add("loc", "Asia/Jakarta")
+	// updates report the matched rows, so setting a column to the value it has is not mistaken for a missing row.
+	connVal.Add("clientFoundRows", "true")
 
 	dbConnectionString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", username, password, host, port, database)
 	dsn := fmt.Sprintf("%s?%s", dbConnectionString, connVal.Encode())
@@ -97,13 +190,106 @@ func (c *Config) loadRedis() *Config {
 	return c
 }
 
+// getSecret returns the content of the file named by "<key>_FILE" when it is set, else the variable itself.
+// A secret mounted as a file does not have to live in the environment.
+func getSecret(key string) string {
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return os.Getenv(key)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatalf("%s_FILE: %v", key, err)
+	}
+
+	return strings.TrimSpace(string(content))
+}
+
 func (c *Config) loadAes() *Config {
-	secretKey := os.Getenv("AES_SECRET_KEY")
+	secretKey := getSecret("AES_SECRET_KEY")
 	c.AES.SecretKey = secretKey
 
 	return c
 }
 
+func (c *Config) loadEncryption() *Config {
+	version := os.Getenv("ENCRYPTION_VERSION")
+	if version == "" {
+		version = "v1"
+	}
+	secretKey := getSecret("ENCRYPTION_SECRET_KEY")
+	if secretKey == "" {
+		secretKey = c.AES.SecretKey
+	}
+
+	// keys are "<key id>:<secret>" separated by comma, the secret key is the key of values without key id.
+	keys := map[string]string{}
+	for _, key := range strings.Split(os.Getenv("ENCRYPTION_KEYS"), ",") {
+		parts := strings.SplitN(strings.TrimSpace(key), ":", 2)
+		if len(parts) == 2 {
+			keys[parts[0]] = parts[1]
+		}
+	}
+	if secretKey != "" {
+		keys[""] = secretKey
+	}
+
+	// zero disables the background re-encryption, it can still be run once with the -reencrypt flag.
+	reencryptionInterval, _ := time.ParseDuration(os.Getenv("REENCRYPTION_INTERVAL"))
+	reencryptionBatchSize, err := strconv.ParseInt(os.Getenv("REENCRYPTION_BATCH_SIZE"), 10, 64)
+	if err != nil || reencryptionBatchSize < 1 {
+		reencryptionBatchSize = 100
+	}
+
+	// key files are "<key id>:<path>" separated by comma, the key file is the key of envelopes without key id.
+	keyFiles := map[string]string{}
+	for _, keyFile := range strings.Split(os.Getenv("ENCRYPTION_KEY_FILES"), ",") {
+		parts := strings.SplitN(strings.TrimSpace(keyFile), ":", 2)
+		if len(parts) == 2 {
+			keyFiles[parts[0]] = parts[1]
+		}
+	}
+	if keyFile := os.Getenv("ENCRYPTION_KEY_FILE"); keyFile != "" {
+		keyFiles[""] = keyFile
+	}
+
+	dataKeyCacheTTL, err := time.ParseDuration(os.Getenv("ENCRYPTION_DATA_KEY_CACHE_TTL"))
+	if err != nil {
+		dataKeyCacheTTL = time.Minute * 5
+	}
+	dataKeyCacheSize, err := strconv.ParseInt(os.Getenv("ENCRYPTION_DATA_KEY_CACHE_SIZE"), 10, 64)
+	if err != nil || dataKeyCacheSize < 0 {
+		dataKeyCacheSize = 1000
+	}
+
+	c.Encryption.Version = version
+	c.Encryption.SecretKey = secretKey
+	c.Encryption.ActiveKeyID = os.Getenv("ENCRYPTION_ACTIVE_KEY_ID")
+	c.Encryption.Keys = keys
+	c.Encryption.ReencryptionInterval = reencryptionInterval
+	c.Encryption.ReencryptionBatchSize = int(reencryptionBatchSize)
+	// "file" or "vault" encrypts new values by envelopes, the key encryption key stays with the key manager.
+	c.Encryption.KeyManager = os.Getenv("ENCRYPTION_KEY_MANAGER")
+	c.Encryption.ActiveKeyFileID = os.Getenv("ENCRYPTION_ACTIVE_KEY_FILE_ID")
+	c.Encryption.KeyFiles = keyFiles
+	// zero disables the cache of unwrapped data keys, every decryption then asks the key manager.
+	c.Encryption.DataKeyCacheTTL = dataKeyCacheTTL
+	c.Encryption.DataKeyCacheSize = int(dataKeyCacheSize)
+	c.Encryption.BlindIndexKey = getSecret("ENCRYPTION_BLIND_INDEX_KEY")
+
+	return c
+}
+
+func (c *Config) loadVault() *Config {
+	c.Vault.Address = os.Getenv("VAULT_ADDR")
+	c.Vault.Token = getSecret("VAULT_TOKEN")
+	c.Vault.TransitMount = os.Getenv("VAULT_TRANSIT_MOUNT")
+	c.Vault.TransitKey = os.Getenv("VAULT_TRANSIT_KEY")
+
+	return c
+}
+
 func (c *Config) loadBasicAuth() *Config {
 	username := os.Getenv("BASIC_AUTH_USERNAME")
 	password := os.Getenv("BASIC_AUTH_PASSWORD")
@@ -114,6 +300,196 @@ func (c *Config) loadBasicAuth() *Config {
 	return c
 }
 
+func (c *Config) loadJWT() *Config {
+	accessTokenTTL, err := time.ParseDuration(os.Getenv("JWT_ACCESS_TOKEN_TTL"))
+	if err != nil {
+		accessTokenTTL = time.Minute * 15
+	}
+	refreshTokenTTL, err := time.ParseDuration(os.Getenv("JWT_REFRESH_TOKEN_TTL"))
+	if err != nil {
+		refreshTokenTTL = time.Hour * 24 * 30
+	}
+	leeway, err := time.ParseDuration(os.Getenv("JWT_LEEWAY"))
+	if err != nil {
+		leeway = time.Second * 30
+	}
+
+	algorithm := os.Getenv("JWT_ALGORITHM")
+	if algorithm == "" {
+		algorithm = "RS256"
+	}
+
+	privateKeyFile := os.Getenv("JWT_PRIVATE_KEY_FILE")
+	if privateKeyFile == "" {
+		privateKeyFile = "./secret/id_rsa"
+	}
+	publicKeyFile := os.Getenv("JWT_PUBLIC_KEY_FILE")
+	if publicKeyFile == "" {
+		publicKeyFile = "./secret/id_rsa.pub"
+	}
+
+	c.JWT.Algorithm = algorithm
+	c.JWT.PrivateKeyFile = privateKeyFile
+	c.JWT.PublicKeyFile = publicKeyFile
+	c.JWT.KeyDirectory = os.Getenv("JWT_KEY_DIRECTORY")
+	c.JWT.Issuer = os.Getenv("JWT_ISSUER")
+	c.JWT.Audience = os.Getenv("JWT_AUDIENCE")
+	c.JWT.Leeway = leeway
+	c.JWT.AccessTokenTTL = accessTokenTTL
+	c.JWT.RefreshTokenTTL = refreshTokenTTL
+
+	return c
+}
+
+func (c *Config) loadMailer() *Config {
+	c.Mailer.From = os.Getenv("MAILER_FROM")
+	c.Mailer.LocalFile = os.Getenv("MAILER_LOCAL_FILE")
+
+	return c
+}
+
+func (c *Config) loadAccount() *Config {
+	passwordResetTTL, err := time.ParseDuration(os.Getenv("ACCOUNT_PASSWORD_RESET_TTL"))
+	if err != nil {
+		passwordResetTTL = time.Minute * 30
+	}
+
+	emailVerificationTTL, err := time.ParseDuration(os.Getenv("ACCOUNT_EMAIL_VERIFICATION_TTL"))
+	if err != nil {
+		emailVerificationTTL = time.Hour * 24
+	}
+	emailVerificationResendInterval, err := time.ParseDuration(os.Getenv("ACCOUNT_EMAIL_VERIFICATION_RESEND_INTERVAL"))
+	if err != nil {
+		emailVerificationResendInterval = time.Minute
+	}
+	mfaChallengeTTL, err := time.ParseDuration(os.Getenv("ACCOUNT_MFA_CHALLENGE_TTL"))
+	if err != nil {
+		mfaChallengeTTL = time.Minute * 5
+	}
+	totpIssuer := os.Getenv("ACCOUNT_TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "Devoria"
+	}
+	emailChangeUndoTTL, err := time.ParseDuration(os.Getenv("ACCOUNT_EMAIL_CHANGE_UNDO_TTL"))
+	if err != nil {
+		emailChangeUndoTTL = time.Hour * 24 * 7
+	}
+	deletionGracePeriod, err := time.ParseDuration(os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"))
+	if err != nil {
+		deletionGracePeriod = time.Hour * 24 * 30
+	}
+	deletionPurgeInterval, err := time.ParseDuration(os.Getenv("ACCOUNT_DELETION_PURGE_INTERVAL"))
+	if err != nil {
+		deletionPurgeInterval = time.Hour
+	}
+	requireVerifiedEmailToLogin, _ := strconv.ParseBool(os.Getenv("ACCOUNT_REQUIRE_VERIFIED_EMAIL_TO_LOGIN"))
+	requireVerifiedEmailToPublish, _ := strconv.ParseBool(os.Getenv("ACCOUNT_REQUIRE_VERIFIED_EMAIL_TO_PUBLISH"))
+	encryptPersonalData, _ := strconv.ParseBool(os.Getenv("ACCOUNT_ENCRYPT_PERSONAL_DATA"))
+
+	c.Account.PasswordResetTTL = passwordResetTTL
+	c.Account.EmailVerificationTTL = emailVerificationTTL
+	c.Account.EmailVerificationResendInterval = emailVerificationResendInterval
+	c.Account.RequireVerifiedEmailToLogin = requireVerifiedEmailToLogin
+	c.Account.RequireVerifiedEmailToPublish = requireVerifiedEmailToPublish
+	c.Account.MFAChallengeTTL = mfaChallengeTTL
+	c.Account.TOTPIssuer = totpIssuer
+	c.Account.EmailChangeUndoTTL = emailChangeUndoTTL
+	c.Account.DeletionGracePeriod = deletionGracePeriod
+	c.Account.DeletionPurgeInterval = deletionPurgeInterval
+	c.Account.EncryptPersonalData = encryptPersonalData
+
+	return c
+}
+
+func (c *Config) loadLoginThrottle() *Config {
+	freeAttempts, err := strconv.ParseInt(os.Getenv("LOGIN_THROTTLE_FREE_ATTEMPTS"), 10, 64)
+	if err != nil {
+		freeAttempts = 3
+	}
+	baseDelay, err := time.ParseDuration(os.Getenv("LOGIN_THROTTLE_BASE_DELAY"))
+	if err != nil {
+		baseDelay = time.Second
+	}
+	maxDelay, err := time.ParseDuration(os.Getenv("LOGIN_THROTTLE_MAX_DELAY"))
+	if err != nil {
+		maxDelay = time.Minute
+	}
+	lockoutThreshold, err := strconv.ParseInt(os.Getenv("LOGIN_THROTTLE_LOCKOUT_THRESHOLD"), 10, 64)
+	if err != nil {
+		lockoutThreshold = 10
+	}
+	lockoutDuration, err := time.ParseDuration(os.Getenv("LOGIN_THROTTLE_LOCKOUT_DURATION"))
+	if err != nil {
+		lockoutDuration = time.Minute * 15
+	}
+	window, err := time.ParseDuration(os.Getenv("LOGIN_THROTTLE_WINDOW"))
+	if err != nil {
+		window = time.Minute * 15
+	}
+	clientIPAttemptsFactor, err := strconv.ParseInt(os.Getenv("LOGIN_THROTTLE_CLIENT_IP_ATTEMPTS_FACTOR"), 10, 64)
+	if err != nil || clientIPAttemptsFactor < 1 {
+		clientIPAttemptsFactor = 10
+	}
+
+	c.LoginThrottle.FreeAttempts = freeAttempts
+	c.LoginThrottle.BaseDelay = baseDelay
+	c.LoginThrottle.MaxDelay = maxDelay
+	c.LoginThrottle.LockoutThreshold = lockoutThreshold
+	c.LoginThrottle.LockoutDuration = lockoutDuration
+	c.LoginThrottle.Window = window
+	c.LoginThrottle.ClientIPAttemptsFactor = clientIPAttemptsFactor
+
+	return c
+}
+
+func (c *Config) loadAuthz() *Config {
+	permissionCacheTTL, err := time.ParseDuration(os.Getenv("AUTHZ_PERMISSION_CACHE_TTL"))
+	if err != nil {
+		permissionCacheTTL = time.Minute
+	}
+
+	c.Authz.PermissionCacheTTL = permissionCacheTTL
+
+	return c
+}
+
+func (c *Config) loadOIDC() *Config {
+	stateTTL, err := time.ParseDuration(os.Getenv("OIDC_STATE_TTL"))
+	if err != nil {
+		stateTTL = time.Minute * 10
+	}
+	leeway, err := time.ParseDuration(os.Getenv("OIDC_LEEWAY"))
+	if err != nil {
+		leeway = time.Second * 30
+	}
+
+	c.OIDC.Issuer = os.Getenv("OIDC_ISSUER")
+	c.OIDC.ClientID = os.Getenv("OIDC_CLIENT_ID")
+	c.OIDC.ClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	c.OIDC.RedirectURL = os.Getenv("OIDC_REDIRECT_URL")
+	c.OIDC.Scopes = strings.Fields(os.Getenv("OIDC_SCOPES"))
+	c.OIDC.StateTTL = stateTTL
+	c.OIDC.Leeway = leeway
+
+	return c
+}
+
+func (c *Config) loadPasswordHasher() *Config {
+	algorithm := os.Getenv("PASSWORD_HASHER_ALGORITHM")
+	bcryptCost, _ := strconv.ParseInt(os.Getenv("PASSWORD_HASHER_BCRYPT_COST"), 10, 64)
+	argon2Time, _ := strconv.ParseUint(os.Getenv("PASSWORD_HASHER_ARGON2_TIME"), 10, 32)
+	argon2Memory, _ := strconv.ParseUint(os.Getenv("PASSWORD_HASHER_ARGON2_MEMORY"), 10, 32)
+	argon2Threads, _ := strconv.ParseUint(os.Getenv("PASSWORD_HASHER_ARGON2_THREADS"), 10, 8)
+
+	c.PasswordHasher.Algorithm = algorithm
+	c.PasswordHasher.BcryptCost = int(bcryptCost)
+	c.PasswordHasher.Argon2Time = uint32(argon2Time)
+	c.PasswordHasher.Argon2Memory = uint32(argon2Memory)
+	c.PasswordHasher.Argon2Threads = uint8(argon2Threads)
+
+	return c
+}
+
 func (c *Config) loadGlobalIV() *Config {
 	globalIV := os.Getenv("GLOBAL_IV")
 	fmt.Println("global iv", globalIV)
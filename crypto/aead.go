@@ -0,0 +1,195 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Versions of authenticated ciphertexts. The version prefixes the ciphertext, so a value always tells
+// how it has to be decrypted whatever the version currently used to encrypt.
+const (
+	VersionAES256GCM         = "v1"
+	VersionXChaCha20Poly1305 = "v2"
+)
+
+const versionSeparator = ":"
+
+// Errors
+var (
+	ErrInvalidKey         = fmt.Errorf("invalid encryption key")
+	ErrInvalidKeyID       = fmt.Errorf("invalid encryption key id")
+	ErrUnknownKey         = fmt.Errorf("unknown encryption key")
+	ErrInvalidCiphertext  = fmt.Errorf("invalid ciphertext")
+	ErrUnsupportedVersion = fmt.Errorf("unsupported ciphertext version")
+)
+
+// Cipher is a collection of behavior of authenticated encryption.
+type Cipher interface {
+	Encrypt(ctx context.Context, plaintext string) (encrypted string, err error)
+	Decrypt(ctx context.Context, encrypted string) (plaintext string, err error)
+	// NeedsRotation tells whether the value is not encrypted with the active version and key.
+	NeedsRotation(encrypted string) bool
+}
+
+// KeyRing holds the 32 bytes secrets by key id, values are encrypted with the active one and decrypted with
+// the one named in them. The empty key id is the key of values encrypted before the key ids.
+type KeyRing struct {
+	ActiveKeyID string
+	Secrets     map[string]string
+}
+
+// AEADCipher is a concrete struct of authenticated encryption with a random nonce per value.
+// A value is "<version>:[<key id>:]<base64url(nonce|ciphertext)>", the prefix is authenticated along with it.
+// Values without version are legacy AES-CBC values, they are decrypted with the legacy secret and iv.
+type AEADCipher struct {
+	version      string
+	activeKeyID  string
+	keys         map[string]map[string]cipher.AEAD
+	legacySecret []byte
+	legacyIV     []byte
+}
+
+// NewAEADCipher is a constructor, values are encrypted with the version and the 32 bytes secret.
+// An empty legacy secret refuses legacy values.
+func NewAEADCipher(version string, secret string, legacySecret string, legacyIV string) (Cipher, error) {
+	return NewKeyRingCipher(version, KeyRing{Secrets: map[string]string{"": secret}}, legacySecret, legacyIV)
+}
+
+// NewKeyRingCipher is a constructor, values are encrypted with the version and the active key of the key ring.
+// An empty legacy secret refuses legacy values.
+func NewKeyRingCipher(version string, keyRing KeyRing, legacySecret string, legacyIV string) (Cipher, error) {
+	keys := make(map[string]map[string]cipher.AEAD, len(keyRing.Secrets))
+	for keyID, secret := range keyRing.Secrets {
+		if strings.Contains(keyID, versionSeparator) {
+			return nil, ErrInvalidKeyID
+		}
+		aeads, err := newAEADs([]byte(secret))
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", keyID, err)
+		}
+		keys[keyID] = aeads
+	}
+
+	active, ok := keys[keyRing.ActiveKeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if _, ok := active[version]; !ok {
+		return nil, ErrUnsupportedVersion
+	}
+
+	return &AEADCipher{
+		version:      version,
+		activeKeyID:  keyRing.ActiveKeyID,
+		keys:         keys,
+		legacySecret: []byte(legacySecret),
+		legacyIV:     []byte(legacyIV),
+	}, nil
+}
+
+func newAEADs(secret []byte) (aeads map[string]cipher.AEAD, err error) {
+	if len(secret) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	xchacha, err := chacha20poly1305.NewX(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	return map[string]cipher.AEAD{
+		VersionAES256GCM:         gcm,
+		VersionXChaCha20Poly1305: xchacha,
+	}, nil
+}
+
+// prefix returns the authenticated prefix of a value, values of the empty key id keep their former shape.
+func prefix(version string, keyID string) string {
+	if keyID == "" {
+		return version
+	}
+	return version + versionSeparator + keyID
+}
+
+// Encrypt returns the versioned ciphertext of the plaintext under the active key and a fresh random nonce.
+func (c *AEADCipher) Encrypt(ctx context.Context, plaintext string) (encrypted string, err error) {
+	aead := c.keys[c.activeKeyID][c.version]
+	ad := prefix(c.version, c.activeKeyID)
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(ad))
+
+	return ad + versionSeparator + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// parse splits a value into its version, key id and payload, legacy values have no version.
+func parse(encrypted string) (version string, keyID string, payload string, legacy bool) {
+	parts := strings.Split(encrypted, versionSeparator)
+	switch len(parts) {
+	case 1:
+		return "", "", encrypted, true
+	case 2:
+		return parts[0], "", parts[1], false
+	default:
+		return parts[0], parts[1], strings.Join(parts[2:], versionSeparator), false
+	}
+}
+
+// Decrypt returns the plaintext of a versioned or a legacy value, a tampered value is refused.
+func (c *AEADCipher) Decrypt(ctx context.Context, encrypted string) (plaintext string, err error) {
+	version, keyID, payload, legacy := parse(encrypted)
+	if legacy {
+		if len(c.legacySecret) == 0 {
+			return "", ErrUnsupportedVersion
+		}
+		return decryptCBC(c.legacySecret, c.legacyIV, encrypted)
+	}
+
+	aeads, ok := c.keys[keyID]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+	aead, ok := aeads[version]
+	if !ok {
+		return "", ErrUnsupportedVersion
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	opened, err := aead.Open(nil, nonce, ciphertext, []byte(prefix(version, keyID)))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	return string(opened), nil
+}
+
+// NeedsRotation tells whether the value is not encrypted with the active version and key.
+func (c *AEADCipher) NeedsRotation(encrypted string) bool {
+	version, keyID, _, legacy := parse(encrypted)
+
+	return legacy || version != c.version || keyID != c.activeKeyID
+}
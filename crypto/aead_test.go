@@ -0,0 +1,149 @@
+package crypto_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/crypto"
+)
+
+const (
+	testSecret       = "abcdefghijklmnopqrstuvwxyz123456"
+	testLegacySecret = "12345678901234567890123456789012"
+	testLegacyIV     = "1234567890123456"
+)
+
+func TestAEADCipher_EncryptAndDecrypt(t *testing.T) {
+	for _, version := range []string{crypto.VersionAES256GCM, crypto.VersionXChaCha20Poly1305} {
+		t.Run(version, func(t *testing.T) {
+			cipher, err := crypto.NewAEADCipher(version, testSecret, "", "")
+			assert.NoError(t, err)
+
+			first, err := cipher.Encrypt(context.Background(), "JBSWY3DPEHPK3PXP")
+			assert.NoError(t, err)
+			second, err := cipher.Encrypt(context.Background(), "JBSWY3DPEHPK3PXP")
+			assert.NoError(t, err)
+
+			assert.True(t, strings.HasPrefix(first, version+":"))
+			assert.NotEqual(t, first, second)
+
+			plaintext, err := cipher.Decrypt(context.Background(), first)
+			assert.NoError(t, err)
+			assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+		})
+	}
+}
+
+func TestAEADCipher_DecryptOtherVersion(t *testing.T) {
+	gcm, _ := crypto.NewAEADCipher(crypto.VersionAES256GCM, testSecret, "", "")
+	xchacha, _ := crypto.NewAEADCipher(crypto.VersionXChaCha20Poly1305, testSecret, "", "")
+
+	encrypted, err := gcm.Encrypt(context.Background(), "secret")
+	assert.NoError(t, err)
+
+	plaintext, err := xchacha.Decrypt(context.Background(), encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", plaintext)
+}
+
+func TestAEADCipher_DecryptTampered(t *testing.T) {
+	cipher, _ := crypto.NewAEADCipher(crypto.VersionAES256GCM, testSecret, "", "")
+	encrypted, _ := cipher.Encrypt(context.Background(), "secret")
+
+	// a character in the middle, the last one may only carry padding bits.
+	middle := len(encrypted) / 2
+	flipped := byte('A')
+	if encrypted[middle] == 'A' {
+		flipped = 'B'
+	}
+
+	testCases := map[string]string{
+		"payload":   encrypted[:middle] + string(flipped) + encrypted[middle+1:],
+		"version":   crypto.VersionXChaCha20Poly1305 + strings.TrimPrefix(encrypted, crypto.VersionAES256GCM),
+		"truncated": encrypted[:10],
+		"encoding":  crypto.VersionAES256GCM + ":not base64!",
+	}
+	for name, tampered := range testCases {
+		t.Run(name, func(t *testing.T) {
+			plaintext, err := cipher.Decrypt(context.Background(), tampered)
+			assert.Equal(t, crypto.ErrInvalidCiphertext, err)
+			assert.Empty(t, plaintext)
+		})
+	}
+
+	_, err := cipher.Decrypt(context.Background(), "v9:"+strings.TrimPrefix(encrypted, crypto.VersionAES256GCM+":"))
+	assert.Equal(t, crypto.ErrUnsupportedVersion, err)
+}
+
+func TestAEADCipher_DecryptLegacy(t *testing.T) {
+	legacy := crypto.NewAES256CBC(testLegacySecret).Encrypt("secret", testLegacyIV)
+
+	cipher, _ := crypto.NewAEADCipher(crypto.VersionAES256GCM, testSecret, testLegacySecret, testLegacyIV)
+	plaintext, err := cipher.Decrypt(context.Background(), legacy)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", plaintext)
+
+	_, err = cipher.Decrypt(context.Background(), "0011")
+	assert.Equal(t, crypto.ErrInvalidCiphertext, err)
+
+	withoutLegacy, _ := crypto.NewAEADCipher(crypto.VersionAES256GCM, testSecret, "", "")
+	_, err = withoutLegacy.Decrypt(context.Background(), legacy)
+	assert.Equal(t, crypto.ErrUnsupportedVersion, err)
+}
+
+func TestNewAEADCipher_Invalid(t *testing.T) {
+	_, err := crypto.NewAEADCipher(crypto.VersionAES256GCM, "short", "", "")
+	assert.ErrorIs(t, err, crypto.ErrInvalidKey)
+
+	_, err = crypto.NewAEADCipher("v9", testSecret, "", "")
+	assert.Equal(t, crypto.ErrUnsupportedVersion, err)
+}
+
+func TestKeyRingCipher_Rotation(t *testing.T) {
+	unkeyed, _ := crypto.NewAEADCipher(crypto.VersionAES256GCM, testSecret, "", "")
+	previous, _ := crypto.NewKeyRingCipher(crypto.VersionAES256GCM, crypto.KeyRing{
+		ActiveKeyID: "2025",
+		Secrets:     map[string]string{"": testSecret, "2025": testLegacySecret},
+	}, "", "")
+	current, err := crypto.NewKeyRingCipher(crypto.VersionXChaCha20Poly1305, crypto.KeyRing{
+		ActiveKeyID: "2026",
+		Secrets:     map[string]string{"": testSecret, "2025": testLegacySecret, "2026": "zyxwvutsrqponmlkjihgfedcba654321"},
+	}, "", "")
+	assert.NoError(t, err)
+
+	unkeyedValue, _ := unkeyed.Encrypt(context.Background(), "secret")
+	previousValue, _ := previous.Encrypt(context.Background(), "secret")
+	currentValue, _ := current.Encrypt(context.Background(), "secret")
+	assert.True(t, strings.HasPrefix(previousValue, crypto.VersionAES256GCM+":2025:"))
+	assert.True(t, strings.HasPrefix(currentValue, crypto.VersionXChaCha20Poly1305+":2026:"))
+
+	for _, value := range []string{unkeyedValue, previousValue, currentValue} {
+		plaintext, err := current.Decrypt(context.Background(), value)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+	}
+
+	assert.True(t, current.NeedsRotation(unkeyedValue))
+	assert.True(t, current.NeedsRotation(previousValue))
+	assert.True(t, current.NeedsRotation("0011"))
+	assert.False(t, current.NeedsRotation(currentValue))
+
+	_, err = previous.Decrypt(context.Background(), currentValue)
+	assert.Equal(t, crypto.ErrUnknownKey, err)
+
+	// the key id is authenticated, a value cannot be moved under another key.
+	moved := strings.Replace(previousValue, ":2025:", ":2026:", 1)
+	_, err = current.Decrypt(context.Background(), moved)
+	assert.Equal(t, crypto.ErrInvalidCiphertext, err)
+}
+
+func TestNewKeyRingCipher_Invalid(t *testing.T) {
+	_, err := crypto.NewKeyRingCipher(crypto.VersionAES256GCM, crypto.KeyRing{ActiveKeyID: "2026", Secrets: map[string]string{"2025": testSecret}}, "", "")
+	assert.Equal(t, crypto.ErrUnknownKey, err)
+
+	_, err = crypto.NewKeyRingCipher(crypto.VersionAES256GCM, crypto.KeyRing{ActiveKeyID: "a:b", Secrets: map[string]string{"a:b": testSecret}}, "", "")
+	assert.Equal(t, crypto.ErrInvalidKeyID, err)
+}
@@ -0,0 +1,36 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// BlindIndex is a collection of behavior of deterministic keyed digests, an encrypted value can be looked up
+// by the equality of its digest without the digest telling the value.
+type BlindIndex interface {
+	Index(value string) string
+}
+
+// HMACBlindIndex is a concrete struct of blind index computed by HMAC-SHA256.
+type HMACBlindIndex struct {
+	key []byte
+}
+
+// NewHMACBlindIndex is a constructor, the key of at least 32 bytes must not be any of the encryption keys.
+// Changing the key invalidates every index.
+func NewHMACBlindIndex(key string) (BlindIndex, error) {
+	if len(key) < 32 {
+		return nil, ErrInvalidKey
+	}
+
+	return &HMACBlindIndex{key: []byte(key)}, nil
+}
+
+// Index returns the hex encoded digest of the value.
+func (b *HMACBlindIndex) Index(value string) string {
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
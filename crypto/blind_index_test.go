@@ -0,0 +1,23 @@
+package crypto_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/crypto"
+)
+
+func TestHMACBlindIndex(t *testing.T) {
+	blindIndex, err := crypto.NewHMACBlindIndex(testSecret)
+	assert.NoError(t, err)
+	other, _ := crypto.NewHMACBlindIndex(testLegacySecret)
+
+	assert.Equal(t, blindIndex.Index("johndoe@mail.com"), blindIndex.Index("johndoe@mail.com"))
+	assert.Len(t, blindIndex.Index("johndoe@mail.com"), 64)
+	assert.NotEqual(t, blindIndex.Index("johndoe@mail.com"), blindIndex.Index("janedoe@mail.com"))
+	assert.NotEqual(t, blindIndex.Index("johndoe@mail.com"), other.Index("johndoe@mail.com"))
+
+	_, err = crypto.NewHMACBlindIndex("short")
+	assert.Equal(t, crypto.ErrInvalidKey, err)
+}
@@ -4,12 +4,14 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"encoding/hex"
+	"fmt"
 	"log"
 
 	"github.com/mergermarket/go-pkcs7"
 )
 
 // Crypto is a collection behavior of encryption.
+// Deprecated: it is unauthenticated and deterministic, new values are encrypted by a Cipher.
 type Crypto interface {
 	Encrypt(plaintext string, iv string) (encrypted string)
 	Decrypt(encrypted string, iv string) (plaintext string)
@@ -42,25 +44,39 @@ func (a AES256CBC) Encrypt(plaintext string, iv string) (encrypted string) {
 	return
 }
 
-// Decrypt returns plaintext of encrypted string.
+// Decrypt returns plaintext of encrypted string, or an empty string when it cannot be decrypted.
 func (a AES256CBC) Decrypt(encrypted string, iv string) (plaintext string) {
-	bSecret := []byte(a.secret)
-	bIV := []byte(iv)
+	plaintext, err := decryptCBC([]byte(a.secret), []byte(iv), encrypted)
+	if err != nil {
+		log.Println(err)
+		return ""
+	}
 
-	cipherText, _ := hex.DecodeString(encrypted)
+	return
+}
 
-	block, _ := aes.NewCipher(bSecret)
+// decryptCBC decrypts a hex encoded AES-CBC value with PKCS#7 padding.
+func decryptCBC(secret []byte, iv []byte, encrypted string) (plaintext string, err error) {
+	ciphertext, err := hex.DecodeString(encrypted)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	if len(ciphertext) < aes.BlockSize || len(ciphertext)%aes.BlockSize != 0 || len(iv) != aes.BlockSize {
+		return "", ErrInvalidCiphertext
+	}
 
-	if len(cipherText) < aes.BlockSize {
-		log.Println("cipherText too short")
-		return
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidKey, err)
 	}
 
-	mode := cipher.NewCBCDecrypter(block, bIV)
-	mode.CryptBlocks(cipherText, cipherText)
+	mode := cipher.NewCBCDecrypter(block, iv)
+	mode.CryptBlocks(ciphertext, ciphertext)
 
-	cipherText, _ = pkcs7.Unpad(cipherText, aes.BlockSize)
-	plaintext = string(cipherText)
+	ciphertext, err = pkcs7.Unpad(ciphertext, aes.BlockSize)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
 
-	return
+	return string(ciphertext), nil
 }
@@ -0,0 +1,240 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VersionEnvelopeAES256GCM is the version of values encrypted by a data key of their own,
+// the data key is stored along wrapped by the key encryption key of the key manager.
+const VersionEnvelopeAES256GCM = "e1"
+
+// Errors
+var (
+	ErrKeyManager = fmt.Errorf("key manager failure")
+)
+
+// KeyManager is a collection of behavior of key encryption keys that never leave their holder.
+type KeyManager interface {
+	// KeyID returns the id of the active key encryption key, the one new data keys are wrapped by.
+	KeyID() string
+	// GenerateDataKey returns a fresh 32 bytes data key in plaintext and wrapped by the active key encryption key.
+	GenerateDataKey(ctx context.Context) (plaintext []byte, wrapped string, err error)
+	// UnwrapDataKey returns the plaintext of a data key wrapped by the key encryption key of the id.
+	UnwrapDataKey(ctx context.Context, keyID string, wrapped string) (plaintext []byte, err error)
+}
+
+// EnvelopeCipher is a concrete struct of envelope encryption, every value is encrypted by a data key of its own.
+// A value is "e1:[<key id>:]<base64url(wrapped data key)>:<base64url(nonce|ciphertext)>", the key id names the
+// key encryption key and the prefix is authenticated along with it. Values without key id were wrapped before
+// the key ids. Any other value is decrypted by the fallback cipher.
+type EnvelopeCipher struct {
+	keyManager KeyManager
+	fallback   Cipher
+	cache      *dataKeyCache
+}
+
+// NewEnvelopeCipher is a constructor, a nil fallback refuses values that are not envelopes. Unwrapped data keys
+// are kept for the cache ttl, up to the cache size, a zero ttl unwraps the data key of every decrypted value.
+func NewEnvelopeCipher(keyManager KeyManager, fallback Cipher, cacheTTL time.Duration, cacheSize int) Cipher {
+	return &EnvelopeCipher{
+		keyManager: keyManager,
+		fallback:   fallback,
+		cache:      newDataKeyCache(cacheTTL, cacheSize),
+	}
+}
+
+func newDataKeyAEAD(dataKey []byte) (aead cipher.AEAD, err error) {
+	if len(dataKey) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Encrypt returns the envelope of the plaintext under a fresh data key and nonce.
+func (c *EnvelopeCipher) Encrypt(ctx context.Context, plaintext string) (encrypted string, err error) {
+	keyID := c.keyManager.KeyID()
+	if strings.Contains(keyID, versionSeparator) {
+		return "", ErrInvalidKeyID
+	}
+
+	dataKey, wrapped, err := c.keyManager.GenerateDataKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newDataKeyAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	ad := prefix(VersionEnvelopeAES256GCM, keyID) + versionSeparator + base64.RawURLEncoding.EncodeToString([]byte(wrapped))
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(ad))
+
+	return ad + versionSeparator + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// parseEnvelope splits an envelope into its key id, wrapped data key and sealed payload.
+func parseEnvelope(encrypted string) (keyID string, wrapped string, sealed string, ok bool) {
+	parts := strings.Split(encrypted, versionSeparator)
+	switch {
+	case parts[0] != VersionEnvelopeAES256GCM:
+		return "", "", "", false
+	case len(parts) == 3:
+		return "", parts[1], parts[2], true
+	case len(parts) == 4:
+		return parts[1], parts[2], parts[3], true
+	default:
+		return "", "", "", false
+	}
+}
+
+// Decrypt returns the plaintext of an envelope, any other value is left to the fallback.
+func (c *EnvelopeCipher) Decrypt(ctx context.Context, encrypted string) (plaintext string, err error) {
+	if !strings.HasPrefix(encrypted, VersionEnvelopeAES256GCM+versionSeparator) {
+		if c.fallback == nil {
+			return "", ErrUnsupportedVersion
+		}
+		return c.fallback.Decrypt(ctx, encrypted)
+	}
+
+	keyID, encodedWrapped, encodedSealed, ok := parseEnvelope(encrypted)
+	if !ok {
+		return "", ErrInvalidCiphertext
+	}
+
+	wrapped, err := base64.RawURLEncoding.DecodeString(encodedWrapped)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encodedSealed)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	aead, err := c.dataKeyAEAD(ctx, keyID, string(wrapped))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	ad := prefix(VersionEnvelopeAES256GCM, keyID) + versionSeparator + encodedWrapped
+	opened, err := aead.Open(nil, nonce, ciphertext, []byte(ad))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	return string(opened), nil
+}
+
+// dataKeyAEAD returns the AEAD of a wrapped data key, from the cache when it was unwrapped lately.
+func (c *EnvelopeCipher) dataKeyAEAD(ctx context.Context, keyID string, wrapped string) (aead cipher.AEAD, err error) {
+	cacheKey := keyID + versionSeparator + wrapped
+	now := time.Now()
+	if aead, ok := c.cache.get(cacheKey, now); ok {
+		return aead, nil
+	}
+
+	dataKey, err := c.keyManager.UnwrapDataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err = newDataKeyAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.put(cacheKey, aead, now)
+
+	return aead, nil
+}
+
+// NeedsRotation tells whether the value is not an envelope yet or its data key is not wrapped by the active
+// key encryption key.
+func (c *EnvelopeCipher) NeedsRotation(encrypted string) bool {
+	keyID, _, _, ok := parseEnvelope(encrypted)
+
+	return !ok || keyID != c.keyManager.KeyID()
+}
+
+type cachedDataKey struct {
+	aead      cipher.AEAD
+	expiresAt time.Time
+}
+
+// dataKeyCache keeps the AEADs of unwrapped data keys by their wrapped form, so a value read again does not
+// reach the key manager.
+type dataKeyCache struct {
+	ttl  time.Duration
+	size int
+
+	mutex   sync.Mutex
+	entries map[string]cachedDataKey
+}
+
+func newDataKeyCache(ttl time.Duration, size int) *dataKeyCache {
+	return &dataKeyCache{
+		ttl:     ttl,
+		size:    size,
+		entries: map[string]cachedDataKey{},
+	}
+}
+
+func (c *dataKeyCache) get(key string, now time.Time) (aead cipher.AEAD, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cached, ok := c.entries[key]
+	if !ok || !now.Before(cached.expiresAt) {
+		return nil, false
+	}
+
+	return cached.aead, true
+}
+
+// put keeps the AEAD, a full cache first drops the expired entries and then any entry.
+func (c *dataKeyCache) put(key string, aead cipher.AEAD, now time.Time) {
+	if c.ttl <= 0 || c.size <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.entries) >= c.size {
+		for k, cached := range c.entries {
+			if !now.Before(cached.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	for k := range c.entries {
+		if len(c.entries) < c.size {
+			break
+		}
+		delete(c.entries, k)
+	}
+
+	c.entries[key] = cachedDataKey{aead: aead, expiresAt: now.Add(c.ttl)}
+}
@@ -0,0 +1,236 @@
+package crypto_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/crypto"
+)
+
+// newKeyFile writes a random key encryption key and returns its path.
+func newKeyFile(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "key-manager")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	key := make([]byte, 32)
+	rand.Read(key)
+	path := filepath.Join(dir, "kek")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600))
+
+	return path
+}
+
+func newFileKeyManager(t *testing.T) crypto.KeyManager {
+	keyManager, err := crypto.NewFileKeyManager("k1", map[string]string{"k1": newKeyFile(t)})
+	assert.NoError(t, err)
+
+	return keyManager
+}
+
+func TestFileKeyManager(t *testing.T) {
+	keyManager := newFileKeyManager(t)
+	assert.Equal(t, "k1", keyManager.KeyID())
+
+	dataKey, wrapped, err := keyManager.GenerateDataKey(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, dataKey, 32)
+	assert.True(t, strings.HasPrefix(wrapped, "local:v1:"))
+
+	unwrapped, err := keyManager.UnwrapDataKey(context.Background(), "k1", wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+
+	// another key encryption key cannot unwrap it.
+	_, err = newFileKeyManager(t).UnwrapDataKey(context.Background(), "k1", wrapped)
+	assert.Equal(t, crypto.ErrInvalidCiphertext, err)
+
+	_, err = keyManager.UnwrapDataKey(context.Background(), "k2", wrapped)
+	assert.Equal(t, crypto.ErrUnknownKey, err)
+
+	_, err = crypto.NewFileKeyManager("k2", map[string]string{"k1": newKeyFile(t)})
+	assert.Equal(t, crypto.ErrUnknownKey, err)
+
+	_, err = crypto.NewFileKeyManager("k:1", map[string]string{"k:1": newKeyFile(t)})
+	assert.Equal(t, crypto.ErrInvalidKeyID, err)
+}
+
+func TestEnvelopeCipher(t *testing.T) {
+	fallback, _ := crypto.NewAEADCipher(crypto.VersionAES256GCM, testSecret, testLegacySecret, testLegacyIV)
+	envelope := crypto.NewEnvelopeCipher(newFileKeyManager(t), fallback, time.Minute, 10)
+
+	first, err := envelope.Encrypt(context.Background(), "secret")
+	assert.NoError(t, err)
+	second, _ := envelope.Encrypt(context.Background(), "secret")
+	assert.True(t, strings.HasPrefix(first, crypto.VersionEnvelopeAES256GCM+":k1:"))
+	// every value has a data key of its own.
+	assert.NotEqual(t, strings.Split(first, ":")[2], strings.Split(second, ":")[2])
+	assert.False(t, envelope.NeedsRotation(first))
+
+	plaintext, err := envelope.Decrypt(context.Background(), first)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", plaintext)
+
+	former, _ := fallback.Encrypt(context.Background(), "secret")
+	legacy := crypto.NewAES256CBC(testLegacySecret).Encrypt("secret", testLegacyIV)
+	for _, value := range []string{former, legacy} {
+		assert.True(t, envelope.NeedsRotation(value))
+		plaintext, err := envelope.Decrypt(context.Background(), value)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+	}
+
+	// the data key of one value does not open another.
+	parts, other := strings.Split(first, ":"), strings.Split(second, ":")
+	_, err = envelope.Decrypt(context.Background(), strings.Join([]string{parts[0], parts[1], other[2], parts[3]}, ":"))
+	assert.Equal(t, crypto.ErrInvalidCiphertext, err)
+
+	// the key id is authenticated along with the data key.
+	_, err = envelope.Decrypt(context.Background(), strings.Join([]string{parts[0], parts[2], parts[3]}, ":"))
+	assert.Equal(t, crypto.ErrUnknownKey, err)
+
+	_, err = crypto.NewEnvelopeCipher(newFileKeyManager(t), nil, 0, 0).Decrypt(context.Background(), former)
+	assert.Equal(t, crypto.ErrUnsupportedVersion, err)
+}
+
+func TestEnvelopeCipher_KeyEncryptionKeyRotation(t *testing.T) {
+	k1, k2 := newKeyFile(t), newKeyFile(t)
+	previous, _ := crypto.NewFileKeyManager("k1", map[string]string{"k1": k1})
+	active, _ := crypto.NewFileKeyManager("k2", map[string]string{"k1": k1, "k2": k2})
+
+	rotated, _ := crypto.NewEnvelopeCipher(previous, nil, 0, 0).Encrypt(context.Background(), "secret")
+	envelope := crypto.NewEnvelopeCipher(active, nil, 0, 0)
+	assert.True(t, envelope.NeedsRotation(rotated))
+
+	plaintext, err := envelope.Decrypt(context.Background(), rotated)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", plaintext)
+
+	current, _ := envelope.Encrypt(context.Background(), "secret")
+	assert.True(t, strings.HasPrefix(current, crypto.VersionEnvelopeAES256GCM+":k2:"))
+	assert.False(t, envelope.NeedsRotation(current))
+}
+
+// countingKeyManager counts the data keys it unwraps.
+type countingKeyManager struct {
+	crypto.KeyManager
+
+	mutex   sync.Mutex
+	unwraps int
+}
+
+func (m *countingKeyManager) UnwrapDataKey(ctx context.Context, keyID string, wrapped string) (plaintext []byte, err error) {
+	m.mutex.Lock()
+	m.unwraps++
+	m.mutex.Unlock()
+
+	return m.KeyManager.UnwrapDataKey(ctx, keyID, wrapped)
+}
+
+func TestEnvelopeCipher_DataKeyCache(t *testing.T) {
+	keyManager := &countingKeyManager{KeyManager: newFileKeyManager(t)}
+	envelope := crypto.NewEnvelopeCipher(keyManager, nil, time.Minute, 1)
+
+	first, _ := envelope.Encrypt(context.Background(), "first")
+	second, _ := envelope.Encrypt(context.Background(), "second")
+
+	for i := 0; i < 3; i++ {
+		plaintext, err := envelope.Decrypt(context.Background(), first)
+		assert.NoError(t, err)
+		assert.Equal(t, "first", plaintext)
+	}
+	assert.Equal(t, 1, keyManager.unwraps)
+
+	// a full cache makes room for the next data key.
+	envelope.Decrypt(context.Background(), second)
+	envelope.Decrypt(context.Background(), first)
+	assert.Equal(t, 3, keyManager.unwraps)
+
+	uncached := crypto.NewEnvelopeCipher(keyManager, nil, 0, 0)
+	uncached.Decrypt(context.Background(), first)
+	uncached.Decrypt(context.Background(), first)
+	assert.Equal(t, 5, keyManager.unwraps)
+}
+
+// newVaultTransitServer stands in for the transit engine of a vault dev server.
+func newVaultTransitServer(t *testing.T, token string) *httptest.Server {
+	var mutex sync.Mutex
+	wrappedKeys := map[string]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{"permission denied"}})
+			return
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		data := map[string]string{}
+		switch r.URL.Path {
+		case "/v1/transit/datakey/plaintext/articles":
+			key := make([]byte, 32)
+			rand.Read(key)
+			data["plaintext"] = base64.StdEncoding.EncodeToString(key)
+			data["ciphertext"] = "vault:v1:" + base64.StdEncoding.EncodeToString(key[:8])
+			wrappedKeys[data["ciphertext"]] = data["plaintext"]
+		case "/v1/transit/decrypt/articles":
+			plaintext, ok := wrappedKeys[body["ciphertext"].(string)]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{"invalid ciphertext"}})
+				return
+			}
+			data["plaintext"] = plaintext
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestVaultTransitKeyManager(t *testing.T) {
+	server := newVaultTransitServer(t, "dev-token")
+	keyManager := crypto.NewVaultTransitKeyManager(server.Client(), server.URL, "dev-token", "", "articles")
+
+	envelope := crypto.NewEnvelopeCipher(keyManager, nil, time.Minute, 10)
+	encrypted, err := envelope.Encrypt(context.Background(), "secret")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, crypto.VersionEnvelopeAES256GCM+":articles:"))
+
+	plaintext, err := envelope.Decrypt(context.Background(), encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", plaintext)
+
+	// another transit key is a rotation, the former one still unwraps its data keys.
+	assert.True(t, crypto.NewEnvelopeCipher(crypto.NewVaultTransitKeyManager(server.Client(), server.URL, "dev-token", "", "articles-2"), nil, 0, 0).NeedsRotation(encrypted))
+
+	_, err = keyManager.UnwrapDataKey(context.Background(), "articles", "vault:v1:unknown")
+	assert.Equal(t, crypto.ErrKeyManager, err)
+
+	unauthorized := crypto.NewVaultTransitKeyManager(server.Client(), server.URL, "wrong-token", "transit", "articles")
+	_, _, err = unauthorized.GenerateDataKey(context.Background())
+	assert.Equal(t, crypto.ErrKeyManager, err)
+}
@@ -0,0 +1,111 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+const fileKeyManagerPrefix = "local:v1:"
+
+// FileKeyManager is a concrete struct of a key manager holding the key encryption keys in local files by key id.
+// Data keys are wrapped by AES-256-GCM as "local:v1:<base64(nonce|ciphertext)>", the key id is authenticated
+// along with them. The empty key id is the key of data keys wrapped before the key ids.
+type FileKeyManager struct {
+	activeKeyID string
+	aeads       map[string]cipher.AEAD
+}
+
+// NewFileKeyManager is a constructor, every file holds a base64 encoded 32 bytes key encryption key,
+// e.g. generated by `openssl rand -base64 32`. New data keys are wrapped by the key of the active key id.
+func NewFileKeyManager(activeKeyID string, paths map[string]string) (KeyManager, error) {
+	aeads := make(map[string]cipher.AEAD, len(paths))
+	for keyID, path := range paths {
+		if strings.Contains(keyID, versionSeparator) {
+			return nil, ErrInvalidKeyID
+		}
+		aead, err := loadKeyEncryptionKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", keyID, err)
+		}
+		aeads[keyID] = aead
+	}
+
+	if _, ok := aeads[activeKeyID]; !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return &FileKeyManager{activeKeyID: activeKeyID, aeads: aeads}, nil
+}
+
+func loadKeyEncryptionKey(path string) (aead cipher.AEAD, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil || len(key) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// KeyID returns the id of the key new data keys are wrapped by.
+func (m *FileKeyManager) KeyID() string {
+	return m.activeKeyID
+}
+
+// GenerateDataKey returns a fresh random data key wrapped by the active key.
+func (m *FileKeyManager) GenerateDataKey(ctx context.Context) (plaintext []byte, wrapped string, err error) {
+	aead := m.aeads[m.activeKeyID]
+
+	plaintext = make([]byte, 32)
+	if _, err = rand.Read(plaintext); err != nil {
+		return nil, "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(fileKeyManagerPrefix+m.activeKeyID))
+
+	return plaintext, fileKeyManagerPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// UnwrapDataKey returns the plaintext of a data key wrapped by the key of the id.
+func (m *FileKeyManager) UnwrapDataKey(ctx context.Context, keyID string, wrapped string) (plaintext []byte, err error) {
+	aead, ok := m.aeads[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if !strings.HasPrefix(wrapped, fileKeyManagerPrefix) {
+		return nil, ErrInvalidCiphertext
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(wrapped, fileKeyManagerPrefix))
+	if err != nil || len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err = aead.Open(nil, nonce, ciphertext, []byte(fileKeyManagerPrefix+keyID))
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// VaultTransitKeyManager is a concrete struct of a key manager backed by the transit secrets engine of
+// HashiCorp Vault. Any server speaking the same endpoints, e.g. a vault dev server, can stand in.
+// The key id is the name of the transit key, vault keeps the versions of a key on its own.
+type VaultTransitKeyManager struct {
+	httpClient *http.Client
+	address    string
+	token      string
+	mountPath  string
+	keyName    string
+}
+
+// NewVaultTransitKeyManager is a constructor, an empty mount path is the default "transit".
+func NewVaultTransitKeyManager(httpClient *http.Client, address string, token string, mountPath string, keyName string) KeyManager {
+	if mountPath == "" {
+		mountPath = "transit"
+	}
+
+	return &VaultTransitKeyManager{
+		httpClient: httpClient,
+		address:    strings.TrimSuffix(address, "/"),
+		token:      token,
+		mountPath:  strings.Trim(mountPath, "/"),
+		keyName:    keyName,
+	}
+}
+
+type vaultTransitResponse struct {
+	Data struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// KeyID returns the name of the transit key new data keys are wrapped by.
+func (m *VaultTransitKeyManager) KeyID() string {
+	return m.keyName
+}
+
+// GenerateDataKey returns a data key generated by vault, wrapped by the transit key.
+func (m *VaultTransitKeyManager) GenerateDataKey(ctx context.Context) (plaintext []byte, wrapped string, err error) {
+	resp, err := m.call(ctx, "datakey/plaintext", m.keyName, map[string]interface{}{"bits": 256})
+	if err != nil {
+		return nil, "", err
+	}
+
+	plaintext, err = base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil || resp.Data.Ciphertext == "" {
+		return nil, "", ErrKeyManager
+	}
+
+	return plaintext, resp.Data.Ciphertext, nil
+}
+
+// UnwrapDataKey returns the plaintext of a data key decrypted by vault with the transit key of the id,
+// data keys wrapped before the key ids are decrypted with the configured one.
+func (m *VaultTransitKeyManager) UnwrapDataKey(ctx context.Context, keyID string, wrapped string) (plaintext []byte, err error) {
+	if keyID == "" {
+		keyID = m.keyName
+	}
+
+	resp, err := m.call(ctx, "decrypt", keyID, map[string]interface{}{"ciphertext": wrapped})
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err = base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, ErrKeyManager
+	}
+
+	return plaintext, nil
+}
+
+func (m *VaultTransitKeyManager) call(ctx context.Context, operation string, keyName string, body map[string]interface{}) (resp vaultTransitResponse, err error) {
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", m.address, m.mountPath, operation, url.PathEscape(keyName))
+	buff, _ := json.Marshal(body)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(buff))
+	if err != nil {
+		return resp, ErrKeyManager
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Vault-Token", m.token)
+
+	response, err := m.httpClient.Do(request)
+	if err != nil {
+		log.Println(err)
+		return resp, ErrKeyManager
+	}
+	defer response.Body.Close()
+
+	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil && response.StatusCode == http.StatusOK {
+		log.Println(err)
+		return resp, ErrKeyManager
+	}
+	if response.StatusCode != http.StatusOK {
+		log.Printf("vault transit %s: %d %v\n", operation, response.StatusCode, resp.Errors)
+		return resp, ErrKeyManager
+	}
+
+	return resp, nil
+}
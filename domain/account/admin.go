@@ -0,0 +1,282 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/response"
+)
+
+// Errors of accounts restricted by an admin.
+var (
+	ErrAccountSuspended      = fmt.Errorf("account is suspended")
+	ErrPasswordResetRequired = fmt.Errorf("password reset is required")
+	ErrUnknownRole           = fmt.Errorf("role does not exist")
+)
+
+// RoleRepository is the part of the authz storage an account needs when its role is assigned.
+// It is implemented by the authz domain.
+type RoleRepository interface {
+	RoleExists(ctx context.Context, role string) (exists bool, err error)
+}
+
+const (
+	defaultAdminAccountListLimit = 20
+	adminAuditLogListLimit       = 100
+)
+
+// AdminListAccounts lists accounts page by page, searched by email or name.
+func (u *accountUsecaseImpl) AdminListAccounts(ctx context.Context, claims entity.AccountStandardJWTClaims, params AdminListAccountsRequest) (resp response.Response) {
+	filter := AccountFilter{
+		Query:  params.Query,
+		Role:   params.Role,
+		Status: params.Status,
+		Cursor: params.Cursor,
+		Limit:  params.Limit,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAdminAccountListLimit
+	}
+
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	bunchOfAccounts, err := u.repository.FindMany(ctx, filter)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	pagination := response.Pagination{}
+	if len(bunchOfAccounts) > limit {
+		bunchOfAccounts = bunchOfAccounts[:limit]
+		pagination.HasMore = true
+		pagination.NextCursor = strconv.FormatInt(bunchOfAccounts[limit-1].ID, 10)
+	}
+
+	for i := range bunchOfAccounts {
+		bunchOfAccounts[i].Password = nil
+	}
+	if bunchOfAccounts == nil {
+		bunchOfAccounts = []Account{}
+	}
+
+	u.audit(ctx, claims, AuditActionListAccounts, 0, params)
+
+	return response.SuccessWithPagination(response.StatusOK, bunchOfAccounts, pagination)
+}
+
+// AdminGetAccount returns an account whatever its state.
+func (u *accountUsecaseImpl) AdminGetAccount(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response) {
+	account, resp := u.findAccountByID(ctx, ID)
+	if resp != nil {
+		return
+	}
+
+	u.audit(ctx, claims, AuditActionViewAccount, account.ID, nil)
+
+	account.Password = nil
+
+	return response.Success(response.StatusOK, account)
+}
+
+// AdminSuspendAccount refuses every login of the account until it is unsuspended and signs it out everywhere.
+func (u *accountUsecaseImpl) AdminSuspendAccount(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64, params AdminSuspendAccountRequest) (resp response.Response) {
+	if claims.Subject == strconv.FormatInt(ID, 10) {
+		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+	}
+
+	account, resp := u.findAccountByID(ctx, ID)
+	if resp != nil {
+		return
+	}
+
+	if account.SuspendedAt != nil {
+		return response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
+	}
+
+	suspendedAt := time.Now().In(u.location)
+	err := u.repository.UpdateSuspendedAt(ctx, account.ID, &suspendedAt)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.revokeAllTokens(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	u.audit(ctx, claims, AuditActionSuspendAccount, account.ID, params)
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// AdminUnsuspendAccount lifts the suspension of the account.
+func (u *accountUsecaseImpl) AdminUnsuspendAccount(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response) {
+	account, resp := u.findAccountByID(ctx, ID)
+	if resp != nil {
+		return
+	}
+
+	if account.SuspendedAt == nil {
+		return response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
+	}
+
+	err := u.repository.UpdateSuspendedAt(ctx, account.ID, nil)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	u.audit(ctx, claims, AuditActionUnsuspendAccount, account.ID, nil)
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// AdminForceLogout revokes every access and refresh token of the account issued until now and its api keys.
+func (u *accountUsecaseImpl) AdminForceLogout(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response) {
+	account, resp := u.findAccountByID(ctx, ID)
+	if resp != nil {
+		return
+	}
+
+	err := u.revokeAllTokens(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.revokeAPIKeys(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	u.audit(ctx, claims, AuditActionForceLogout, account.ID, nil)
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// AdminForcePasswordReset signs the account out everywhere, api keys included, and refuses its login until
+// the password is reset with the token mailed to it.
+func (u *accountUsecaseImpl) AdminForcePasswordReset(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response) {
+	account, resp := u.findAccountByID(ctx, ID)
+	if resp != nil {
+		return
+	}
+
+	requiredAt := time.Now().In(u.location)
+	err := u.repository.UpdatePasswordResetRequiredAt(ctx, account.ID, &requiredAt)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.revokeAllTokens(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.revokeAPIKeys(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	u.audit(ctx, claims, AuditActionForcePasswordReset, account.ID, nil)
+
+	err = u.sendPasswordReset(ctx, account)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// AdminAssignRole replaces the role of the account and signs it out everywhere,
+// as the role claim of its tokens would otherwise stay valid until they expire.
+func (u *accountUsecaseImpl) AdminAssignRole(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64, params AdminAssignRoleRequest) (resp response.Response) {
+	if claims.Subject == strconv.FormatInt(ID, 10) {
+		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+	}
+
+	account, resp := u.findAccountByID(ctx, ID)
+	if resp != nil {
+		return
+	}
+
+	if account.Role == params.Role {
+		return response.Success(response.StatusOK, nil)
+	}
+
+	exists, err := u.roleRepository.RoleExists(ctx, params.Role)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if !exists {
+		return response.Error(response.StatusInvalidPayload, nil, ErrUnknownRole)
+	}
+
+	err = u.repository.UpdateRole(ctx, account.ID, params.Role)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.revokeAllTokens(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	u.audit(ctx, claims, AuditActionAssignRole, account.ID, map[string]string{"from": account.Role, "to": params.Role})
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// AdminListAuditLogs returns the latest admin actions on the account.
+func (u *accountUsecaseImpl) AdminListAuditLogs(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response) {
+	bunchOfAuditLogs, err := u.repository.FindAuditLogsByTargetID(ctx, ID, adminAuditLogListLimit)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if bunchOfAuditLogs == nil {
+		bunchOfAuditLogs = []AuditLog{}
+	}
+
+	u.audit(ctx, claims, AuditActionViewAuditLogs, ID, nil)
+
+	return response.Success(response.StatusOK, bunchOfAuditLogs)
+}
+
+// audit records an admin action, a failure is only logged as the action has already been done.
+func (u *accountUsecaseImpl) audit(ctx context.Context, claims entity.AccountStandardJWTClaims, action string, targetID int64, detail interface{}) {
+	actorID, _ := strconv.ParseInt(claims.Subject, 10, 64)
+	auditLog := AuditLog{
+		ActorID:   actorID,
+		Action:    action,
+		TargetID:  targetID,
+		Detail:    "{}",
+		CreatedAt: time.Now().In(u.location),
+	}
+	if detail != nil {
+		buff, _ := json.Marshal(detail)
+		auditLog.Detail = string(buff)
+	}
+	log.Printf("admin %d: %s on account %d\n", auditLog.ActorID, auditLog.Action, auditLog.TargetID)
+
+	if _, err := u.repository.SaveAuditLog(ctx, auditLog); err != nil {
+		log.Println(err)
+	}
+}
+
+// findAccountByID returns the account, otherwise the response to send back.
+func (u *accountUsecaseImpl) findAccountByID(ctx context.Context, ID int64) (account Account, resp response.Response) {
+	account, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return account, response.Error(response.StatusNotFound, nil, exception.ErrNotFound)
+		}
+		return account, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return account, nil
+}
@@ -0,0 +1,257 @@
+package account
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/sangianpatrick/devoria-article-service/domain/authz"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+	"github.com/sangianpatrick/devoria-article-service/response"
+)
+
+type AccountAdminHTTPHandler struct {
+	Validate *validator.Validate
+	Usecase  AccountUsecase
+}
+
+// NewAccountAdminHTTPHandler registers the account management routes, they are only let through
+// for a valid token whose role is granted the permission of the route.
+func NewAccountAdminHTTPHandler(
+	router *mux.Router,
+	jwtAuth jwt.JwtMiddleware,
+	authorizer authz.Authorizer,
+	validate *validator.Validate,
+	usecase AccountUsecase,
+) {
+	handler := &AccountAdminHTTPHandler{
+		Validate: validate,
+		Usecase:  usecase,
+	}
+
+	require := func(permission string, next http.HandlerFunc) http.HandlerFunc {
+		return jwtAuth.VerifyToken(authorizer.Require(permission).Verify(next))
+	}
+
+	router.HandleFunc("/v1/admin/accounts", require(entity.PermissionAccountsRead, handler.ListAccounts)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/admin/accounts/{id}", require(entity.PermissionAccountsRead, handler.GetAccount)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/admin/accounts/{id}/suspend", require(entity.PermissionAccountsManage, handler.SuspendAccount)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/admin/accounts/{id}/unsuspend", require(entity.PermissionAccountsManage, handler.UnsuspendAccount)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/admin/accounts/{id}/logout", require(entity.PermissionAccountsManage, handler.ForceLogout)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/admin/accounts/{id}/password-reset", require(entity.PermissionAccountsManage, handler.ForcePasswordReset)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/admin/accounts/{id}/role", require(entity.PermissionRolesAssign, handler.AssignRole)).Methods(http.MethodPut)
+	router.HandleFunc("/v1/admin/accounts/{id}/audit-logs", require(entity.PermissionAccountsRead, handler.ListAuditLogs)).Methods(http.MethodGet)
+}
+
+func (handler *AccountAdminHTTPHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	params, err := parseAdminListAccountsRequest(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	claims, err := getClaims(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.AdminListAccounts(ctx, claims, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountAdminHTTPHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	ID, claims, err := getAdminTarget(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.AdminGetAccount(ctx, claims, ID)
+	resp.JSON(w)
+}
+
+func (handler *AccountAdminHTTPHandler) SuspendAccount(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AdminSuspendAccountRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	ID, claims, err := getAdminTarget(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.AdminSuspendAccount(ctx, claims, ID, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountAdminHTTPHandler) UnsuspendAccount(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	ID, claims, err := getAdminTarget(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.AdminUnsuspendAccount(ctx, claims, ID)
+	resp.JSON(w)
+}
+
+func (handler *AccountAdminHTTPHandler) ForceLogout(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	ID, claims, err := getAdminTarget(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.AdminForceLogout(ctx, claims, ID)
+	resp.JSON(w)
+}
+
+func (handler *AccountAdminHTTPHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	ID, claims, err := getAdminTarget(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.AdminForcePasswordReset(ctx, claims, ID)
+	resp.JSON(w)
+}
+
+func (handler *AccountAdminHTTPHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AdminAssignRoleRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	ID, claims, err := getAdminTarget(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.AdminAssignRole(ctx, claims, ID, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountAdminHTTPHandler) ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	ID, claims, err := getAdminTarget(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.AdminListAuditLogs(ctx, claims, ID)
+	resp.JSON(w)
+}
+
+// getClaims returns the claims bound to the request by the jwt middleware.
+func getClaims(r *http.Request) (claims entity.AccountStandardJWTClaims, err error) {
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	return
+}
+
+// getAdminTarget returns the ID of the account in the path along with the claims of the admin.
+func getAdminTarget(r *http.Request) (ID int64, claims entity.AccountStandardJWTClaims, err error) {
+	ID, err = strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return
+	}
+
+	claims, err = getClaims(r)
+	return
+}
+
+func parseAdminListAccountsRequest(r *http.Request) (params AdminListAccountsRequest, err error) {
+	query := r.URL.Query()
+
+	params.Query = query.Get("q")
+	params.Role = query.Get("role")
+	params.Status = query.Get("status")
+
+	if v := query.Get("cursor"); v != "" {
+		if params.Cursor, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if params.Limit, err = strconv.Atoi(v); err != nil {
+			return
+		}
+	}
+
+	return
+}
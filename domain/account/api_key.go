@@ -0,0 +1,101 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/response"
+)
+
+// ErrAPIKeyLimitReached is returned when an account already holds as many api keys as it may.
+var ErrAPIKeyLimitReached = fmt.Errorf("api key limit reached")
+
+const (
+	apiKeyByteSize       = 32
+	apiKeyPrefixLength   = len(APIKeyPrefix) + 8
+	maxAPIKeysPerAccount = 20
+)
+
+// CreateAPIKey issues a named personal api key restricted to the given scopes. The key is returned once,
+// only its hash is stored.
+func (u *accountUsecaseImpl) CreateAPIKey(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountCreateAPIKeyRequest) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	bunchOfAPIKeys, err := u.repository.FindAPIKeysByAccountID(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if len(bunchOfAPIKeys) >= maxAPIKeysPerAccount {
+		return response.Error(response.StatusConflicted, nil, ErrAPIKeyLimitReached)
+	}
+
+	secret := u.generateBase64String(apiKeyByteSize)
+	if secret == "" {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	key := APIKeyPrefix + secret
+
+	apiKey := APIKey{
+		AccountID: account.ID,
+		Name:      params.Name,
+		Prefix:    key[:apiKeyPrefixLength],
+		KeyHash:   hashToken(key),
+		Scopes:    params.Scopes,
+		CreatedAt: time.Now().In(u.location),
+	}
+
+	apiKey.ID, err = u.repository.SaveAPIKey(ctx, apiKey)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusCreated, AccountAPIKeyResponse{APIKey: apiKey, Key: key})
+}
+
+// ListAPIKeys returns the personal api keys of the account without the keys themselves.
+func (u *accountUsecaseImpl) ListAPIKeys(ctx context.Context, claims entity.AccountStandardJWTClaims) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	bunchOfAPIKeys, err := u.repository.FindAPIKeysByAccountID(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if bunchOfAPIKeys == nil {
+		bunchOfAPIKeys = []APIKey{}
+	}
+
+	return response.Success(response.StatusOK, bunchOfAPIKeys)
+}
+
+// RevokeAPIKey removes a personal api key of the account, the key of another account is reported as not found.
+func (u *accountUsecaseImpl) RevokeAPIKey(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	err := u.repository.DeleteAPIKey(ctx, account.ID, ID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusNotFound, nil, exception.ErrNotFound)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// revokeAPIKeys removes every api key of the account. Signing out everywhere has to reach them too,
+// they do not expire and are not bound to a device session.
+func (u *accountUsecaseImpl) revokeAPIKeys(ctx context.Context, accountID int64) (err error) {
+	return u.repository.DeleteAPIKeysByAccountID(ctx, accountID)
+}
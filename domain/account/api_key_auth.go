@@ -0,0 +1,101 @@
+package account
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/context"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+	"github.com/sangianpatrick/devoria-article-service/response"
+)
+
+const (
+	apiKeyAuthScheme = "ApiKey "
+	// apiKeyLastUsedPrecision keeps a busy key from writing its last use on every request.
+	apiKeyLastUsedPrecision = time.Minute
+)
+
+// APIKeyAuth accepts personal api keys sent as "Authorization: ApiKey <key>" and hands every other request
+// to the jwt middleware. Both bind the same claims, those of an api key carry its scopes.
+type APIKeyAuth struct {
+	jwtAuth    jwt.JwtMiddleware
+	location   *time.Location
+	repository AccountRepository
+}
+
+func NewAPIKeyAuth(jwtAuth jwt.JwtMiddleware, location *time.Location, repository AccountRepository) jwt.JwtMiddleware {
+	return &APIKeyAuth{jwtAuth: jwtAuth, location: location, repository: repository}
+}
+
+func (a *APIKeyAuth) VerifyToken(next http.HandlerFunc) http.HandlerFunc {
+	verifyJWT := a.jwtAuth.VerifyToken(next)
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var resp response.Response
+		authorization := request.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, apiKeyAuthScheme) {
+			verifyJWT(writer, request)
+			return
+		}
+		key := strings.TrimSpace(strings.TrimPrefix(authorization, apiKeyAuthScheme))
+		ctx := request.Context()
+
+		apiKey, err := a.repository.FindAPIKeyByHash(ctx, hashToken(key))
+		if err == nil && len(apiKey.Scopes) == 0 {
+			// claims without scopes would be allowed everything, a key must never yield them.
+			err = exception.ErrNotFound
+		}
+		if err != nil {
+			if err == exception.ErrNotFound {
+				resp = response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+				resp.JSON(writer)
+				return
+			}
+			resp = response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+			resp.JSON(writer)
+			return
+		}
+
+		account, err := a.repository.FindByID(ctx, apiKey.AccountID)
+		if err != nil {
+			if err == exception.ErrNotFound {
+				resp = response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+				resp.JSON(writer)
+				return
+			}
+			resp = response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+			resp.JSON(writer)
+			return
+		}
+		if account.DeletedAt != nil || account.SuspendedAt != nil {
+			resp = response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+			resp.JSON(writer)
+			return
+		}
+
+		now := time.Now().In(a.location)
+		if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyLastUsedPrecision {
+			if err = a.repository.UpdateAPIKeyLastUsedAt(ctx, apiKey.ID, now); err != nil {
+				log.Println(err)
+			}
+		}
+
+		claims := entity.AccountStandardJWTClaims{}
+		claims.Subject = strconv.FormatInt(account.ID, 10)
+		claims.Email = account.Email
+		claims.Role = account.Role
+		claims.EmailVerified = account.VerifiedAt != nil
+		claims.Scopes = apiKey.Scopes
+		claims.IssuedAt = now.Unix()
+
+		byt, _ := json.Marshal(claims)
+		context.Set(request, "bind", byt)
+		next.ServeHTTP(writer, request)
+	}
+}
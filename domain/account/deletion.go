@@ -0,0 +1,202 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/mailer"
+	"github.com/sangianpatrick/devoria-article-service/response"
+)
+
+// ErrAccountDeleted is returned when an account scheduled for its purge tries to sign in.
+var ErrAccountDeleted = fmt.Errorf("account is deleted")
+
+// ErrAccountNotDeleted is returned when an account that is not scheduled for its purge is restored.
+var ErrAccountNotDeleted = fmt.Errorf("account is not deleted")
+
+// ErrAccountPurgeDue is returned when an account is restored after its grace period, its purge may already be running.
+var ErrAccountPurgeDue = fmt.Errorf("account purge is due")
+
+const exportFormatZIP = "zip"
+
+// AuthoredArticleRepository is the part of the article storage an account needs when it is exported or purged.
+// It is implemented by the article domain, which depends on this one.
+type AuthoredArticleRepository interface {
+	FindByAuthorID(ctx context.Context, authorID int64) (bunchOfArticles []AuthoredArticle, err error)
+	DeleteByAuthorID(ctx context.Context, authorID int64) (err error)
+	AnonymiseByAuthorID(ctx context.Context, authorID int64) (err error)
+}
+
+// DeleteAccount schedules the account for its purge after checking the password, then signs it out everywhere.
+// Nothing is removed until the grace period is over, see AccountPurger.
+func (u *accountUsecaseImpl) DeleteAccount(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountDeletionRequest) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	if account.DeletedAt != nil {
+		return response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
+	}
+
+	err := u.verifyPassword(ctx, account, params.Password)
+	if err != nil {
+		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+	}
+
+	deletedAt := time.Now().In(u.location)
+	err = u.repository.MarkDeleted(ctx, account.ID, deletedAt, params.Articles)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	// the account can no longer sign in or refresh, so a failed revocation only leaves short-lived access tokens behind.
+	if err = u.revokeAllTokens(ctx, account.ID); err != nil {
+		log.Println(err)
+	}
+
+	purgeAt := deletedAt.Add(u.policy.DeletionGracePeriod)
+	err = u.mailer.Send(ctx, mailer.Mail{
+		To:      account.Email,
+		Subject: "Your account is scheduled for deletion",
+		Body:    fmt.Sprintf("Hi %s,\n\nyour account and its personal data will be deleted on %s.\nRestore it with your password before then if you did not ask for it.", account.FirstName, purgeAt.Format(time.RFC1123)),
+	})
+	if err != nil {
+		log.Println(err)
+	}
+
+	return response.Success(response.StatusOK, AccountDeletionResponse{PurgeAt: purgeAt})
+}
+
+// RestoreAccount cancels the deletion of the account during its grace period after checking the password.
+// The account signs in again on its own, the password attempts are counted like the ones of a login.
+func (u *accountUsecaseImpl) RestoreAccount(ctx context.Context, params AccountAuthenticationRequest) (resp response.Response) {
+	attempt, retryAfter, err := u.attemptLogin(ctx, params)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if retryAfter > 0 {
+		return response.ErrorWithRetryAfter(response.StatusTooManyRequests, nil, ErrTooManyRequests, retryAfter)
+	}
+
+	account, err := u.repository.FindByEmail(ctx, params.Email)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			u.failLogin(ctx, attempt)
+			return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.verifyPassword(ctx, account, params.Password)
+	if err != nil {
+		u.failLogin(ctx, attempt)
+		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+	}
+
+	u.passLogin(ctx, attempt)
+
+	if account.DeletedAt == nil {
+		return response.Error(response.StatusConflicted, nil, ErrAccountNotDeleted)
+	}
+
+	// the purger picks the account up once the grace period is over, restoring it then would race with it.
+	deletedAfter := time.Now().In(u.location).Add(-u.policy.DeletionGracePeriod)
+	err = u.repository.Restore(ctx, account.ID, deletedAfter)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusConflicted, nil, ErrAccountPurgeDue)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.mailer.Send(ctx, mailer.Mail{
+		To:      account.Email,
+		Subject: "Your account is restored",
+		Body:    fmt.Sprintf("Hi %s,\n\nyour account is no longer scheduled for deletion.\nChange your password if you did not restore it.", account.FirstName),
+	})
+	if err != nil {
+		log.Println(err)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// ExportData returns the profile and every authored article of the account, either as json or as a zip bundle.
+func (u *accountUsecaseImpl) ExportData(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountExportRequest) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	articles, err := u.authoredArticleRepository.FindByAuthorID(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if articles == nil {
+		articles = []AuthoredArticle{}
+	}
+
+	account.Password = nil
+
+	export := AccountExportResponse{
+		Profile:    account,
+		Articles:   articles,
+		ExportedAt: time.Now().In(u.location),
+	}
+
+	if params.Format != exportFormatZIP {
+		return response.Success(response.StatusOK, export)
+	}
+
+	content, err := zipExport(export)
+	if err != nil {
+		log.Println(err)
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Attachment(fmt.Sprintf("account-%d-export.zip", account.ID), "application/zip", content)
+}
+
+// zipExport bundles the profile and the articles as separate json files.
+func zipExport(export AccountExportResponse) (content []byte, err error) {
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"articles.json", export.Articles},
+	}
+
+	var buff bytes.Buffer
+	archive := zip.NewWriter(&buff)
+	for _, file := range files {
+		header := &zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt}
+		w, err := archive.CreateHeader(header)
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
@@ -0,0 +1,75 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/response"
+)
+
+// ListSessions returns the signed in devices of the account, the one making the request is flagged as current.
+func (u *accountUsecaseImpl) ListSessions(ctx context.Context, claims entity.AccountStandardJWTClaims) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	deviceSessions, err := u.deviceSessionRepository.FindByAccountID(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	sessionResponses := make([]AccountSessionResponse, 0, len(deviceSessions))
+	for _, deviceSession := range deviceSessions {
+		sessionResponses = append(sessionResponses, AccountSessionResponse{
+			DeviceSession: deviceSession,
+			Current:       deviceSession.ID == claims.SessionID,
+		})
+	}
+
+	return response.Success(response.StatusOK, sessionResponses)
+}
+
+// RevokeSession signs a device of the account out, its access token stops working on the next request.
+func (u *accountUsecaseImpl) RevokeSession(ctx context.Context, claims entity.AccountStandardJWTClaims, ID string) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	deviceSession, err := u.deviceSessionRepository.FindByID(ctx, ID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusNotFound, nil, exception.ErrNotFound)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	// a session of another account is reported as missing, its ID must not be confirmed.
+	if deviceSession.AccountID != account.ID {
+		return response.Error(response.StatusNotFound, nil, exception.ErrNotFound)
+	}
+
+	if resp = u.deleteDeviceSession(ctx, account.ID, deviceSession.ID); resp != nil {
+		return
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// deleteDeviceSession drops the device session and its refresh token family, it returns nil on success.
+func (u *accountUsecaseImpl) deleteDeviceSession(ctx context.Context, accountID int64, ID string) (resp response.Response) {
+	err := u.deviceSessionRepository.Delete(ctx, accountID, ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if err = u.refreshTokenSession.Delete(ctx, fmt.Sprintf(AccountRefreshTokenFamilyKeyFormat, ID)); err != nil {
+		log.Println(err)
+	}
+
+	return nil
+}
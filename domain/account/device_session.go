@@ -0,0 +1,162 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	rv8 "github.com/go-redis/redis/v8"
+
+	"github.com/sangianpatrick/devoria-article-service/exception"
+)
+
+// DeviceSessionRepository stores the signed in devices of the accounts.
+type DeviceSessionRepository interface {
+	Save(ctx context.Context, deviceSession DeviceSession) (err error)
+	FindByID(ctx context.Context, ID string) (deviceSession DeviceSession, err error)
+	FindByAccountID(ctx context.Context, accountID int64) (deviceSessions []DeviceSession, err error)
+	Delete(ctx context.Context, accountID int64, ID string) (err error)
+	DeleteByAccountID(ctx context.Context, accountID int64) (err error)
+	IsSessionActive(ctx context.Context, subject string, sessionID string) (active bool, err error)
+}
+
+type deviceSessionRepositoryImpl struct {
+	c      rv8.UniversalClient
+	maxAge time.Duration
+}
+
+// NewDeviceSessionRepository is a constructor, a device session expires maxAge after it was last seen.
+func NewDeviceSessionRepository(rdb rv8.UniversalClient, maxAge time.Duration) DeviceSessionRepository {
+	return &deviceSessionRepositoryImpl{
+		c:      rdb,
+		maxAge: maxAge,
+	}
+}
+
+func (r *deviceSessionRepositoryImpl) Save(ctx context.Context, deviceSession DeviceSession) (err error) {
+	buff, _ := json.Marshal(deviceSession)
+	indexKey := fmt.Sprintf(AccountDeviceSessionIndexKeyFormat, deviceSession.AccountID)
+
+	if err = r.c.Set(ctx, fmt.Sprintf(AccountDeviceSessionKeyFormat, deviceSession.ID), buff, r.maxAge).Err(); err != nil {
+		log.Println(err)
+		return exception.ErrInternalServer
+	}
+	if err = r.c.SAdd(ctx, indexKey, deviceSession.ID).Err(); err != nil {
+		log.Println(err)
+		return exception.ErrInternalServer
+	}
+	if err = r.c.Expire(ctx, indexKey, r.maxAge).Err(); err != nil {
+		log.Println(err)
+		return exception.ErrInternalServer
+	}
+
+	return
+}
+
+func (r *deviceSessionRepositoryImpl) FindByID(ctx context.Context, ID string) (deviceSession DeviceSession, err error) {
+	buff, err := r.c.Get(ctx, fmt.Sprintf(AccountDeviceSessionKeyFormat, ID)).Bytes()
+	if err != nil {
+		if err == rv8.Nil {
+			return deviceSession, exception.ErrNotFound
+		}
+		log.Println(err)
+		return deviceSession, exception.ErrInternalServer
+	}
+
+	if err = json.Unmarshal(buff, &deviceSession); err != nil {
+		log.Println(err)
+		return deviceSession, exception.ErrInternalServer
+	}
+
+	return
+}
+
+// FindByAccountID returns the live device sessions of the account, the most recently seen first.
+// Expired sessions still listed in the index are removed from it on the way.
+func (r *deviceSessionRepositoryImpl) FindByAccountID(ctx context.Context, accountID int64) (deviceSessions []DeviceSession, err error) {
+	indexKey := fmt.Sprintf(AccountDeviceSessionIndexKeyFormat, accountID)
+
+	IDs, err := r.c.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		log.Println(err)
+		return nil, exception.ErrInternalServer
+	}
+
+	deviceSessions = make([]DeviceSession, 0, len(IDs))
+	for _, ID := range IDs {
+		deviceSession, err := r.FindByID(ctx, ID)
+		if err == exception.ErrNotFound {
+			if err = r.c.SRem(ctx, indexKey, ID).Err(); err != nil {
+				log.Println(err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		deviceSessions = append(deviceSessions, deviceSession)
+	}
+
+	sort.Slice(deviceSessions, func(i, j int) bool {
+		return deviceSessions[i].LastSeenAt.After(deviceSessions[j].LastSeenAt)
+	})
+
+	return
+}
+
+func (r *deviceSessionRepositoryImpl) Delete(ctx context.Context, accountID int64, ID string) (err error) {
+	if err = r.c.Del(ctx, fmt.Sprintf(AccountDeviceSessionKeyFormat, ID)).Err(); err != nil {
+		log.Println(err)
+		return exception.ErrInternalServer
+	}
+	if err = r.c.SRem(ctx, fmt.Sprintf(AccountDeviceSessionIndexKeyFormat, accountID), ID).Err(); err != nil {
+		log.Println(err)
+		return exception.ErrInternalServer
+	}
+
+	return
+}
+
+func (r *deviceSessionRepositoryImpl) DeleteByAccountID(ctx context.Context, accountID int64) (err error) {
+	indexKey := fmt.Sprintf(AccountDeviceSessionIndexKeyFormat, accountID)
+
+	IDs, err := r.c.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		log.Println(err)
+		return exception.ErrInternalServer
+	}
+
+	keys := []string{indexKey}
+	for _, ID := range IDs {
+		keys = append(keys, fmt.Sprintf(AccountDeviceSessionKeyFormat, ID))
+	}
+
+	if err = r.c.Del(ctx, keys...).Err(); err != nil {
+		log.Println(err)
+		return exception.ErrInternalServer
+	}
+
+	return
+}
+
+// IsSessionActive tells the jwt middleware whether the session of a token still exists and belongs to its subject.
+func (r *deviceSessionRepositoryImpl) IsSessionActive(ctx context.Context, subject string, sessionID string) (active bool, err error) {
+	if sessionID == "" {
+		return false, nil
+	}
+
+	deviceSession, err := r.FindByID(ctx, sessionID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return strconv.FormatInt(deviceSession.AccountID, 10) == subject, nil
+}
@@ -0,0 +1,205 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/mailer"
+	"github.com/sangianpatrick/devoria-article-service/response"
+	"github.com/sangianpatrick/devoria-article-service/session"
+)
+
+const emailChangeTokenByteSize = 32
+
+// ChangeEmail starts an email change after checking the password. The new address gets a confirmation link,
+// the old one a notification with a link to undo the change, which keeps working after it is confirmed.
+func (u *accountUsecaseImpl) ChangeEmail(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountChangeEmailRequest) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	if strings.EqualFold(account.Email, params.NewEmail) {
+		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+	}
+
+	err := u.verifyPassword(ctx, account, params.Password)
+	if err != nil {
+		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+	}
+
+	// the unique email index has the final say when the change is confirmed, this only spares a pointless mail.
+	_, err = u.repository.FindByEmail(ctx, params.NewEmail)
+	if err == nil {
+		return response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
+	}
+	if err != exception.ErrNotFound {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	confirmToken := u.generateBase64String(emailChangeTokenByteSize)
+	undoToken := u.generateBase64String(emailChangeTokenByteSize)
+	if confirmToken == "" || undoToken == "" {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	// only the latest change can be confirmed, the links of an earlier one stop working.
+	if err = u.dropPendingEmailChange(ctx, account.ID); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	buff, _ := json.Marshal(EmailChange{
+		AccountID:        account.ID,
+		OldEmail:         account.Email,
+		NewEmail:         params.NewEmail,
+		ConfirmTokenHash: hashToken(confirmToken),
+		UndoTokenHash:    hashToken(undoToken),
+	})
+
+	err = u.emailVerificationSession.Set(ctx, fmt.Sprintf(AccountEmailChangePendingKeyFormat, account.ID), buff)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.emailVerificationSession.Set(ctx, fmt.Sprintf(AccountEmailChangeKeyFormat, hashToken(confirmToken)), buff)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.emailChangeUndoSession.Set(ctx, fmt.Sprintf(AccountEmailChangeUndoKeyFormat, hashToken(undoToken)), buff)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	mails := []mailer.Mail{
+		{
+			To:      params.NewEmail,
+			Subject: "Confirm your new email",
+			Body:    fmt.Sprintf("Hi %s,\n\nuse the link below to confirm %s as the new email of your account:\n%s/confirm-email-change?token=%s\n\nIf you did not ask for it, you can ignore this mail.", account.FirstName, params.NewEmail, u.frontendURL, confirmToken),
+		},
+		{
+			To:      account.Email,
+			Subject: "Your email is being changed",
+			Body:    fmt.Sprintf("Hi %s,\n\nthe email of your account is being changed to %s.\nIf you did not ask for it, use the link below to undo the change and then reset your password:\n%s/undo-email-change?token=%s", account.FirstName, params.NewEmail, u.frontendURL, undoToken),
+		},
+	}
+	for _, mail := range mails {
+		if err = u.mailer.Send(ctx, mail); err != nil {
+			log.Println(err)
+		}
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// ConfirmEmailChange consumes the confirmation token and moves the account to the new email, which is verified by then.
+// Every token issued until now carries the old email, so the account is signed out everywhere.
+func (u *accountUsecaseImpl) ConfirmEmailChange(ctx context.Context, params AccountConfirmEmailChangeRequest) (resp response.Response) {
+	change, resp := u.consumeEmailChange(ctx, u.emailVerificationSession, fmt.Sprintf(AccountEmailChangeKeyFormat, hashToken(params.Token)))
+	if resp != nil {
+		return
+	}
+	u.forgetPendingEmailChange(ctx, change.AccountID)
+
+	return u.swapEmail(ctx, change.AccountID, change.OldEmail, change.NewEmail)
+}
+
+// UndoEmailChange consumes the undo token, it cancels the change while it is pending and reverts it once confirmed.
+func (u *accountUsecaseImpl) UndoEmailChange(ctx context.Context, params AccountUndoEmailChangeRequest) (resp response.Response) {
+	change, resp := u.consumeEmailChange(ctx, u.emailChangeUndoSession, fmt.Sprintf(AccountEmailChangeUndoKeyFormat, hashToken(params.Token)))
+	if resp != nil {
+		return
+	}
+
+	// a change still pending is cancelled by taking its confirmation away, a confirmed one is reverted.
+	_, err := u.emailVerificationSession.Consume(ctx, fmt.Sprintf(AccountEmailChangeKeyFormat, change.ConfirmTokenHash))
+	if err == nil {
+		u.forgetPendingEmailChange(ctx, change.AccountID)
+		return response.Success(response.StatusOK, nil)
+	}
+	if err != session.ErrSessionNotFound {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return u.swapEmail(ctx, change.AccountID, change.NewEmail, change.OldEmail)
+}
+
+// consumeEmailChange takes the stored email change out of the session.
+func (u *accountUsecaseImpl) consumeEmailChange(ctx context.Context, sess session.Session, key string) (change EmailChange, resp response.Response) {
+	buff, err := sess.Consume(ctx, key)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return change, response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+		}
+		return change, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if err = json.Unmarshal(buff, &change); err != nil {
+		return change, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return change, nil
+}
+
+// swapEmail moves the account from one email to the other, following the link proves the target address is owned.
+func (u *accountUsecaseImpl) swapEmail(ctx context.Context, accountID int64, fromEmail string, toEmail string) (resp response.Response) {
+	err := u.repository.UpdateEmail(ctx, accountID, fromEmail, toEmail, time.Now().In(u.location))
+	if err != nil {
+		switch err {
+		case exception.ErrNotFound:
+			return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+		case exception.ErrConflicted:
+			return response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
+		default:
+			return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+		}
+	}
+
+	if err = u.revokeAllTokens(ctx, accountID); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// dropPendingEmailChange removes the email change the account is waiting to confirm along with its links.
+func (u *accountUsecaseImpl) dropPendingEmailChange(ctx context.Context, accountID int64) (err error) {
+	buff, err := u.emailVerificationSession.Consume(ctx, fmt.Sprintf(AccountEmailChangePendingKeyFormat, accountID))
+	if err == session.ErrSessionNotFound {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+
+	var change EmailChange
+	if err = json.Unmarshal(buff, &change); err != nil {
+		return
+	}
+
+	// either link may already be used or expired.
+	_, err = u.emailVerificationSession.Consume(ctx, fmt.Sprintf(AccountEmailChangeKeyFormat, change.ConfirmTokenHash))
+	if err != nil && err != session.ErrSessionNotFound {
+		return
+	}
+	_, err = u.emailChangeUndoSession.Consume(ctx, fmt.Sprintf(AccountEmailChangeUndoKeyFormat, change.UndoTokenHash))
+	if err != nil && err != session.ErrSessionNotFound {
+		return
+	}
+
+	return nil
+}
+
+// forgetPendingEmailChange removes the record of a change that is no longer pending, its undo link keeps working.
+func (u *accountUsecaseImpl) forgetPendingEmailChange(ctx context.Context, accountID int64) {
+	_, err := u.emailVerificationSession.Consume(ctx, fmt.Sprintf(AccountEmailChangePendingKeyFormat, accountID))
+	if err != nil && err != session.ErrSessionNotFound {
+		log.Println(err)
+	}
+}
@@ -4,7 +4,45 @@ import (
 	"time"
 )
 
-const AccountSessionKeyFormat = "account:session:%s"
+// Key formats of device sessions, keyed by the session ID, and of the session IDs of an account.
+const (
+	AccountDeviceSessionKeyFormat      = "account:device-session:%s"
+	AccountDeviceSessionIndexKeyFormat = "account:device-sessions:%d"
+)
+
+// AccountPasswordResetKeyFormat is a key format of password reset tokens, keyed by the token hash.
+const AccountPasswordResetKeyFormat = "account:password-reset:%s"
+
+// Key formats of email verification tokens, keyed by the token hash, and of the count of mails sent within the resend interval, keyed by the email hash.
+const (
+	AccountEmailVerificationKeyFormat     = "account:email-verification:%s"
+	AccountEmailVerificationSentKeyFormat = "account:email-verification-sent:%s"
+)
+
+// Key formats of pending email changes and of their undo links, both keyed by the token hash,
+// and of the email change an account is waiting to confirm, keyed by the account id.
+const (
+	AccountEmailChangeKeyFormat        = "account:email-change:%s"
+	AccountEmailChangeUndoKeyFormat    = "account:email-change-undo:%s"
+	AccountEmailChangePendingKeyFormat = "account:email-change-pending:%d"
+)
+
+// Key formats of pending second login steps and of their attempt counters, keyed by the challenge token hash,
+// and of the last accepted totp step of an account, so a code is never accepted twice.
+const (
+	AccountMFAChallengeKeyFormat         = "account:mfa-challenge:%s"
+	AccountMFAChallengeAttemptsKeyFormat = "account:mfa-challenge-attempts:%s"
+	AccountTOTPUsedStepKeyFormat         = "account:totp-used-step:%d"
+)
+
+// Key formats of refresh token states, the refresh token itself is never stored.
+const (
+	AccountRefreshTokenKeyFormat       = "account:refresh-token:%s"
+	AccountRefreshTokenFamilyKeyFormat = "account:refresh-token-family:%s"
+)
+
+// AccountOIDCStateKeyFormat is a key format of pending external provider logins, keyed by the state hash.
+const AccountOIDCStateKeyFormat = "account:oidc-state:%s"
 
 type AccountContextKey struct{}
 
@@ -15,6 +53,164 @@ type Account struct {
 	Password       *string    `json:"password,omitempty"`
 	FirstName      string     `json:"firstName"`
 	LastName       string     `json:"lastName"`
+	Role           string     `json:"role"`
+	VerifiedAt     *time.Time `json:"verifiedAt"`
+	TOTPSecret     *string    `json:"-"`
+	TOTPEnabledAt  *time.Time `json:"totpEnabledAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	LastModifiedAt *time.Time `json:"lastModifiedAt"`
+	// DeletedAt is set while the account waits for its purge, ArticleDisposition tells what happens to its articles then.
+	DeletedAt          *time.Time         `json:"deletedAt"`
+	ArticleDisposition ArticleDisposition `json:"-"`
+	// SuspendedAt and PasswordResetRequiredAt are set by an admin, both refuse the login until they are lifted.
+	SuspendedAt             *time.Time `json:"suspendedAt"`
+	PasswordResetRequiredAt *time.Time `json:"passwordResetRequiredAt"`
+}
+
+// Statuses of account listing.
+const (
+	AccountStatusActive    = "active"
+	AccountStatusSuspended = "suspended"
+	AccountStatusDeleted   = "deleted"
+)
+
+// AccountFilter is a collection of criteria of account listing. Accounts are ordered by ID,
+// the cursor is the ID of the last account of the previous page.
+type AccountFilter struct {
+	Query  string
+	Role   string
+	Status string
+	Cursor int64
+	Limit  int
+}
+
+// ArticleDisposition is what happens to the articles of a deleted account once it is purged.
+type ArticleDisposition string
+
+const (
+	ArticleDispositionDelete    ArticleDisposition = "DELETE"
+	ArticleDispositionAnonymise ArticleDisposition = "ANONYMISE"
+)
+
+// AuthoredArticle is an article of an account as it is exported and purged along with it.
+type AuthoredArticle struct {
+	ID             int64      `json:"id"`
+	Title          string     `json:"title"`
+	Subtitle       string     `json:"subtitle"`
+	Content        string     `json:"content"`
+	Status         string     `json:"status"`
 	CreatedAt      time.Time  `json:"createdAt"`
+	PublishedAt    *time.Time `json:"publishedAt"`
 	LastModifiedAt *time.Time `json:"lastModifiedAt"`
-}
\ No newline at end of file
+}
+
+// AccountPolicy is a collection of configurable account rules.
+type AccountPolicy struct {
+	RequireVerifiedEmailToLogin bool
+	DeletionGracePeriod         time.Duration
+}
+
+// Subjects of login throttling.
+const (
+	LoginSubjectEmail    = "email"
+	LoginSubjectClientIP = "clientIP"
+)
+
+// LoginLockout is an audit record of a login subject locked out after too many failed attempts.
+type LoginLockout struct {
+	ID          int64     `json:"id"`
+	Email       string    `json:"email"`
+	ClientIP    string    `json:"clientIP"`
+	Subject     string    `json:"subject"`
+	Failures    int64     `json:"failures"`
+	LockedUntil time.Time `json:"lockedUntil"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Actions of admin audit logs.
+const (
+	AuditActionListAccounts       = "LIST_ACCOUNTS"
+	AuditActionViewAccount        = "VIEW_ACCOUNT"
+	AuditActionSuspendAccount     = "SUSPEND_ACCOUNT"
+	AuditActionUnsuspendAccount   = "UNSUSPEND_ACCOUNT"
+	AuditActionForceLogout        = "FORCE_LOGOUT"
+	AuditActionForcePasswordReset = "FORCE_PASSWORD_RESET"
+	AuditActionAssignRole         = "ASSIGN_ROLE"
+	AuditActionViewAuditLogs      = "VIEW_AUDIT_LOGS"
+)
+
+// AuditLog is an audit record of an admin action. TargetID is zero for an action on no single account.
+type AuditLog struct {
+	ID        int64     `json:"id"`
+	ActorID   int64     `json:"actorId"`
+	Action    string    `json:"action"`
+	TargetID  int64     `json:"targetId"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// APIKeyPrefix starts every personal api key, so a leaked one is easy to recognise.
+const APIKeyPrefix = "dak_"
+
+// APIKey is a personal api key of an account, only the hash of the key is stored.
+// Prefix is the beginning of the key, enough for its owner to tell the keys apart.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	AccountID  int64      `json:"accountId"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// DeviceSession is a signed in device of an account. Its ID is the family ID of the refresh tokens
+// issued to the device, so the session lives exactly as long as the device can refresh.
+type DeviceSession struct {
+	ID         string    `json:"id"`
+	AccountID  int64     `json:"accountId"`
+	UserAgent  string    `json:"userAgent"`
+	ClientIP   string    `json:"clientIP"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+}
+
+// EmailChange is a stored state of an email change, the confirmation token hash lets the undo link cancel it while pending
+// and both hashes let a newer change or a claim of the account drop its links.
+type EmailChange struct {
+	AccountID        int64  `json:"accountId"`
+	OldEmail         string `json:"oldEmail"`
+	NewEmail         string `json:"newEmail"`
+	ConfirmTokenHash string `json:"confirmTokenHash"`
+	UndoTokenHash    string `json:"undoTokenHash,omitempty"`
+}
+
+// MFAChallenge is a stored state of a login waiting for its second factor.
+type MFAChallenge struct {
+	AccountID int64 `json:"accountId"`
+}
+
+// OIDCState is a stored state of a login sent to the external provider, it is consumed by the callback.
+type OIDCState struct {
+	CodeVerifier string `json:"codeVerifier"`
+	Nonce        string `json:"nonce"`
+}
+
+// AccountIdentity links an account to the subject of an external provider, the subject is unique per issuer.
+type AccountIdentity struct {
+	ID        int64     `json:"id"`
+	AccountID int64     `json:"accountId"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RefreshToken is a stored state of an issued refresh token.
+// Every token rotated from the same login shares the family ID.
+type RefreshToken struct {
+	AccountID int64     `json:"accountId"`
+	FamilyID  string    `json:"familyId"`
+	Used      bool      `json:"used"`
+	CreatedAt time.Time `json:"createdAt"`
+}
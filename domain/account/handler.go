@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/sangianpatrick/devoria-article-service/entity"
+	"io"
+	"net"
 	"github.com/sangianpatrick/devoria-article-service/jwt"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/context"
 	"github.com/go-playground/validator/v10"
@@ -33,7 +36,33 @@ func NewAccountHTTPHandler(
 
 	router.HandleFunc("/v1/account/registration", basicAuthMiddleware.Verify(handler.Register)).Methods(http.MethodPost)
 	router.HandleFunc("/v1/account/login", basicAuthMiddleware.Verify(handler.Login)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/token/refresh", basicAuthMiddleware.Verify(handler.RefreshToken)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/logout", jwtAuth.VerifyToken(handler.Logout)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/logout-all", jwtAuth.VerifyToken(handler.LogoutAll)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/sessions", jwtAuth.VerifyToken(handler.ListSessions)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/account/sessions/{id}", jwtAuth.VerifyToken(handler.RevokeSession)).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/account/api-keys", jwtAuth.VerifyToken(handler.CreateAPIKey)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/api-keys", jwtAuth.VerifyToken(handler.ListAPIKeys)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/account/api-keys/{id}", jwtAuth.VerifyToken(handler.RevokeAPIKey)).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/account/password", jwtAuth.VerifyToken(handler.ChangePassword)).Methods(http.MethodPut)
+	router.HandleFunc("/v1/account/password/forgot", basicAuthMiddleware.Verify(handler.ForgotPassword)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/password/reset", basicAuthMiddleware.Verify(handler.ResetPassword)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/login/mfa", basicAuthMiddleware.Verify(handler.LoginMFA)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/login/oidc", basicAuthMiddleware.Verify(handler.StartOIDCLogin)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/login/oidc/callback", basicAuthMiddleware.Verify(handler.CompleteOIDCLogin)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/mfa/totp", jwtAuth.VerifyToken(handler.EnrolTOTP)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/mfa/totp/confirm", jwtAuth.VerifyToken(handler.ConfirmTOTP)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/mfa/totp", jwtAuth.VerifyToken(handler.DisableTOTP)).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/account/verify", handler.VerifyEmail).Methods(http.MethodGet)
+	router.HandleFunc("/v1/account/email", jwtAuth.VerifyToken(handler.ChangeEmail)).Methods(http.MethodPut)
+	router.HandleFunc("/v1/account/email/confirm", basicAuthMiddleware.Verify(handler.ConfirmEmailChange)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/email/undo", basicAuthMiddleware.Verify(handler.UndoEmailChange)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/verify/resend", basicAuthMiddleware.Verify(handler.ResendEmailVerification)).Methods(http.MethodPost)
 	router.HandleFunc("/v1/account", jwtAuth.VerifyToken(handler.Profile)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/account", jwtAuth.VerifyToken(handler.UpdateProfile)).Methods(http.MethodPatch)
+	router.HandleFunc("/v1/account", jwtAuth.VerifyToken(handler.DeleteAccount)).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/account/restore", basicAuthMiddleware.Verify(handler.RestoreAccount)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/account/export", jwtAuth.VerifyToken(handler.ExportData)).Methods(http.MethodGet)
 
 }
 
@@ -56,6 +85,8 @@ func (handler *AccountHTTPHandler) Register(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	params.Device = device(r)
+
 	resp = handler.Usecase.Register(ctx, params)
 	resp.JSON(w)
 }
@@ -79,10 +110,35 @@ func (handler *AccountHTTPHandler) Login(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	params.Device = device(r)
+
 	resp = handler.Usecase.Login(ctx, params)
 	resp.JSON(w)
 }
 
+func (handler *AccountHTTPHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountRefreshTokenRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.RefreshToken(ctx, params)
+	resp.JSON(w)
+}
+
 func (handler *AccountHTTPHandler) Profile(w http.ResponseWriter, r *http.Request) {
 	var resp response.Response
 	var ctx = r.Context()
@@ -106,3 +162,733 @@ func (handler *AccountHTTPHandler) Profile(w http.ResponseWriter, r *http.Reques
 	resp = handler.Usecase.GetProfile(ctx, claims)
 	resp.JSON(w)
 }
+
+func (handler *AccountHTTPHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountLogoutRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil && err != io.EOF {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.Logout(ctx, claims, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	var err error
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.LogoutAll(ctx, claims)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	var err error
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.ListSessions(ctx, claims)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+	ID := mux.Vars(r)["id"]
+
+	var err error
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.RevokeSession(ctx, claims, ID)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountCreateAPIKeyRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.CreateAPIKey(ctx, claims, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	var err error
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.ListAPIKeys(ctx, claims)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	ID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.RevokeAPIKey(ctx, claims, ID)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountUpdateRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.UpdateProfile(ctx, claims, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountDeletionRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.DeleteAccount(ctx, claims, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) RestoreAccount(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountAuthenticationRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	params.Device = device(r)
+
+	resp = handler.Usecase.RestoreAccount(ctx, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountExportRequest
+	var ctx = r.Context()
+
+	params.Format = r.URL.Query().Get("format")
+
+	err := handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.ExportData(ctx, claims, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountChangePasswordRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.ChangePassword(ctx, claims, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountForgotPasswordRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.ForgotPassword(ctx, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountResetPasswordRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.ResetPassword(ctx, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountChangeEmailRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.ChangeEmail(ctx, claims, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountConfirmEmailChangeRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.ConfirmEmailChange(ctx, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) UndoEmailChange(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountUndoEmailChangeRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.UndoEmailChange(ctx, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountVerifyEmailRequest
+	var ctx = r.Context()
+
+	params.Token = r.URL.Query().Get("token")
+
+	err := handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.VerifyEmail(ctx, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) ResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountResendEmailVerificationRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.ResendEmailVerification(ctx, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountMFALoginRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	params.Device = device(r)
+
+	resp = handler.Usecase.LoginMFA(ctx, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	resp = handler.Usecase.StartOIDCLogin(ctx)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) CompleteOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountOIDCCallbackRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	params.Device = device(r)
+
+	resp = handler.Usecase.CompleteOIDCLogin(ctx, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) EnrolTOTP(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	var err error
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.EnrolTOTP(ctx, claims)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountConfirmTOTPRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.ConfirmTOTP(ctx, claims, params)
+	resp.JSON(w)
+}
+
+func (handler *AccountHTTPHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var params AccountDisableTOTPRequest
+	var ctx = r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		resp = response.Error(response.StatusUnprocessabelEntity, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.DisableTOTP(ctx, claims, params)
+	resp.JSON(w)
+}
+
+// device describes the client making the request.
+func device(r *http.Request) Device {
+	return Device{
+		UserAgent: r.UserAgent(),
+		ClientIP:  clientIP(r),
+	}
+}
+
+// clientIP returns the address of the peer, forwarded headers are ignored as any client can forge them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
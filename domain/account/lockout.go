@@ -0,0 +1,100 @@
+package account
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/throttle"
+)
+
+// loginAttempt is a login counted against the throttles of its email and client address before its password is verified.
+type loginAttempt struct {
+	params   AccountAuthenticationRequest
+	email    throttle.Result
+	clientIP throttle.Result
+}
+
+// attemptLogin counts the login against its email and client address. While either is still blocked the login
+// is refused with how long it has to wait, and nothing is counted.
+func (u *accountUsecaseImpl) attemptLogin(ctx context.Context, params AccountAuthenticationRequest) (attempt loginAttempt, retryAfter time.Duration, err error) {
+	attempt.params = params
+
+	attempt.email, err = u.emailThrottle.Attempt(ctx, strings.ToLower(params.Email))
+	if err != nil {
+		return
+	}
+	if attempt.email.Refused {
+		return attempt, attempt.email.RetryAfter, nil
+	}
+	if params.ClientIP == "" {
+		return
+	}
+
+	attempt.clientIP, err = u.clientIPThrottle.Attempt(ctx, params.ClientIP)
+	if err == nil && !attempt.clientIP.Refused {
+		return
+	}
+
+	// the login never gets to its password, so the email does not pay for it.
+	if succeedErr := u.emailThrottle.Succeed(ctx, strings.ToLower(params.Email), attempt.email); succeedErr != nil {
+		log.Println(succeedErr)
+	}
+	if err != nil {
+		return
+	}
+
+	return attempt, attempt.clientIP.RetryAfter, nil
+}
+
+// passLogin takes the attempt of a login with the right password back. Earlier failures of the email stay
+// until every factor is proven, see unlockLogin.
+func (u *accountUsecaseImpl) passLogin(ctx context.Context, attempt loginAttempt) {
+	if err := u.emailThrottle.Succeed(ctx, strings.ToLower(attempt.params.Email), attempt.email); err != nil {
+		log.Println(err)
+	}
+	if attempt.params.ClientIP == "" {
+		return
+	}
+	if err := u.clientIPThrottle.Succeed(ctx, attempt.params.ClientIP, attempt.clientIP); err != nil {
+		log.Println(err)
+	}
+}
+
+// failLogin records every lockout the failed login caused, its attempt is already counted.
+// Unknown emails are counted as well, otherwise the lockout would tell which accounts exist.
+func (u *accountUsecaseImpl) failLogin(ctx context.Context, attempt loginAttempt) {
+	u.recordLockout(ctx, attempt.params, LoginSubjectEmail, attempt.email)
+	if attempt.params.ClientIP != "" {
+		u.recordLockout(ctx, attempt.params, LoginSubjectClientIP, attempt.clientIP)
+	}
+}
+
+func (u *accountUsecaseImpl) recordLockout(ctx context.Context, params AccountAuthenticationRequest, subject string, result throttle.Result) {
+	if !result.Locked {
+		return
+	}
+
+	now := time.Now().In(u.location)
+	lockout := LoginLockout{
+		Email:       params.Email,
+		ClientIP:    params.ClientIP,
+		Subject:     subject,
+		Failures:    result.Failures,
+		LockedUntil: now.Add(result.RetryAfter),
+		CreatedAt:   now,
+	}
+	log.Printf("login locked out by %s, email %s, client ip %s, %d failures\n", subject, lockout.Email, lockout.ClientIP, lockout.Failures)
+
+	if _, err := u.repository.SaveLoginLockout(ctx, lockout); err != nil {
+		log.Println(err)
+	}
+}
+
+// unlockLogin lifts the lockout of the email, the client address is left alone as it may be shared by an attacker.
+func (u *accountUsecaseImpl) unlockLogin(ctx context.Context, email string) {
+	if err := u.emailThrottle.Reset(ctx, strings.ToLower(email)); err != nil {
+		log.Println(err)
+	}
+}
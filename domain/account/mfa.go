@@ -0,0 +1,275 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/response"
+	"github.com/sangianpatrick/devoria-article-service/session"
+	"github.com/sangianpatrick/devoria-article-service/totp"
+)
+
+const (
+	mfaChallengeTokenByteSize = 32
+	mfaChallengeMaxAttempts   = 5
+	totpSkew                  = 1
+	recoveryCodeCount         = 10
+	recoveryCodeByteSize      = 5
+)
+
+// EnrolTOTP generates a new totp secret of the account. It is not enforced until ConfirmTOTP proves
+// the authenticator app holds it.
+func (u *accountUsecaseImpl) EnrolTOTP(ctx context.Context, claims entity.AccountStandardJWTClaims) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	if account.TOTPEnabledAt != nil {
+		return response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	encryptedSecret, err := u.cipher.Encrypt(ctx, secret)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	err = u.repository.UpdateTOTP(ctx, account.ID, &encryptedSecret, nil)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, AccountTOTPEnrolmentResponse{
+		Secret: secret,
+		URI:    totp.URI(u.totpIssuer, account.Email, secret),
+	})
+}
+
+// ConfirmTOTP enables totp with the first code of the enrolled secret and returns the recovery codes.
+func (u *accountUsecaseImpl) ConfirmTOTP(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountConfirmTOTPRequest) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	if account.TOTPEnabledAt != nil {
+		return response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
+	}
+	if account.TOTPSecret == nil {
+		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+	}
+
+	ok, err := u.validateTOTP(ctx, account, params.Code)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if !ok {
+		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+	}
+
+	enabledAt := time.Now().In(u.location)
+	err = u.repository.UpdateTOTP(ctx, account.ID, account.TOTPSecret, &enabledAt)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	codeHashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code := u.generateBase64String(recoveryCodeByteSize)
+		if code == "" {
+			return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+		}
+		code = fmt.Sprintf("%s-%s", code[:5], code[5:])
+
+		recoveryCodes = append(recoveryCodes, code)
+		codeHashes = append(codeHashes, hashRecoveryCode(code))
+	}
+
+	err = u.repository.SaveRecoveryCodes(ctx, account.ID, codeHashes, enabledAt)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, AccountRecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// DisableTOTP removes the totp secret and the remaining recovery codes after checking the password.
+func (u *accountUsecaseImpl) DisableTOTP(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountDisableTOTPRequest) (resp response.Response) {
+	account, resp := u.findAccountByClaims(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	err := u.verifyPassword(ctx, account, params.Password)
+	if err != nil {
+		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+	}
+
+	err = u.repository.UpdateTOTP(ctx, account.ID, nil, nil)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.repository.SaveRecoveryCodes(ctx, account.ID, nil, time.Now().In(u.location))
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// LoginMFA completes a login challenged for its second factor with a totp code or a recovery code.
+// A challenge is dropped after too many wrong codes, the login has to start over with the password.
+// Wrong codes count against the email like wrong passwords, so new challenges do not buy more guesses.
+// The account is checked again, it may have been deleted, suspended or sent to a password reset meanwhile.
+func (u *accountUsecaseImpl) LoginMFA(ctx context.Context, params AccountMFALoginRequest) (resp response.Response) {
+	tokenHash := hashToken(params.ChallengeToken)
+	key := fmt.Sprintf(AccountMFAChallengeKeyFormat, tokenHash)
+
+	buff, err := u.mfaChallengeSession.Get(ctx, key)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	var challenge MFAChallenge
+	if err = json.Unmarshal(buff, &challenge); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	// counted before the code is checked, concurrent guesses cannot outrun the limit.
+	attempts, err := u.mfaChallengeSession.Increment(ctx, fmt.Sprintf(AccountMFAChallengeAttemptsKeyFormat, tokenHash))
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if attempts > mfaChallengeMaxAttempts {
+		return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+	}
+
+	account, err := u.repository.FindByID(ctx, challenge.AccountID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	attempt, retryAfter, err := u.attemptLogin(ctx, AccountAuthenticationRequest{Email: account.Email})
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if retryAfter > 0 {
+		return response.ErrorWithRetryAfter(response.StatusTooManyRequests, nil, ErrTooManyRequests, retryAfter)
+	}
+
+	if resp = u.checkLoginAllowed(account); resp != nil {
+		return
+	}
+
+	// taken before the code is checked, a request losing the challenge to a concurrent one burns no recovery code.
+	if _, err = u.mfaChallengeSession.Consume(ctx, key); err != nil {
+		if err == session.ErrSessionNotFound {
+			return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	var ok bool
+	if params.RecoveryCode != "" {
+		err = u.repository.ConsumeRecoveryCode(ctx, account.ID, hashRecoveryCode(params.RecoveryCode))
+		ok = err == nil
+		if err == exception.ErrNotFound {
+			err = nil
+		}
+	} else {
+		ok, err = u.validateTOTP(ctx, account, params.Code)
+	}
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if !ok {
+		u.failLogin(ctx, attempt)
+		// the challenge is given back for another code until its last attempt.
+		if attempts < mfaChallengeMaxAttempts {
+			if err = u.mfaChallengeSession.Set(ctx, key, buff); err != nil {
+				log.Println(err)
+			}
+		}
+		return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+	}
+
+	u.unlockLogin(ctx, account.Email)
+
+	return u.authenticate(ctx, account, params.Device, response.StatusOK)
+}
+
+// issueMFAChallenge stores a short-lived challenge of the account and returns its opaque token.
+// It is deliberately not a jwt, so it can never be mistaken for an access token.
+func (u *accountUsecaseImpl) issueMFAChallenge(ctx context.Context, account Account) (token string, err error) {
+	token = u.generateBase64String(mfaChallengeTokenByteSize)
+	if token == "" {
+		return "", exception.ErrInternalServer
+	}
+
+	buff, _ := json.Marshal(MFAChallenge{AccountID: account.ID})
+	err = u.mfaChallengeSession.Set(ctx, fmt.Sprintf(AccountMFAChallengeKeyFormat, hashToken(token)), buff)
+
+	return
+}
+
+// validateTOTP checks the code against the account secret and refuses a step that has already been accepted.
+func (u *accountUsecaseImpl) validateTOTP(ctx context.Context, account Account, code string) (ok bool, err error) {
+	if account.TOTPSecret == nil {
+		return false, nil
+	}
+
+	secret, err := u.cipher.Decrypt(ctx, *account.TOTPSecret)
+	if err != nil {
+		return false, err
+	}
+	step, err := totp.Validate(secret, code, time.Now(), totpSkew)
+	if err != nil {
+		return false, nil
+	}
+
+	// checked and stored in a single step, of concurrent logins with the same code only one gets it.
+	return u.mfaChallengeSession.SetIfGreater(ctx, fmt.Sprintf(AccountTOTPUsedStepKeyFormat, account.ID), step)
+}
+
+// findAccountByClaims returns the account of the token subject, otherwise the response to send back.
+func (u *accountUsecaseImpl) findAccountByClaims(ctx context.Context, claims entity.AccountStandardJWTClaims) (account Account, resp response.Response) {
+	ID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return account, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+	}
+
+	account, err = u.repository.FindByID(ctx, ID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return account, response.Error(response.StatusNotFound, nil, exception.ErrNotFound)
+		}
+		return account, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return account, nil
+}
+
+// hashRecoveryCode hashes the recovery code regardless of its letter case and separator.
+func hashRecoveryCode(code string) string {
+	return hashToken(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", "")))
+}
@@ -0,0 +1,239 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/oidc"
+	"github.com/sangianpatrick/devoria-article-service/response"
+	"github.com/sangianpatrick/devoria-article-service/session"
+)
+
+// ErrOIDCEmailNotVerified is returned when the external provider does not vouch for the email of a new subject.
+var ErrOIDCEmailNotVerified = fmt.Errorf("email of the external account is not verified")
+
+const (
+	oidcStateByteSize        = 32
+	oidcCodeVerifierByteSize = 32
+	oidcNonceByteSize        = 16
+)
+
+// StartOIDCLogin starts the authorization code flow with PKCE at the external provider. The code verifier
+// and the nonce stay in the state session, only the state travels with the user agent.
+func (u *accountUsecaseImpl) StartOIDCLogin(ctx context.Context) (resp response.Response) {
+	if u.oidcProvider == nil {
+		return response.Error(response.StatusNotFound, nil, exception.ErrNotFound)
+	}
+
+	state := u.generateBase64String(oidcStateByteSize)
+	codeVerifier := u.generateBase64String(oidcCodeVerifierByteSize)
+	nonce := u.generateBase64String(oidcNonceByteSize)
+	if state == "" || codeVerifier == "" || nonce == "" {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	authorizationURL, err := u.oidcProvider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	buff, _ := json.Marshal(OIDCState{CodeVerifier: codeVerifier, Nonce: nonce})
+	err = u.oidcStateSession.Set(ctx, fmt.Sprintf(AccountOIDCStateKeyFormat, hashToken(state)), buff)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, AccountOIDCAuthorizationResponse{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+	})
+}
+
+// CompleteOIDCLogin redeems the authorization code of a started login and signs the account of the id token in,
+// with the same checks and second factor as a password login.
+func (u *accountUsecaseImpl) CompleteOIDCLogin(ctx context.Context, params AccountOIDCCallbackRequest) (resp response.Response) {
+	if u.oidcProvider == nil {
+		return response.Error(response.StatusNotFound, nil, exception.ErrNotFound)
+	}
+
+	key := fmt.Sprintf(AccountOIDCStateKeyFormat, hashToken(params.State))
+	buff, err := u.oidcStateSession.Consume(ctx, key)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	var state OIDCState
+	if err = json.Unmarshal(buff, &state); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	idToken, err := u.oidcProvider.Exchange(ctx, params.Code, state.CodeVerifier)
+	if err != nil {
+		if err == oidc.ErrExchangeFailed {
+			return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	claims, err := u.oidcProvider.VerifyIDToken(ctx, idToken, state.Nonce)
+	if err != nil {
+		if err == oidc.ErrUnexpected {
+			return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+		}
+		return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+	}
+
+	account, resp := u.findOIDCAccount(ctx, claims)
+	if resp != nil {
+		return
+	}
+
+	if account.DeletedAt != nil {
+		return response.Error(response.StatusForbiddend, nil, ErrAccountDeleted)
+	}
+
+	if account.SuspendedAt != nil {
+		return response.Error(response.StatusForbiddend, nil, ErrAccountSuspended)
+	}
+
+	if account.TOTPEnabledAt != nil {
+		challengeToken, err := u.issueMFAChallenge(ctx, account)
+		if err != nil {
+			return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+		}
+
+		return response.Success(response.StatusOK, AccountMFAChallengeResponse{
+			MFARequired:    true,
+			ChallengeToken: challengeToken,
+		})
+	}
+
+	return u.authenticate(ctx, account, params.Device, response.StatusOK)
+}
+
+// findOIDCAccount returns the account linked to the subject of the id token, otherwise the response to send back.
+// A subject seen for the first time is linked to the account of its email, or to a new account, but only
+// when the provider verified that email.
+func (u *accountUsecaseImpl) findOIDCAccount(ctx context.Context, claims oidc.IDTokenClaims) (account Account, resp response.Response) {
+	issuer := u.oidcProvider.Issuer()
+
+	identity, err := u.repository.FindIdentity(ctx, issuer, claims.Subject)
+	if err == nil {
+		account, err = u.repository.FindByID(ctx, identity.AccountID)
+		if err != nil {
+			if err == exception.ErrNotFound {
+				return account, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+			}
+			return account, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+		}
+		return account, nil
+	}
+	if err != exception.ErrNotFound {
+		return account, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return account, response.Error(response.StatusForbiddend, nil, ErrOIDCEmailNotVerified)
+	}
+
+	now := time.Now().In(u.location)
+	account, err = u.repository.FindByEmail(ctx, claims.Email)
+	if err != nil && err != exception.ErrNotFound {
+		return account, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if err == exception.ErrNotFound {
+		account, resp = u.createOIDCAccount(ctx, claims, now)
+	} else if account.VerifiedAt == nil {
+		resp = u.claimUnverifiedAccount(ctx, &account, now)
+	}
+	if resp != nil {
+		return
+	}
+
+	_, err = u.repository.SaveIdentity(ctx, AccountIdentity{
+		AccountID: account.ID,
+		Issuer:    issuer,
+		Subject:   claims.Subject,
+		CreatedAt: now,
+	})
+	// a conflict means a concurrent callback of the same subject linked it first.
+	if err != nil && err != exception.ErrConflicted {
+		return account, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return account, nil
+}
+
+// createOIDCAccount creates a verified account without password for the subject, a password can be set later
+// through the password reset.
+func (u *accountUsecaseImpl) createOIDCAccount(ctx context.Context, claims oidc.IDTokenClaims, now time.Time) (account Account, resp response.Response) {
+	account.Email = claims.Email
+	account.FirstName = claims.GivenName
+	account.LastName = claims.FamilyName
+	account.Role = entity.RoleUser
+	account.CreatedAt = now
+
+	ID, err := u.repository.Save(ctx, account)
+	if err != nil {
+		if err == exception.ErrConflicted {
+			return account, response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
+		}
+		return account, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	account.ID = ID
+
+	if err = u.repository.UpdateVerifiedAt(ctx, account.ID, now); err != nil {
+		return account, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	account.VerifiedAt = &now
+
+	return account, nil
+}
+
+// claimUnverifiedAccount marks the account verified on behalf of the provider. Nobody ever proved owning its
+// email, so whoever registered it may not be the owner: every credential they could have set up is dropped,
+// the password, the second factor, the api keys, a pending email change and every token.
+func (u *accountUsecaseImpl) claimUnverifiedAccount(ctx context.Context, account *Account, now time.Time) (resp response.Response) {
+	if err := u.repository.ClearPassword(ctx, account.ID); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	account.Password = nil
+
+	if account.TOTPSecret != nil || account.TOTPEnabledAt != nil {
+		if err := u.repository.UpdateTOTP(ctx, account.ID, nil, nil); err != nil {
+			return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+		}
+		account.TOTPSecret = nil
+		account.TOTPEnabledAt = nil
+	}
+
+	if err := u.repository.SaveRecoveryCodes(ctx, account.ID, nil, now); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if err := u.revokeAPIKeys(ctx, account.ID); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if err := u.dropPendingEmailChange(ctx, account.ID); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if err := u.revokeAllTokens(ctx, account.ID); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if err := u.repository.UpdateVerifiedAt(ctx, account.ID, now); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	account.VerifiedAt = &now
+
+	return nil
+}
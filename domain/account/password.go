@@ -0,0 +1,147 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/mailer"
+	"github.com/sangianpatrick/devoria-article-service/response"
+	"github.com/sangianpatrick/devoria-article-service/session"
+)
+
+const passwordResetTokenByteSize = 32
+
+// ChangePassword replaces the password after checking the current one, then signs the account out everywhere.
+func (u *accountUsecaseImpl) ChangePassword(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountChangePasswordRequest) (resp response.Response) {
+	ID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+	}
+
+	account, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusNotFound, nil, exception.ErrNotFound)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.verifyPassword(ctx, account, params.CurrentPassword)
+	if err != nil {
+		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+	}
+
+	return u.replacePassword(ctx, account, params.NewPassword)
+}
+
+// ForgotPassword mails a single-use reset token. It answers the same way whether the email exists or not.
+func (u *accountUsecaseImpl) ForgotPassword(ctx context.Context, params AccountForgotPasswordRequest) (resp response.Response) {
+	account, err := u.repository.FindByEmail(ctx, params.Email)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Success(response.StatusOK, nil)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.sendPasswordReset(ctx, account)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// ResetPassword consumes the reset token and replaces the password.
+func (u *accountUsecaseImpl) ResetPassword(ctx context.Context, params AccountResetPasswordRequest) (resp response.Response) {
+	key := fmt.Sprintf(AccountPasswordResetKeyFormat, hashToken(params.Token))
+
+	value, err := u.passwordResetSession.Consume(ctx, key)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	ID, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	account, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	resp = u.replacePassword(ctx, account, params.NewPassword)
+	if resp.Err() == nil {
+		u.unlockLogin(ctx, account.Email)
+	}
+
+	return
+}
+
+// sendPasswordReset stores a single-use reset token of the account and mails it.
+func (u *accountUsecaseImpl) sendPasswordReset(ctx context.Context, account Account) (err error) {
+	token := u.generateBase64String(passwordResetTokenByteSize)
+	if token == "" {
+		return exception.ErrInternalServer
+	}
+
+	err = u.passwordResetSession.Set(ctx, fmt.Sprintf(AccountPasswordResetKeyFormat, hashToken(token)), []byte(fmt.Sprintf("%d", account.ID)))
+	if err != nil {
+		return
+	}
+
+	err = u.mailer.Send(ctx, mailer.Mail{
+		To:      account.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Hi %s,\n\nuse the link below to choose a new password:\n%s/reset-password?token=%s\n\nIf you did not ask for it, you can ignore this mail.", account.FirstName, u.frontendURL, token),
+	})
+	if err != nil {
+		log.Println(err)
+	}
+
+	return
+}
+
+// replacePassword stores the new password, lifts a password reset required by an admin
+// and revokes every session, token and api key of the account.
+func (u *accountUsecaseImpl) replacePassword(ctx context.Context, account Account, password string) (resp response.Response) {
+	hashedPassword, err := u.passwordHasher.Hash(password)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.repository.UpdatePassword(ctx, account.ID, hashedPassword)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if account.PasswordResetRequiredAt != nil {
+		err = u.repository.UpdatePasswordResetRequiredAt(ctx, account.ID, nil)
+		if err != nil {
+			return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+		}
+	}
+
+	err = u.revokeAllTokens(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.revokeAPIKeys(ctx, account.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
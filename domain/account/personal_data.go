@@ -0,0 +1,259 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/sangianpatrick/devoria-article-service/crypto"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+)
+
+// NewEncryptedAccountRepository is a constructor of a repository encrypting the email, the first name and the
+// last name of the accounts. The email is looked up by its blind index, the names cannot be searched anymore.
+// Rows written before are read as they are until EncryptPersonalData reaches them, IndexEmails must have given
+// them their email index before serving, so the unique email index covers every account.
+// The login lockout records hold the blind index instead of the email.
+func NewEncryptedAccountRepository(db *sql.DB, tableName string, cipher crypto.Cipher, blindIndex crypto.BlindIndex) AccountRepository {
+	repository := newAccountRepository(db, tableName)
+	repository.cipher = cipher
+	repository.blindIndex = blindIndex
+
+	return repository
+}
+
+// personalData is the personal data of an account as it is stored.
+type personalData struct {
+	email      string
+	emailIndex string
+	firstName  string
+	lastName   string
+}
+
+// encrypted tells whether the personal data is encrypted.
+func (r *accountRepositoryImpl) encrypted() bool {
+	return r.cipher != nil
+}
+
+// emailIndex returns the blind index of the email, emails differing only by case share it.
+func (r *accountRepositoryImpl) emailIndex(email string) string {
+	return r.blindIndex.Index(strings.ToLower(strings.TrimSpace(email)))
+}
+
+func (r *accountRepositoryImpl) sealPersonalData(ctx context.Context, email string, firstName string, lastName string) (data personalData, err error) {
+	data.emailIndex = r.emailIndex(email)
+	if data.email, err = r.cipher.Encrypt(ctx, email); err != nil {
+		return
+	}
+	if data.firstName, err = r.cipher.Encrypt(ctx, firstName); err != nil {
+		return
+	}
+	data.lastName, err = r.cipher.Encrypt(ctx, lastName)
+
+	return
+}
+
+// openPersonalData decrypts the personal data of an account unless it is still stored in plaintext.
+func (r *accountRepositoryImpl) openPersonalData(ctx context.Context, account *Account, personalDataEncrypted bool) (err error) {
+	if !personalDataEncrypted {
+		return
+	}
+	if account.Email, err = r.cipher.Decrypt(ctx, account.Email); err != nil {
+		return
+	}
+	if account.FirstName, err = r.cipher.Decrypt(ctx, account.FirstName); err != nil {
+		return
+	}
+	account.LastName, err = r.cipher.Decrypt(ctx, account.LastName)
+
+	return
+}
+
+// selectAccountColumns returns the columns read by scanAccount of the repository.
+func (r *accountRepositoryImpl) selectAccountColumns() string {
+	if r.encrypted() {
+		return accountColumns + ", personalDataEncrypted"
+	}
+	return accountColumns
+}
+
+// scanAccount reads an account and decrypts its personal data, a failed decryption is exception.ErrInternalServer.
+func (r *accountRepositoryImpl) scanAccount(ctx context.Context, row rowScanner) (account Account, err error) {
+	if !r.encrypted() {
+		return scanAccount(row)
+	}
+
+	var personalDataEncrypted bool
+	if account, err = scanAccount(row, &personalDataEncrypted); err != nil {
+		return
+	}
+	if err = r.openPersonalData(ctx, &account, personalDataEncrypted); err != nil {
+		log.Printf("failed to decrypt account %d: %v\n", account.ID, err)
+		err = exception.ErrInternalServer
+	}
+
+	return
+}
+
+// purgeLoginLockouts removes the login lockout records of the account, by the blind index of its email or by
+// the email itself for the records IndexLoginLockouts has not reached yet.
+func (r *accountRepositoryImpl) purgeLoginLockouts(ctx context.Context, tx *sql.Tx, ID int64) (err error) {
+	var email string
+	var personalDataEncrypted bool
+
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT email, personalDataEncrypted FROM %s WHERE id = ?`, r.tableName), ID).Scan(&email, &personalDataEncrypted)
+	if err == sql.ErrNoRows {
+		return exception.ErrNotFound
+	}
+	if err != nil {
+		log.Println(err)
+		return exception.ErrInternalServer
+	}
+
+	if personalDataEncrypted {
+		if email, err = r.cipher.Decrypt(ctx, email); err != nil {
+			log.Println(err)
+			return exception.ErrInternalServer
+		}
+	}
+
+	command := fmt.Sprintf(`DELETE FROM %s WHERE emailIndex = ? OR email = ?`, r.loginLockoutTableName)
+	if _, err = tx.ExecContext(ctx, command, r.emailIndex(email), email); err != nil {
+		log.Println(err)
+		return exception.ErrInternalServer
+	}
+
+	return
+}
+
+// EncryptPersonalData encrypts one batch of the accounts after the given id still stored in plaintext.
+// It returns the last id read and how many were read, fewer than the limit means none is left.
+// An account changed meanwhile is left for the next run. It does nothing unless the personal data is encrypted.
+func (r *accountRepositoryImpl) EncryptPersonalData(ctx context.Context, afterID int64, limit int) (lastID int64, scanned int, err error) {
+	if !r.encrypted() {
+		return
+	}
+
+	query := fmt.Sprintf(`SELECT id, email, firstName, lastName FROM %s WHERE id > ? AND personalDataEncrypted = FALSE ORDER BY id ASC LIMIT ?`, r.tableName)
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	var accounts []Account
+	for rows.Next() {
+		var account Account
+		if err = rows.Scan(&account.ID, &account.Email, &account.FirstName, &account.LastName); err != nil {
+			rows.Close()
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+		accounts = append(accounts, account)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	command := fmt.Sprintf(`UPDATE %s SET email = ?, emailIndex = ?, firstName = ?, lastName = ?, personalDataEncrypted = TRUE WHERE id = ? AND personalDataEncrypted = FALSE AND email = ? AND firstName = ? AND lastName = ?`, r.tableName)
+	for _, account := range accounts {
+		data, err := r.sealPersonalData(ctx, account.Email, account.FirstName, account.LastName)
+		if err != nil {
+			log.Println(err)
+			return lastID, scanned, exception.ErrInternalServer
+		}
+
+		_, err = r.db.ExecContext(ctx, command, data.email, data.emailIndex, data.firstName, data.lastName, account.ID, account.Email, account.FirstName, account.LastName)
+		if err != nil && !isDuplicateEntry(err) {
+			log.Println(err)
+			return lastID, scanned, exception.ErrInternalServer
+		}
+		if err != nil {
+			log.Printf("account %d shares its email with another account, it is left in plaintext\n", account.ID)
+		}
+
+		lastID = account.ID
+		scanned++
+	}
+
+	return
+}
+
+// IndexEmails gives one batch of the accounts after the given id their email index, the email stays in plaintext.
+// It returns the last id read and how many were read, fewer than the limit means none is left. Only the accounts
+// sharing their email with another one but for its case are left without index, they are found by the email.
+// It does nothing unless the personal data is encrypted.
+func (r *accountRepositoryImpl) IndexEmails(ctx context.Context, afterID int64, limit int) (lastID int64, scanned int, err error) {
+	if !r.encrypted() {
+		return
+	}
+
+	command := fmt.Sprintf(`UPDATE %s SET emailIndex = ? WHERE id = ? AND emailIndex IS NULL AND email = ?`, r.tableName)
+	return r.indexEmails(ctx, r.tableName, command, afterID, limit)
+}
+
+// IndexLoginLockouts replaces the email of one batch of the login lockout records after the given id by its blind
+// index, like IndexEmails. It does nothing unless the personal data is encrypted.
+func (r *accountRepositoryImpl) IndexLoginLockouts(ctx context.Context, afterID int64, limit int) (lastID int64, scanned int, err error) {
+	if !r.encrypted() {
+		return
+	}
+
+	command := fmt.Sprintf(`UPDATE %s SET emailIndex = ?, email = NULL WHERE id = ? AND emailIndex IS NULL AND email = ?`, r.loginLockoutTableName)
+	return r.indexEmails(ctx, r.loginLockoutTableName, command, afterID, limit)
+}
+
+// indexEmails runs the command on every row of the table without email index, with the index, the id and the email.
+func (r *accountRepositoryImpl) indexEmails(ctx context.Context, tableName string, command string, afterID int64, limit int) (lastID int64, scanned int, err error) {
+	query := fmt.Sprintf(`SELECT id, email FROM %s WHERE id > ? AND emailIndex IS NULL ORDER BY id ASC LIMIT ?`, tableName)
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	var IDs []int64
+	var emails []string
+	for rows.Next() {
+		var ID int64
+		var email string
+		if err = rows.Scan(&ID, &email); err != nil {
+			rows.Close()
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+		IDs = append(IDs, ID)
+		emails = append(emails, email)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	for i, ID := range IDs {
+		_, err := r.db.ExecContext(ctx, command, r.emailIndex(emails[i]), ID, emails[i])
+		if err != nil && !isDuplicateEntry(err) {
+			log.Println(err)
+			return lastID, scanned, exception.ErrInternalServer
+		}
+		if err != nil {
+			log.Printf("%s %d shares its email with another row, it is left without email index\n", tableName, ID)
+		}
+
+		lastID = ID
+		scanned++
+	}
+
+	return
+}
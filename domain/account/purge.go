@@ -0,0 +1,93 @@
+package account
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const purgeBatchSize = 100
+
+// AccountPurger removes the accounts whose deletion grace period is over along with their articles.
+type AccountPurger interface {
+	Purge(ctx context.Context) (purged int, err error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type accountPurgerImpl struct {
+	gracePeriod               time.Duration
+	location                  *time.Location
+	authoredArticleRepository AuthoredArticleRepository
+	repository                AccountRepository
+}
+
+// NewAccountPurger is a constructor.
+func NewAccountPurger(
+	gracePeriod time.Duration,
+	location *time.Location,
+	authoredArticleRepository AuthoredArticleRepository,
+	repository AccountRepository,
+) AccountPurger {
+	return &accountPurgerImpl{
+		gracePeriod:               gracePeriod,
+		location:                  location,
+		authoredArticleRepository: authoredArticleRepository,
+		repository:                repository,
+	}
+}
+
+// Purge removes one batch of accounts due for their purge. An account that fails is left for the next run
+// and does not stop the others, the last error is returned.
+func (p *accountPurgerImpl) Purge(ctx context.Context) (purged int, err error) {
+	accounts, err := p.repository.FindDeletedBefore(ctx, time.Now().In(p.location).Add(-p.gracePeriod), purgeBatchSize)
+	if err != nil {
+		return
+	}
+
+	for _, account := range accounts {
+		if purgeErr := p.purgeAccount(ctx, account); purgeErr != nil {
+			log.Printf("failed to purge account %d: %v\n", account.ID, purgeErr)
+			err = purgeErr
+			continue
+		}
+		purged++
+	}
+
+	return
+}
+
+// purgeAccount disposes of the articles first, the account row cannot go while they still reference it.
+// Anything but an explicit deletion keeps the articles without their author.
+func (p *accountPurgerImpl) purgeAccount(ctx context.Context, account Account) (err error) {
+	if account.ArticleDisposition == ArticleDispositionDelete {
+		err = p.authoredArticleRepository.DeleteByAuthorID(ctx, account.ID)
+	} else {
+		err = p.authoredArticleRepository.AnonymiseByAuthorID(ctx, account.ID)
+	}
+	if err != nil {
+		return
+	}
+
+	return p.repository.Purge(ctx, account.ID)
+}
+
+// Run purges the due accounts every interval until the context is done.
+func (p *accountPurgerImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// a full batch means more accounts may be due, a failed one stops this round.
+			for {
+				purged, err := p.Purge(ctx)
+				if err != nil || purged < purgeBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
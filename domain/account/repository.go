@@ -3,51 +3,731 @@ package account
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/sangianpatrick/devoria-article-service/crypto"
 	"github.com/sangianpatrick/devoria-article-service/exception"
 )
 
-type AccountRepository interface {
-	Save(ctx context.Context, account Account) (ID int64, err error)
-	Update(ctx context.Context, ID int64, updatedAccount Account) (err error)
-	FindByEmail(ctx context.Context, email string) (account Account, err error)
-	FindByID(ctx context.Context, ID int64) (account Account, err error)
+// mysqlErrDuplicateEntry is the error number of ER_DUP_ENTRY.
+const mysqlErrDuplicateEntry = 1062
+
+type AccountRepository interface {
+	Save(ctx context.Context, account Account) (ID int64, err error)
+	Update(ctx context.Context, ID int64, updatedAccount Account) (err error)
+	UpdatePassword(ctx context.Context, ID int64, password string) (err error)
+	ClearPassword(ctx context.Context, ID int64) (err error)
+	UpdateVerifiedAt(ctx context.Context, ID int64, verifiedAt time.Time) (err error)
+	UpdateEmail(ctx context.Context, ID int64, currentEmail string, newEmail string, verifiedAt time.Time) (err error)
+	UpdateTOTP(ctx context.Context, ID int64, secret *string, enabledAt *time.Time) (err error)
+	UpdateSuspendedAt(ctx context.Context, ID int64, suspendedAt *time.Time) (err error)
+	UpdatePasswordResetRequiredAt(ctx context.Context, ID int64, requiredAt *time.Time) (err error)
+	UpdateRole(ctx context.Context, ID int64, role string) (err error)
+	SaveRecoveryCodes(ctx context.Context, ID int64, codeHashes []string, createdAt time.Time) (err error)
+	ConsumeRecoveryCode(ctx context.Context, ID int64, codeHash string) (err error)
+	SaveLoginLockout(ctx context.Context, lockout LoginLockout) (ID int64, err error)
+	SaveAuditLog(ctx context.Context, auditLog AuditLog) (ID int64, err error)
+	FindAuditLogsByTargetID(ctx context.Context, targetID int64, limit int) (bunchOfAuditLogs []AuditLog, err error)
+	SaveAPIKey(ctx context.Context, apiKey APIKey) (ID int64, err error)
+	UpdateAPIKeyLastUsedAt(ctx context.Context, ID int64, lastUsedAt time.Time) (err error)
+	DeleteAPIKey(ctx context.Context, accountID int64, ID int64) (err error)
+	DeleteAPIKeysByAccountID(ctx context.Context, accountID int64) (err error)
+	FindAPIKeyByHash(ctx context.Context, keyHash string) (apiKey APIKey, err error)
+	FindAPIKeysByAccountID(ctx context.Context, accountID int64) (bunchOfAPIKeys []APIKey, err error)
+	SaveIdentity(ctx context.Context, identity AccountIdentity) (ID int64, err error)
+	FindIdentity(ctx context.Context, issuer string, subject string) (identity AccountIdentity, err error)
+	MarkDeleted(ctx context.Context, ID int64, deletedAt time.Time, articleDisposition ArticleDisposition) (err error)
+	Restore(ctx context.Context, ID int64, deletedAfter time.Time) (err error)
+	Purge(ctx context.Context, ID int64) (err error)
+	FindByEmail(ctx context.Context, email string) (account Account, err error)
+	FindByID(ctx context.Context, ID int64) (account Account, err error)
+	FindMany(ctx context.Context, filter AccountFilter) (bunchOfAccounts []Account, err error)
+	FindDeletedBefore(ctx context.Context, before time.Time, limit int) (bunchOfAccounts []Account, err error)
+	EncryptPersonalData(ctx context.Context, afterID int64, limit int) (lastID int64, scanned int, err error)
+	IndexEmails(ctx context.Context, afterID int64, limit int) (lastID int64, scanned int, err error)
+	IndexLoginLockouts(ctx context.Context, afterID int64, limit int) (lastID int64, scanned int, err error)
+}
+
+type accountRepositoryImpl struct {
+	db                    *sql.DB
+	tableName             string
+	recoveryCodeTableName string
+	loginLockoutTableName string
+	auditLogTableName     string
+	apiKeyTableName       string
+	identityTableName     string
+	cipher                crypto.Cipher
+	blindIndex            crypto.BlindIndex
+}
+
+func NewAccountRepository(db *sql.DB, tableName string) AccountRepository {
+	return newAccountRepository(db, tableName)
+}
+
+func newAccountRepository(db *sql.DB, tableName string) *accountRepositoryImpl {
+	return &accountRepositoryImpl{
+		db:                    db,
+		tableName:             tableName,
+		recoveryCodeTableName: fmt.Sprintf("%s_recovery_code", tableName),
+		loginLockoutTableName: fmt.Sprintf("%s_login_lockout", tableName),
+		auditLogTableName:     fmt.Sprintf("%s_audit_log", tableName),
+		apiKeyTableName:       fmt.Sprintf("%s_api_key", tableName),
+		identityTableName:     fmt.Sprintf("%s_identity", tableName),
+	}
+}
+
+func (r *accountRepositoryImpl) Save(ctx context.Context, account Account) (ID int64, err error) {
+	command := fmt.Sprintf("INSERT INTO %s (email, password, firstName, lastName, role, createdAt) VALUES (?, ?, ?, ?, ?, ?)", r.tableName)
+	args := []interface{}{account.Email, account.Password, account.FirstName, account.LastName, account.Role, account.CreatedAt}
+	if r.encrypted() {
+		data, sealErr := r.sealPersonalData(ctx, account.Email, account.FirstName, account.LastName)
+		if sealErr != nil {
+			log.Println(sealErr)
+			err = exception.ErrInternalServer
+			return
+		}
+		command = fmt.Sprintf("INSERT INTO %s (email, emailIndex, personalDataEncrypted, password, firstName, lastName, role, createdAt) VALUES (?, ?, TRUE, ?, ?, ?, ?, ?)", r.tableName)
+		args = []interface{}{data.email, data.emailIndex, account.Password, data.firstName, data.lastName, account.Role, account.CreatedAt}
+	}
+
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+
+	if err != nil {
+		if isDuplicateEntry(err) {
+			err = exception.ErrConflicted
+			return
+		}
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	ID, _ = result.LastInsertId()
+
+	return
+}
+
+// isDuplicateEntry tells whether the error is a violation of a unique index.
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
+
+// Update saves the names of the account, the password is only changed by UpdatePassword and ClearPassword.
+// With encrypted personal data the names of an account still stored in plaintext stay in plaintext, the
+// account is left whole to EncryptPersonalData.
+func (r *accountRepositoryImpl) Update(ctx context.Context, ID int64, updatedAccount Account) (err error) {
+	command := fmt.Sprintf(`UPDATE %s SET firstName = ?, lastName = ?, lastModified = ? WHERE id = ?`, r.tableName)
+	args := []interface{}{updatedAccount.FirstName, updatedAccount.LastName, updatedAccount.LastModifiedAt, ID}
+	if r.encrypted() {
+		firstName, encryptErr := r.cipher.Encrypt(ctx, updatedAccount.FirstName)
+		if encryptErr == nil {
+			var lastName string
+			lastName, encryptErr = r.cipher.Encrypt(ctx, updatedAccount.LastName)
+			args = []interface{}{firstName, updatedAccount.FirstName, lastName, updatedAccount.LastName, updatedAccount.LastModifiedAt, ID}
+		}
+		if encryptErr != nil {
+			log.Println(encryptErr)
+			err = exception.ErrInternalServer
+			return
+		}
+		command = fmt.Sprintf(`UPDATE %s SET firstName = IF(personalDataEncrypted, ?, ?), lastName = IF(personalDataEncrypted, ?, ?), lastModified = ? WHERE id = ?`, r.tableName)
+	}
+
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected < 1 {
+		err = exception.ErrNotFound
+		return
+	}
+
+	return
+}
+
+func (r *accountRepositoryImpl) UpdatePassword(ctx context.Context, ID int64, password string) (err error) {
+	command := fmt.Sprintf(`UPDATE %s SET password = ? WHERE id = ?`, r.tableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, password, ID)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected < 1 {
+		err = exception.ErrNotFound
+		return
+	}
+
+	return
+}
+
+func (r *accountRepositoryImpl) UpdateVerifiedAt(ctx context.Context, ID int64, verifiedAt time.Time) (err error) {
+	command := fmt.Sprintf(`UPDATE %s SET verifiedAt = ? WHERE id = ?`, r.tableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, verifiedAt, ID)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected < 1 {
+		err = exception.ErrNotFound
+		return
+	}
+
+	return
+}
+
+// UpdateEmail replaces the email only while it is still the current one, so concurrent changes cannot both win.
+// It returns exception.ErrNotFound when the email has changed meanwhile and exception.ErrConflicted when another account owns the new one.
+func (r *accountRepositoryImpl) UpdateEmail(ctx context.Context, ID int64, currentEmail string, newEmail string, verifiedAt time.Time) (err error) {
+	command := fmt.Sprintf(`UPDATE %s SET email = ?, verifiedAt = ? WHERE id = ? AND email = ?`, r.tableName)
+	args := []interface{}{newEmail, verifiedAt, ID, currentEmail}
+	if r.encrypted() {
+		encryptedEmail, encryptErr := r.cipher.Encrypt(ctx, newEmail)
+		if encryptErr != nil {
+			log.Println(encryptErr)
+			err = exception.ErrInternalServer
+			return
+		}
+		// an account still in plaintext keeps its email in plaintext, every account is given the index of the new one.
+		command = fmt.Sprintf(`UPDATE %s SET email = IF(personalDataEncrypted, ?, ?), emailIndex = ?, verifiedAt = ? WHERE id = ? AND (emailIndex = ? OR (emailIndex IS NULL AND email = ?))`, r.tableName)
+		args = []interface{}{encryptedEmail, newEmail, r.emailIndex(newEmail), verifiedAt, ID, r.emailIndex(currentEmail), currentEmail}
+	}
+
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			err = exception.ErrConflicted
+			return
+		}
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected < 1 {
+		err = exception.ErrNotFound
+		return
+	}
+
+	return
+}
+
+func (r *accountRepositoryImpl) UpdateTOTP(ctx context.Context, ID int64, secret *string, enabledAt *time.Time) (err error) {
+	command := fmt.Sprintf(`UPDATE %s SET totpSecret = ?, totpEnabledAt = ? WHERE id = ?`, r.tableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, secret, enabledAt, ID)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected < 1 {
+		err = exception.ErrNotFound
+		return
+	}
+
+	return
+}
+
+// UpdateSuspendedAt suspends the account, a nil time lifts the suspension.
+func (r *accountRepositoryImpl) UpdateSuspendedAt(ctx context.Context, ID int64, suspendedAt *time.Time) (err error) {
+	return r.updateColumn(ctx, ID, "suspendedAt", suspendedAt)
+}
+
+// UpdatePasswordResetRequiredAt requires a password reset before the next login, a nil time lifts the requirement.
+func (r *accountRepositoryImpl) UpdatePasswordResetRequiredAt(ctx context.Context, ID int64, requiredAt *time.Time) (err error) {
+	return r.updateColumn(ctx, ID, "passwordResetRequiredAt", requiredAt)
+}
+
+// ClearPassword removes the password, the account can then only sign in through a linked identity or a password reset.
+func (r *accountRepositoryImpl) ClearPassword(ctx context.Context, ID int64) (err error) {
+	return r.updateColumn(ctx, ID, "password", nil)
+}
+
+func (r *accountRepositoryImpl) UpdateRole(ctx context.Context, ID int64, role string) (err error) {
+	return r.updateColumn(ctx, ID, "role", role)
+}
+
+// updateColumn sets a single column of the account, the column name must never come from the client.
+func (r *accountRepositoryImpl) updateColumn(ctx context.Context, ID int64, column string, value interface{}) (err error) {
+	command := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ?`, r.tableName, column)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, value, ID)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected < 1 {
+		err = exception.ErrNotFound
+		return
+	}
+
+	return
+}
+
+// SaveRecoveryCodes replaces every recovery code of the account, no code hashes only removes them.
+func (r *accountRepositoryImpl) SaveRecoveryCodes(ctx context.Context, ID int64, codeHashes []string, createdAt time.Time) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE accountId = ?`, r.recoveryCodeTableName), ID)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	if len(codeHashes) > 0 {
+		stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (accountId, codeHash, createdAt) VALUES (?, ?, ?)`, r.recoveryCodeTableName))
+		if err != nil {
+			log.Println(err)
+			return exception.ErrInternalServer
+		}
+		defer stmt.Close()
+
+		for _, codeHash := range codeHashes {
+			if _, err = stmt.ExecContext(ctx, ID, codeHash, createdAt); err != nil {
+				log.Println(err)
+				return exception.ErrInternalServer
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+	}
+
+	return
+}
+
+// ConsumeRecoveryCode deletes the recovery code, it returns exception.ErrNotFound when the code is unknown or already used.
+func (r *accountRepositoryImpl) ConsumeRecoveryCode(ctx context.Context, ID int64, codeHash string) (err error) {
+	command := fmt.Sprintf(`DELETE FROM %s WHERE accountId = ? AND codeHash = ?`, r.recoveryCodeTableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, ID, codeHash)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected < 1 {
+		err = exception.ErrNotFound
+		return
+	}
+
+	return
+}
+
+// SaveLoginLockout records a lockout, with encrypted personal data it holds the blind index of the email instead.
+func (r *accountRepositoryImpl) SaveLoginLockout(ctx context.Context, lockout LoginLockout) (ID int64, err error) {
+	command := fmt.Sprintf("INSERT INTO %s (email, clientIP, subject, failures, lockedUntil, createdAt) VALUES (?, ?, ?, ?, ?, ?)", r.loginLockoutTableName)
+	email := lockout.Email
+	if r.encrypted() {
+		command = fmt.Sprintf("INSERT INTO %s (emailIndex, clientIP, subject, failures, lockedUntil, createdAt) VALUES (?, ?, ?, ?, ?, ?)", r.loginLockoutTableName)
+		email = r.emailIndex(lockout.Email)
+	}
+
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(
+		ctx,
+		email,
+		lockout.ClientIP,
+		lockout.Subject,
+		lockout.Failures,
+		lockout.LockedUntil,
+		lockout.CreatedAt,
+	)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	ID, _ = result.LastInsertId()
+
+	return
+}
+
+func (r *accountRepositoryImpl) SaveAuditLog(ctx context.Context, auditLog AuditLog) (ID int64, err error) {
+	command := fmt.Sprintf("INSERT INTO %s (actorId, action, targetId, detail, createdAt) VALUES (?, ?, ?, ?, ?)", r.auditLogTableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	targetID := sql.NullInt64{Int64: auditLog.TargetID, Valid: auditLog.TargetID != 0}
+	result, err := stmt.ExecContext(
+		ctx,
+		auditLog.ActorID,
+		auditLog.Action,
+		targetID,
+		auditLog.Detail,
+		auditLog.CreatedAt,
+	)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	ID, _ = result.LastInsertId()
+
+	return
+}
+
+// FindAuditLogsByTargetID returns the latest audit logs of actions on the account, the newest first.
+func (r *accountRepositoryImpl) FindAuditLogsByTargetID(ctx context.Context, targetID int64, limit int) (bunchOfAuditLogs []AuditLog, err error) {
+	query := fmt.Sprintf(`SELECT id, actorId, action, targetId, detail, createdAt FROM %s WHERE targetId = ? ORDER BY id DESC LIMIT ?`, r.auditLogTableName)
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, targetID, limit)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var auditLog AuditLog
+		var targetID sql.NullInt64
+
+		if err = rows.Scan(&auditLog.ID, &auditLog.ActorID, &auditLog.Action, &targetID, &auditLog.Detail, &auditLog.CreatedAt); err != nil {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+
+		auditLog.TargetID = targetID.Int64
+		bunchOfAuditLogs = append(bunchOfAuditLogs, auditLog)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	return
+}
+
+func (r *accountRepositoryImpl) SaveAPIKey(ctx context.Context, apiKey APIKey) (ID int64, err error) {
+	command := fmt.Sprintf("INSERT INTO %s (accountId, name, prefix, keyHash, scopes, createdAt) VALUES (?, ?, ?, ?, ?, ?)", r.apiKeyTableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(
+		ctx,
+		apiKey.AccountID,
+		apiKey.Name,
+		apiKey.Prefix,
+		apiKey.KeyHash,
+		strings.Join(apiKey.Scopes, " "),
+		apiKey.CreatedAt,
+	)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	ID, _ = result.LastInsertId()
+
+	return
+}
+
+func (r *accountRepositoryImpl) UpdateAPIKeyLastUsedAt(ctx context.Context, ID int64, lastUsedAt time.Time) (err error) {
+	command := fmt.Sprintf(`UPDATE %s SET lastUsedAt = ? WHERE id = ?`, r.apiKeyTableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, lastUsedAt, ID); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	return
+}
+
+// DeleteAPIKey removes the api key of the account, it returns exception.ErrNotFound when the account owns no such key.
+func (r *accountRepositoryImpl) DeleteAPIKey(ctx context.Context, accountID int64, ID int64) (err error) {
+	command := fmt.Sprintf(`DELETE FROM %s WHERE id = ? AND accountId = ?`, r.apiKeyTableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, ID, accountID)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected < 1 {
+		err = exception.ErrNotFound
+		return
+	}
+
+	return
 }
 
-type accountRepositoryImpl struct {
-	db        *sql.DB
-	tableName string
+// DeleteAPIKeysByAccountID removes every api key of the account, an account without keys is not an error.
+func (r *accountRepositoryImpl) DeleteAPIKeysByAccountID(ctx context.Context, accountID int64) (err error) {
+	command := fmt.Sprintf(`DELETE FROM %s WHERE accountId = ?`, r.apiKeyTableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, accountID); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	return
 }
 
-func NewAccountRepository(db *sql.DB, tableName string) AccountRepository {
-	return &accountRepositoryImpl{
-		db:        db,
-		tableName: tableName,
+func (r *accountRepositoryImpl) FindAPIKeyByHash(ctx context.Context, keyHash string) (apiKey APIKey, err error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE keyHash = ?`, apiKeyColumns, r.apiKeyTableName)
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	apiKey, err = scanAPIKey(stmt.QueryRowContext(ctx, keyHash))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+		err = exception.ErrNotFound
+		return
 	}
+
+	return
 }
 
-func (r *accountRepositoryImpl) Save(ctx context.Context, account Account) (ID int64, err error) {
-	command := fmt.Sprintf("INSERT INTO %s (email, password, firstName, lastName, createdAt) VALUES (?, ?, ?, ?, ?)", r.tableName)
-	stmt, err := r.db.PrepareContext(ctx, command)
+// FindAPIKeysByAccountID returns every api key of the account, the newest first.
+func (r *accountRepositoryImpl) FindAPIKeysByAccountID(ctx context.Context, accountID int64) (bunchOfAPIKeys []APIKey, err error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE accountId = ? ORDER BY id DESC`, apiKeyColumns, r.apiKeyTableName)
+	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		log.Println(err)
+		err = exception.ErrInternalServer
 		return
 	}
 	defer stmt.Close()
 
-	result, err := stmt.ExecContext(
-		ctx,
-		account.Email,
-		*account.Password,
-		account.FirstName,
-		account.LastName,
-		account.CreatedAt,
+	rows, err := stmt.QueryContext(ctx, accountID)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var apiKey APIKey
+		if apiKey, err = scanAPIKey(rows); err != nil {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+
+		bunchOfAPIKeys = append(bunchOfAPIKeys, apiKey)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	return
+}
+
+// apiKeyColumns are the columns read by scanAPIKey, in its order.
+const apiKeyColumns = "id, accountId, name, prefix, keyHash, scopes, createdAt, lastUsedAt"
+
+func scanAPIKey(row rowScanner) (apiKey APIKey, err error) {
+	var scopes string
+	var lastUsedAt sql.NullTime
+
+	err = row.Scan(
+		&apiKey.ID,
+		&apiKey.AccountID,
+		&apiKey.Name,
+		&apiKey.Prefix,
+		&apiKey.KeyHash,
+		&scopes,
+		&apiKey.CreatedAt,
+		&lastUsedAt,
 	)
+	if err != nil {
+		return
+	}
+
+	apiKey.Scopes = strings.Fields(scopes)
+
+	if lastUsedAt.Valid {
+		apiKey.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return
+}
+
+// SaveIdentity links the account to a subject of an external provider,
+// it returns exception.ErrConflicted when the subject is already linked.
+func (r *accountRepositoryImpl) SaveIdentity(ctx context.Context, identity AccountIdentity) (ID int64, err error) {
+	command := fmt.Sprintf("INSERT INTO %s (accountId, issuer, subject, createdAt) VALUES (?, ?, ?, ?)", r.identityTableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
 
+	result, err := stmt.ExecContext(ctx, identity.AccountID, identity.Issuer, identity.Subject, identity.CreatedAt)
 	if err != nil {
+		if isDuplicateEntry(err) {
+			err = exception.ErrConflicted
+			return
+		}
 		log.Println(err)
+		err = exception.ErrInternalServer
 		return
 	}
 
@@ -56,9 +736,9 @@ func (r *accountRepositoryImpl) Save(ctx context.Context, account Account) (ID i
 	return
 }
 
-func (r *accountRepositoryImpl) Update(ctx context.Context, ID int64, updatedAccount Account) (err error) {
-	command := fmt.Sprintf(`UPDATE %s SET password = ?, firstName = ?, lastName = ?, lastModified = ? WHERE id = ?`, r.tableName)
-	stmt, err := r.db.PrepareContext(ctx, command)
+func (r *accountRepositoryImpl) FindIdentity(ctx context.Context, issuer string, subject string) (identity AccountIdentity, err error) {
+	query := fmt.Sprintf(`SELECT id, accountId, issuer, subject, createdAt FROM %s WHERE issuer = ? AND subject = ?`, r.identityTableName)
+	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		log.Println(err)
 		err = exception.ErrInternalServer
@@ -66,14 +746,38 @@ func (r *accountRepositoryImpl) Update(ctx context.Context, ID int64, updatedAcc
 	}
 	defer stmt.Close()
 
-	result, err := stmt.ExecContext(
-		ctx,
-		*updatedAccount.Password,
-		updatedAccount.FirstName,
-		updatedAccount.LastName,
-		updatedAccount.LastModifiedAt,
+	err = stmt.QueryRowContext(ctx, issuer, subject).Scan(
+		&identity.ID,
+		&identity.AccountID,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.CreatedAt,
 	)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+		err = exception.ErrNotFound
+		return
+	}
+
+	return
+}
+
+// MarkDeleted schedules the account for its purge, it returns exception.ErrNotFound when it is already scheduled.
+func (r *accountRepositoryImpl) MarkDeleted(ctx context.Context, ID int64, deletedAt time.Time, articleDisposition ArticleDisposition) (err error) {
+	command := fmt.Sprintf(`UPDATE %s SET deletedAt = ?, articleDisposition = ? WHERE id = ? AND deletedAt IS NULL`, r.tableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
 
+	result, err := stmt.ExecContext(ctx, deletedAt, articleDisposition, ID)
 	if err != nil {
 		log.Println(err)
 		err = exception.ErrInternalServer
@@ -89,49 +793,170 @@ func (r *accountRepositoryImpl) Update(ctx context.Context, ID int64, updatedAcc
 	return
 }
 
-func (r *accountRepositoryImpl) FindByEmail(ctx context.Context, email string) (account Account, err error) {
-	query := fmt.Sprintf(`SELECT id, email, password, firstName, lastName, createdAt, lastModified FROM %s WHERE email = ?`, r.tableName)
-	stmt, err := r.db.PrepareContext(ctx, query)
+// Restore cancels the deletion of an account deleted after the given time, it returns exception.ErrNotFound when
+// the account is not deleted or was deleted before.
+func (r *accountRepositoryImpl) Restore(ctx context.Context, ID int64, deletedAfter time.Time) (err error) {
+	command := fmt.Sprintf(`UPDATE %s SET deletedAt = NULL, articleDisposition = NULL WHERE id = ? AND deletedAt > ?`, r.tableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
 	if err != nil {
 		log.Println(err)
+		err = exception.ErrInternalServer
 		return
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRowContext(ctx, email)
+	result, err := stmt.ExecContext(ctx, ID, deletedAfter)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
 
-	var password sql.NullString
-	var lastModifiedAt sql.NullTime
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected < 1 {
+		err = exception.ErrNotFound
+		return
+	}
 
-	err = row.Scan(
-		&account.ID,
-		&account.Email,
-		&password,
-		&account.FirstName,
-		&account.LastName,
-		&account.CreatedAt,
-		&lastModifiedAt,
-	)
+	return
+}
+
+// Purge removes a deleted account along with its recovery codes, api keys, identities and login lockout records.
+// The articles of the account must have been deleted or anonymised before.
+func (r *accountRepositoryImpl) Purge(ctx context.Context, ID int64) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer tx.Rollback()
+
+	commands := []string{
+		fmt.Sprintf(`DELETE FROM %s WHERE accountId = ?`, r.recoveryCodeTableName),
+		fmt.Sprintf(`DELETE FROM %s WHERE accountId = ?`, r.apiKeyTableName),
+		fmt.Sprintf(`DELETE FROM %s WHERE accountId = ?`, r.identityTableName),
+	}
+	if !r.encrypted() {
+		commands = append(commands, fmt.Sprintf(`DELETE FROM %s WHERE email = (SELECT email FROM %s WHERE id = ?)`, r.loginLockoutTableName, r.tableName))
+	}
+	for _, command := range commands {
+		if _, err = tx.ExecContext(ctx, command, ID); err != nil {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+	}
+
+	// the login lockout records hold the blind index of the email, it has to be decrypted to compute it.
+	if r.encrypted() {
+		if err = r.purgeLoginLockouts(ctx, tx, ID); err != nil {
+			return
+		}
+	}
 
+	result, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = ? AND deletedAt IS NOT NULL`, r.tableName), ID)
 	if err != nil {
 		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected < 1 {
 		err = exception.ErrNotFound
 		return
 	}
 
-	if password.Valid {
-		account.Password = &password.String
+	if err = tx.Commit(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
 	}
 
-	if lastModifiedAt.Valid {
-		account.LastModifiedAt = &lastModifiedAt.Time
+	return
+}
+
+func (r *accountRepositoryImpl) FindByEmail(ctx context.Context, email string) (account Account, err error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE email = ?`, accountColumns, r.tableName)
+	args := []interface{}{email}
+	if r.encrypted() {
+		query = fmt.Sprintf(`SELECT %s FROM %s WHERE emailIndex = ? OR (emailIndex IS NULL AND email = ?) LIMIT 1`, r.selectAccountColumns(), r.tableName)
+		args = []interface{}{r.emailIndex(email), email}
+	}
+
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer stmt.Close()
+
+	account, err = r.scanAccount(ctx, stmt.QueryRowContext(ctx, args...))
+	if err != nil {
+		if err != exception.ErrInternalServer {
+			log.Println(err)
+			err = exception.ErrNotFound
+		}
+		return
 	}
 
 	return
 }
 
 func (r *accountRepositoryImpl) FindByID(ctx context.Context, ID int64) (account Account, err error) {
-	query := fmt.Sprintf(`SELECT id, email, password, firstName, lastName, createdAt, lastModified FROM %s WHERE id = ?`, r.tableName)
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, r.selectAccountColumns(), r.tableName)
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	account, err = r.scanAccount(ctx, stmt.QueryRowContext(ctx, ID))
+	if err != nil {
+		if err != exception.ErrInternalServer {
+			log.Println(err)
+			err = exception.ErrNotFound
+		}
+		return
+	}
+
+	return
+}
+
+// FindMany lists accounts page by page. The query matches any part of the email, first name or last name.
+// With encrypted personal data it only matches the whole email of the encrypted accounts.
+func (r *accountRepositoryImpl) FindMany(ctx context.Context, filter AccountFilter) (bunchOfAccounts []Account, err error) {
+	conditions := []string{"id > ?"}
+	args := []interface{}{filter.Cursor}
+
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Query) + "%"
+		if r.encrypted() {
+			conditions = append(conditions, "(emailIndex = ? OR (personalDataEncrypted = FALSE AND (email LIKE ? OR firstName LIKE ? OR lastName LIKE ?)))")
+			args = append(args, r.emailIndex(filter.Query), pattern, pattern, pattern)
+		} else {
+			conditions = append(conditions, "(email LIKE ? OR firstName LIKE ? OR lastName LIKE ?)")
+			args = append(args, pattern, pattern, pattern)
+		}
+	}
+	if filter.Role != "" {
+		conditions = append(conditions, "role = ?")
+		args = append(args, filter.Role)
+	}
+	switch filter.Status {
+	case AccountStatusActive:
+		conditions = append(conditions, "suspendedAt IS NULL AND deletedAt IS NULL")
+	case AccountStatusSuspended:
+		conditions = append(conditions, "suspendedAt IS NOT NULL")
+	case AccountStatusDeleted:
+		conditions = append(conditions, "deletedAt IS NOT NULL")
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s ORDER BY id ASC LIMIT ?`, r.selectAccountColumns(), r.tableName, strings.Join(conditions, " AND "))
+	args = append(args, filter.Limit)
+
 	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		log.Println(err)
@@ -140,24 +965,76 @@ func (r *accountRepositoryImpl) FindByID(ctx context.Context, ID int64) (account
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRowContext(ctx, ID)
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var account Account
+		if account, err = r.scanAccount(ctx, rows); err != nil {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+
+		bunchOfAccounts = append(bunchOfAccounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	return
+}
+
+// accountColumns are the columns read by scanAccount, in its order.
+const accountColumns = "id, email, password, firstName, lastName, role, verifiedAt, totpSecret, totpEnabledAt, createdAt, lastModified, deletedAt, articleDisposition, suspendedAt, passwordResetRequiredAt"
+
+// likeEscaper escapes the wildcards of a LIKE pattern, so a search query matches them literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// rowScanner is a row of a single or a multiple row query.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+// scanAccount reads the account columns, then the extra destinations.
+func scanAccount(row rowScanner, extra ...interface{}) (account Account, err error) {
 	var password sql.NullString
+	var verifiedAt sql.NullTime
+	var totpSecret sql.NullString
+	var totpEnabledAt sql.NullTime
 	var lastModifiedAt sql.NullTime
+	var deletedAt sql.NullTime
+	var articleDisposition sql.NullString
+	var suspendedAt sql.NullTime
+	var passwordResetRequiredAt sql.NullTime
 
-	err = row.Scan(
+	dest := []interface{}{
 		&account.ID,
 		&account.Email,
 		&password,
 		&account.FirstName,
 		&account.LastName,
+		&account.Role,
+		&verifiedAt,
+		&totpSecret,
+		&totpEnabledAt,
 		&account.CreatedAt,
 		&lastModifiedAt,
-	)
-
+		&deletedAt,
+		&articleDisposition,
+		&suspendedAt,
+		&passwordResetRequiredAt,
+	}
+	err = row.Scan(append(dest, extra...)...)
 	if err != nil {
-		log.Println(err)
-		err = exception.ErrNotFound
 		return
 	}
 
@@ -165,9 +1042,96 @@ func (r *accountRepositoryImpl) FindByID(ctx context.Context, ID int64) (account
 		account.Password = &password.String
 	}
 
+	if verifiedAt.Valid {
+		account.VerifiedAt = &verifiedAt.Time
+	}
+
+	if totpSecret.Valid {
+		account.TOTPSecret = &totpSecret.String
+	}
+
+	if totpEnabledAt.Valid {
+		account.TOTPEnabledAt = &totpEnabledAt.Time
+	}
+
 	if lastModifiedAt.Valid {
 		account.LastModifiedAt = &lastModifiedAt.Time
 	}
 
+	if deletedAt.Valid {
+		account.DeletedAt = &deletedAt.Time
+	}
+
+	if suspendedAt.Valid {
+		account.SuspendedAt = &suspendedAt.Time
+	}
+
+	if passwordResetRequiredAt.Valid {
+		account.PasswordResetRequiredAt = &passwordResetRequiredAt.Time
+	}
+
+	account.ArticleDisposition = ArticleDisposition(articleDisposition.String)
+
+	return
+}
+
+// FindDeletedBefore returns the accounts deleted before the given time, the longest waiting first.
+func (r *accountRepositoryImpl) FindDeletedBefore(ctx context.Context, before time.Time, limit int) (bunchOfAccounts []Account, err error) {
+	columns := "id, email, deletedAt, articleDisposition"
+	if r.encrypted() {
+		columns += ", personalDataEncrypted"
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE deletedAt <= ? ORDER BY deletedAt ASC, id ASC LIMIT ?`, columns, r.tableName)
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, before, limit)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var account Account
+		var deletedAt time.Time
+		var articleDisposition sql.NullString
+		var personalDataEncrypted bool
+
+		dest := []interface{}{&account.ID, &account.Email, &deletedAt, &articleDisposition}
+		if r.encrypted() {
+			dest = append(dest, &personalDataEncrypted)
+		}
+
+		if err = rows.Scan(dest...); err != nil {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+		if personalDataEncrypted {
+			if account.Email, err = r.cipher.Decrypt(ctx, account.Email); err != nil {
+				log.Println(err)
+				err = exception.ErrInternalServer
+				return
+			}
+		}
+
+		account.DeletedAt = &deletedAt
+		account.ArticleDisposition = ArticleDisposition(articleDisposition.String)
+		bunchOfAccounts = append(bunchOfAccounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
 	return
 }
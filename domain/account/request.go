@@ -2,14 +2,142 @@ package account
 
 // AccountRegistrationRequest is a model for account registration.
 type AccountRegistrationRequest struct {
+	Device    `json:"-"`
 	Email     string `json:"email" validate:"email"`
 	Password  string `json:"password" validate:"required"`
 	FirstName string `json:"firstName" validate:"required"`
 	LastName  string `json:"lastName" validate:"required"`
 }
 
+// Device is the client a request comes from, it is filled by the handler and never decoded from the body.
+type Device struct {
+	UserAgent string
+	ClientIP  string
+}
+
 // AccountAuthenticationRequest is a model of account authentication.
 type AccountAuthenticationRequest struct {
+	Device   `json:"-"`
 	Email    string `json:"email" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
+
+// AccountRefreshTokenRequest is a model of access token renewal.
+type AccountRefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// AccountLogoutRequest is a model of logout from the current device.
+type AccountLogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// AccountUpdateRequest is a model of partial account profile update, omitted fields are left unchanged.
+type AccountUpdateRequest struct {
+	FirstName *string `json:"firstName" validate:"omitempty,min=1,max=100"`
+	LastName  *string `json:"lastName" validate:"omitempty,min=1,max=100"`
+}
+
+// AccountChangePasswordRequest is a model of password change.
+type AccountChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8,nefield=CurrentPassword"`
+}
+
+// AccountForgotPasswordRequest is a model of password reset request.
+type AccountForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// AccountResetPasswordRequest is a model of password reset.
+type AccountResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,min=8"`
+}
+
+// AccountVerifyEmailRequest is a model of email verification.
+type AccountVerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// AccountResendEmailVerificationRequest is a model of verification mail resend.
+type AccountResendEmailVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// AccountChangeEmailRequest is a model of email change.
+type AccountChangeEmailRequest struct {
+	NewEmail string `json:"newEmail" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// AccountConfirmEmailChangeRequest is a model of email change confirmation from the new address.
+type AccountConfirmEmailChangeRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// AccountUndoEmailChangeRequest is a model of email change cancellation from the old address.
+type AccountUndoEmailChangeRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// AccountCreateAPIKeyRequest is a model of personal api key creation.
+type AccountCreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,unique,dive,oneof=articles:read articles:write"`
+}
+
+// AccountConfirmTOTPRequest is a model of totp enrolment confirmation.
+type AccountConfirmTOTPRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// AccountDisableTOTPRequest is a model of totp removal.
+type AccountDisableTOTPRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// AccountMFALoginRequest is a model of the second login step, either a totp code or a recovery code.
+type AccountMFALoginRequest struct {
+	Device         `json:"-"`
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode   string `json:"recoveryCode" validate:"required_without=Code"`
+}
+
+// AccountOIDCCallbackRequest is a model of the return from the external provider with its authorization code.
+type AccountOIDCCallbackRequest struct {
+	Device `json:"-"`
+	Code   string `json:"code" validate:"required"`
+	State  string `json:"state" validate:"required"`
+}
+
+// AccountDeletionRequest is a model of account deletion, the articles are either deleted or kept without their author.
+type AccountDeletionRequest struct {
+	Password string             `json:"password" validate:"required"`
+	Articles ArticleDisposition `json:"articles" validate:"required,oneof=DELETE ANONYMISE"`
+}
+
+// AccountExportRequest is a model of personal data export, the format is either json or zip.
+type AccountExportRequest struct {
+	Format string `validate:"omitempty,oneof=json zip"`
+}
+
+// AdminListAccountsRequest is a model of account listing by an admin, it is read from the query string.
+type AdminListAccountsRequest struct {
+	Query  string `json:"query,omitempty" validate:"max=100"`
+	Role   string `json:"role,omitempty" validate:"omitempty,oneof=user editor admin"`
+	Status string `json:"status,omitempty" validate:"omitempty,oneof=active suspended deleted"`
+	Cursor int64  `json:"cursor,omitempty" validate:"gte=0"`
+	Limit  int    `json:"limit,omitempty" validate:"omitempty,min=1,max=100"`
+}
+
+// AdminSuspendAccountRequest is a model of account suspension, the reason is kept in the audit log.
+type AdminSuspendAccountRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// AdminAssignRoleRequest is a model of role assignment, the role must be one of the stored roles.
+type AdminAssignRoleRequest struct {
+	Role string `json:"role" validate:"required,max=30"`
+}
@@ -1,6 +1,63 @@
 package account
 
+import "time"
+
 type AccountAuthenticationResponse struct {
-	Token   string  `json:"token"`
-	Profile Account `json:"profile"`
+	Token        string  `json:"token"`
+	RefreshToken string  `json:"refreshToken"`
+	Profile      Account `json:"profile"`
+}
+
+// AccountTokenResponse is a model of renewed account tokens.
+type AccountTokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// AccountMFAChallengeResponse is a model of a login waiting for its second factor.
+type AccountMFAChallengeResponse struct {
+	MFARequired    bool   `json:"mfaRequired"`
+	ChallengeToken string `json:"challengeToken"`
+}
+
+// AccountOIDCAuthorizationResponse is a model of a login started at the external provider,
+// the user agent is sent to the authorization url and comes back with the state.
+type AccountOIDCAuthorizationResponse struct {
+	AuthorizationURL string `json:"authorizationURL"`
+	State            string `json:"state"`
+}
+
+// AccountTOTPEnrolmentResponse is a model of a pending totp enrolment.
+type AccountTOTPEnrolmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// AccountRecoveryCodesResponse is a model of one-time recovery codes, they are only shown once.
+type AccountRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// AccountSessionResponse is a model of a signed in device.
+type AccountSessionResponse struct {
+	DeviceSession
+	Current bool `json:"current"`
+}
+
+// AccountAPIKeyResponse is a model of a created personal api key, the key is only shown once.
+type AccountAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// AccountDeletionResponse is a model of an account waiting for its purge.
+type AccountDeletionResponse struct {
+	PurgeAt time.Time `json:"purgeAt"`
+}
+
+// AccountExportResponse is a model of the personal data of an account.
+type AccountExportResponse struct {
+	Profile    Account           `json:"profile"`
+	Articles   []AuthoredArticle `json:"articles"`
+	ExportedAt time.Time         `json:"exportedAt"`
 }
@@ -0,0 +1,254 @@
+package account
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/response"
+	"github.com/sangianpatrick/devoria-article-service/session"
+)
+
+const refreshTokenByteSize = 32
+
+// RefreshToken rotates the refresh token and issues a new access token.
+// Presenting a refresh token that has already been rotated revokes its whole family,
+// because either the client or an attacker holds a stolen copy.
+func (u *accountUsecaseImpl) RefreshToken(ctx context.Context, params AccountRefreshTokenRequest) (resp response.Response) {
+	tokenKey := fmt.Sprintf(AccountRefreshTokenKeyFormat, hashToken(params.RefreshToken))
+
+	buff, err := u.refreshTokenSession.Get(ctx, tokenKey)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	var refreshToken RefreshToken
+	if err = json.Unmarshal(buff, &refreshToken); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	familyKey := fmt.Sprintf(AccountRefreshTokenFamilyKeyFormat, refreshToken.FamilyID)
+
+	if refreshToken.Used {
+		return u.revokeRefreshTokenFamily(ctx, refreshToken)
+	}
+
+	if _, err = u.refreshTokenSession.Get(ctx, familyKey); err != nil {
+		if err == session.ErrSessionNotFound {
+			return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	revoked, err := u.tokenRevocation.IsRevoked(ctx, "", fmt.Sprintf("%d", refreshToken.AccountID), refreshToken.CreatedAt)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if revoked {
+		return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+	}
+
+	deviceSession, err := u.deviceSessionRepository.FindByID(ctx, refreshToken.FamilyID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	// only one of the requests presenting the same token marks it used, the others are a reuse.
+	refreshToken.Used = true
+	usedBuff, _ := json.Marshal(refreshToken)
+	swapped, err := u.refreshTokenSession.CompareAndSwap(ctx, tokenKey, buff, usedBuff)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if !swapped {
+		return u.revokeRefreshTokenFamily(ctx, refreshToken)
+	}
+
+	account, err := u.repository.FindByID(ctx, refreshToken.AccountID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if account.DeletedAt != nil || account.SuspendedAt != nil {
+		return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+	}
+
+	token, err := u.signAccessToken(ctx, account, deviceSession.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	// saving the device session again slides its expiry along with the refresh token family.
+	deviceSession.LastSeenAt = time.Now().In(u.location)
+	if err = u.deviceSessionRepository.Save(ctx, deviceSession); err != nil {
+		log.Println(err)
+	}
+
+	newRefreshToken, err := u.issueRefreshToken(ctx, account.ID, refreshToken.FamilyID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	accountTokenResponse := AccountTokenResponse{}
+	accountTokenResponse.Token = token
+	accountTokenResponse.RefreshToken = newRefreshToken
+
+	return response.Success(response.StatusOK, accountTokenResponse)
+}
+
+// revokeRefreshTokenFamily ends the family of a refresh token presented again after its rotation.
+func (u *accountUsecaseImpl) revokeRefreshTokenFamily(ctx context.Context, refreshToken RefreshToken) (resp response.Response) {
+	log.Printf("refresh token reuse detected, revoking family %s of account %d\n", refreshToken.FamilyID, refreshToken.AccountID)
+	if err := u.refreshTokenSession.Delete(ctx, fmt.Sprintf(AccountRefreshTokenFamilyKeyFormat, refreshToken.FamilyID)); err != nil {
+		log.Println(err)
+	}
+	return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+}
+
+// Logout revokes the access token and the session of the current device and, when given, its refresh token family.
+func (u *accountUsecaseImpl) Logout(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountLogoutRequest) (resp response.Response) {
+	if claims.Id != "" {
+		if err := u.tokenRevocation.Revoke(ctx, claims.Id); err != nil {
+			return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+		}
+	}
+
+	if claims.SessionID != "" {
+		ID, err := strconv.ParseInt(claims.Subject, 10, 64)
+		if err != nil {
+			return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+		}
+		if resp = u.deleteDeviceSession(ctx, ID, claims.SessionID); resp != nil {
+			return
+		}
+	}
+
+	if params.RefreshToken == "" {
+		return response.Success(response.StatusOK, nil)
+	}
+
+	buff, err := u.refreshTokenSession.Get(ctx, fmt.Sprintf(AccountRefreshTokenKeyFormat, hashToken(params.RefreshToken)))
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return response.Success(response.StatusOK, nil)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	var refreshToken RefreshToken
+	if err = json.Unmarshal(buff, &refreshToken); err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if fmt.Sprintf("%d", refreshToken.AccountID) != claims.Subject {
+		return response.Error(response.StatusForbiddend, nil, exception.ErrForbidden)
+	}
+
+	err = u.refreshTokenSession.Delete(ctx, fmt.Sprintf(AccountRefreshTokenFamilyKeyFormat, refreshToken.FamilyID))
+	if err != nil {
+		log.Println(err)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// LogoutAll revokes every access and refresh token of the account issued until now and its api keys.
+func (u *accountUsecaseImpl) LogoutAll(ctx context.Context, claims entity.AccountStandardJWTClaims) (resp response.Response) {
+	ID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+	}
+
+	err = u.revokeAllTokens(ctx, ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	err = u.revokeAPIKeys(ctx, ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// revokeAllTokens revokes every access and refresh token of the account issued until now and drops its device sessions.
+func (u *accountUsecaseImpl) revokeAllTokens(ctx context.Context, accountID int64) (err error) {
+	err = u.tokenRevocation.RevokeAll(ctx, strconv.FormatInt(accountID, 10), time.Now())
+	if err != nil {
+		return
+	}
+
+	if err := u.deviceSessionRepository.DeleteByAccountID(ctx, accountID); err != nil {
+		log.Println(err)
+	}
+
+	return
+}
+
+// signAccessToken signs a short-lived access token of the account.
+func (u *accountUsecaseImpl) signAccessToken(ctx context.Context, account Account, sessionID string) (token string, err error) {
+	claims := entity.AccountStandardJWTClaims{}
+	claims.SessionID = sessionID
+	claims.Email = account.Email
+	claims.Role = account.Role
+	claims.EmailVerified = account.VerifiedAt != nil
+	claims.Id = u.generateBase64String(16)
+	claims.Subject = fmt.Sprintf("%d", account.ID)
+	claims.IssuedAt = time.Now().Unix()
+	claims.ExpiresAt = time.Now().Add(u.accessTokenTTL).Unix()
+
+	return u.jsonWebToken.Sign(ctx, claims)
+}
+
+// issueRefreshToken stores a new refresh token in the family, an empty family ID starts a new one.
+// Storing the family again slides its expiry, so an active client stays signed in.
+func (u *accountUsecaseImpl) issueRefreshToken(ctx context.Context, accountID int64, familyID string) (token string, err error) {
+	if familyID == "" {
+		familyID = u.generateBase64String(16)
+	}
+
+	token = u.generateBase64String(refreshTokenByteSize)
+	if token == "" || familyID == "" {
+		return "", exception.ErrInternalServer
+	}
+
+	err = u.refreshTokenSession.Set(ctx, fmt.Sprintf(AccountRefreshTokenFamilyKeyFormat, familyID), []byte(fmt.Sprintf("%d", accountID)))
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken := RefreshToken{
+		AccountID: accountID,
+		FamilyID:  familyID,
+		CreatedAt: time.Now().In(u.location),
+	}
+	buff, _ := json.Marshal(refreshToken)
+
+	err = u.refreshTokenSession.Set(ctx, fmt.Sprintf(AccountRefreshTokenKeyFormat, hashToken(token)), buff)
+	if err != nil {
+		return "", err
+	}
+
+	return
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,100 @@
+package unittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/middleware"
+)
+
+// stubJwtAuth stands for the jwt middleware, it lets every request through without binding claims.
+type stubJwtAuth struct {
+	called bool
+}
+
+func (s *stubJwtAuth) VerifyToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.called = true
+		next(w, r)
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	location, _ := time.LoadLocation("Asia/Jakarta")
+	accountRepository := new(MockAccountRepository)
+
+	key := "dak_0123456789abcdef"
+	accountRepository.On("FindAPIKeyByHash", mock.Anything, hashOf(key)).Return(account.APIKey{ID: 3, AccountID: 14, Scopes: []string{entity.ScopeArticlesRead}}, nil)
+	accountRepository.On("FindByID", mock.Anything, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", Role: entity.RoleUser}, nil)
+	accountRepository.On("UpdateAPIKeyLastUsedAt", mock.Anything, int64(3), mock.AnythingOfType("time.Time")).Return(nil)
+
+	jwtAuth := &stubJwtAuth{}
+	apiKeyAuth := account.NewAPIKeyAuth(jwtAuth, location, accountRepository)
+
+	var claims entity.AccountStandardJWTClaims
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		bind, _ := context.Get(r, "bind").([]byte)
+		json.Unmarshal(bind, &claims)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/articles", nil)
+	request.Header.Set("Authorization", "ApiKey "+key)
+	recorder := httptest.NewRecorder()
+	apiKeyAuth.VerifyToken(middleware.NewScope(entity.ScopeArticlesRead).Verify(handler))(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.False(t, jwtAuth.called)
+	assert.Equal(t, "14", claims.Subject)
+	assert.Equal(t, "johndoe@mail.com", claims.Email)
+	assert.Equal(t, []string{entity.ScopeArticlesRead}, claims.Scopes)
+
+	request = httptest.NewRequest(http.MethodPost, "/v1/article/create", nil)
+	request.Header.Set("Authorization", "ApiKey "+key)
+	recorder = httptest.NewRecorder()
+	apiKeyAuth.VerifyToken(middleware.NewScope(entity.ScopeArticlesWrite).Verify(handler))(recorder, request)
+
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestAPIKeyAuth_UnknownKey(t *testing.T) {
+	location, _ := time.LoadLocation("Asia/Jakarta")
+	accountRepository := new(MockAccountRepository)
+
+	accountRepository.On("FindAPIKeyByHash", mock.Anything, mock.AnythingOfType("string")).Return(account.APIKey{}, exception.ErrNotFound)
+
+	apiKeyAuth := account.NewAPIKeyAuth(&stubJwtAuth{}, location, accountRepository)
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/articles", nil)
+	request.Header.Set("Authorization", "ApiKey dak_unknown")
+	recorder := httptest.NewRecorder()
+	apiKeyAuth.VerifyToken(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler must not be reached")
+	})(recorder, request)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestAPIKeyAuth_Bearer(t *testing.T) {
+	location, _ := time.LoadLocation("Asia/Jakarta")
+	accountRepository := new(MockAccountRepository)
+
+	jwtAuth := &stubJwtAuth{}
+	apiKeyAuth := account.NewAPIKeyAuth(jwtAuth, location, accountRepository)
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/articles", nil)
+	request.Header.Set("Authorization", "Bearer token")
+	apiKeyAuth.VerifyToken(func(w http.ResponseWriter, r *http.Request) {})(httptest.NewRecorder(), request)
+
+	assert.True(t, jwtAuth.called)
+	accountRepository.AssertNotCalled(t, "FindAPIKeyByHash", mock.Anything, mock.Anything)
+}
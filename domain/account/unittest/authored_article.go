@@ -0,0 +1,27 @@
+package unittest
+
+import (
+	"context"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuthoredArticleRepository struct {
+	mock.Mock
+}
+
+func (d *MockAuthoredArticleRepository) FindByAuthorID(ctx context.Context, authorID int64) (bunchOfArticles []account.AuthoredArticle, err error) {
+	args := d.Called(ctx, authorID)
+	return args.Get(0).([]account.AuthoredArticle), args.Error(1)
+}
+
+func (d *MockAuthoredArticleRepository) DeleteByAuthorID(ctx context.Context, authorID int64) (err error) {
+	args := d.Called(ctx, authorID)
+	return args.Error(0)
+}
+
+func (d *MockAuthoredArticleRepository) AnonymiseByAuthorID(ctx context.Context, authorID int64) (err error) {
+	args := d.Called(ctx, authorID)
+	return args.Error(0)
+}
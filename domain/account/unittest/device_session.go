@@ -0,0 +1,42 @@
+package unittest
+
+import (
+	"context"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDeviceSessionRepository struct {
+	mock.Mock
+}
+
+func (d *MockDeviceSessionRepository) Save(ctx context.Context, deviceSession account.DeviceSession) (err error) {
+	args := d.Called(ctx, deviceSession)
+	return args.Error(0)
+}
+
+func (d *MockDeviceSessionRepository) FindByID(ctx context.Context, ID string) (deviceSession account.DeviceSession, err error) {
+	args := d.Called(ctx, ID)
+	return args.Get(0).(account.DeviceSession), args.Error(1)
+}
+
+func (d *MockDeviceSessionRepository) FindByAccountID(ctx context.Context, accountID int64) (deviceSessions []account.DeviceSession, err error) {
+	args := d.Called(ctx, accountID)
+	return args.Get(0).([]account.DeviceSession), args.Error(1)
+}
+
+func (d *MockDeviceSessionRepository) Delete(ctx context.Context, accountID int64, ID string) (err error) {
+	args := d.Called(ctx, accountID, ID)
+	return args.Error(0)
+}
+
+func (d *MockDeviceSessionRepository) DeleteByAccountID(ctx context.Context, accountID int64) (err error) {
+	args := d.Called(ctx, accountID)
+	return args.Error(0)
+}
+
+func (d *MockDeviceSessionRepository) IsSessionActive(ctx context.Context, subject string, sessionID string) (active bool, err error) {
+	args := d.Called(ctx, subject, sessionID)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,60 @@
+package unittest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+
+	redismock "github.com/go-redis/redismock/v8"
+)
+
+func TestDeviceSessionRepository_FindByAccountID_PrunesExpired(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	mock.ExpectSMembers("account:device-sessions:14").SetVal([]string{"expired", "live"})
+	mock.ExpectGet("account:device-session:expired").RedisNil()
+	mock.ExpectSRem("account:device-sessions:14", "expired").SetVal(1)
+	mock.ExpectGet("account:device-session:live").SetVal(`{"id":"live","accountId":14,"userAgent":"curl/7.79.1"}`)
+
+	repository := account.NewDeviceSessionRepository(rdb, time.Hour)
+	deviceSessions, err := repository.FindByAccountID(context.TODO(), 14)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []account.DeviceSession{{ID: "live", AccountID: 14, UserAgent: "curl/7.79.1"}}, deviceSessions)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeviceSessionRepository_IsSessionActive(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	mock.ExpectGet("account:device-session:live").SetVal(`{"id":"live","accountId":14}`)
+	mock.ExpectGet("account:device-session:live").SetVal(`{"id":"live","accountId":14}`)
+	mock.ExpectGet("account:device-session:revoked").RedisNil()
+
+	repository := account.NewDeviceSessionRepository(rdb, time.Hour)
+
+	active, err := repository.IsSessionActive(context.TODO(), "14", "live")
+	assert.NoError(t, err)
+	assert.True(t, active)
+
+	active, err = repository.IsSessionActive(context.TODO(), "15", "live")
+	assert.NoError(t, err)
+	assert.False(t, active)
+
+	active, err = repository.IsSessionActive(context.TODO(), "14", "revoked")
+	assert.NoError(t, err)
+	assert.False(t, active)
+
+	active, err = repository.IsSessionActive(context.TODO(), "14", "")
+	assert.NoError(t, err)
+	assert.False(t, active)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,17 @@
+package unittest
+
+import (
+	"context"
+
+	"github.com/sangianpatrick/devoria-article-service/mailer"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (d *MockMailer) Send(ctx context.Context, mail mailer.Mail) (err error) {
+	args := d.Called(ctx, mail)
+	return args.Error(0)
+}
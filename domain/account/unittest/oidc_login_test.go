@@ -0,0 +1,272 @@
+package unittest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+	"github.com/sangianpatrick/devoria-article-service/oidc"
+	"github.com/sangianpatrick/devoria-article-service/oidc/oidctest"
+	"github.com/sangianpatrick/devoria-article-service/response"
+	"github.com/sangianpatrick/devoria-article-service/session"
+)
+
+type oidcLoginFixture struct {
+	server                  *oidctest.Server
+	oidcStateSess           *MockSession
+	mfaChallengeSess        *MockSession
+	refreshTokenSess        *MockSession
+	emailVerificationSess   *MockSession
+	emailChangeUndoSess     *MockSession
+	deviceSessionRepository *MockDeviceSessionRepository
+	jsonWebToken            *MockJSONWebToken
+	accountRepository       *MockAccountRepository
+	accountUsecase          account.AccountUsecase
+}
+
+func newOIDCLoginFixture() *oidcLoginFixture {
+	f := &oidcLoginFixture{
+		server:                  oidctest.NewServer("devoria", "secret"),
+		oidcStateSess:           new(MockSession),
+		mfaChallengeSess:        new(MockSession),
+		refreshTokenSess:        new(MockSession),
+		emailVerificationSess:   new(MockSession),
+		emailChangeUndoSess:     new(MockSession),
+		deviceSessionRepository: new(MockDeviceSessionRepository),
+		jsonWebToken:            new(MockJSONWebToken),
+		accountRepository:       new(MockAccountRepository),
+	}
+	provider := oidc.NewHTTPProvider(http.DefaultClient, f.server.Config())
+	deps := newAccountUsecaseDependencies(f.accountRepository)
+	deps.DeviceSessionRepository = f.deviceSessionRepository
+	deps.RefreshTokenSession = f.refreshTokenSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(f.refreshTokenSess)
+	deps.MFAChallengeSession = f.mfaChallengeSess
+	deps.EmailVerificationSession = f.emailVerificationSess
+	deps.EmailChangeUndoSession = f.emailChangeUndoSess
+	deps.OIDCStateSession = f.oidcStateSess
+	deps.JSONWebToken = f.jsonWebToken
+	deps.OIDCProvider = provider
+	f.accountUsecase = account.NewAccountUsecase(deps)
+
+	return f
+}
+
+// login starts a login, lets the stub provider authorize it with the claims and returns the callback request.
+func (f *oidcLoginFixture) login(t *testing.T, ctx context.Context, claims jwtgo.MapClaims) account.AccountOIDCCallbackRequest {
+	var storedState []byte
+	f.oidcStateSess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Run(func(args mock.Arguments) {
+		storedState = args.Get(2).([]byte)
+	}).Return(nil).Once()
+
+	resp := f.accountUsecase.StartOIDCLogin(ctx)
+	assert.NoError(t, resp.Err())
+	var authorization account.AccountOIDCAuthorizationResponse
+	decodeResponseData(t, resp, &authorization)
+
+	code, state, err := f.server.Authorize(authorization.AuthorizationURL, claims)
+	assert.NoError(t, err)
+	assert.Equal(t, authorization.State, state)
+
+	sum := sha256.Sum256([]byte(state))
+	stateKey := fmt.Sprintf(account.AccountOIDCStateKeyFormat, hex.EncodeToString(sum[:]))
+	f.oidcStateSess.On("Consume", ctx, stateKey).Return(storedState, nil).Once()
+
+	return account.AccountOIDCCallbackRequest{Code: code, State: state}
+}
+
+func (f *oidcLoginFixture) expectAuthentication(ctx context.Context) {
+	f.jsonWebToken.On("Sign", ctx, mock.Anything).Return("access-token", nil)
+	f.refreshTokenSess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Return(nil)
+	f.deviceSessionRepository.On("Save", ctx, mock.AnythingOfType("account.DeviceSession")).Return(nil)
+}
+
+func TestOIDCLogin_NewAccount(t *testing.T) {
+	ctx := context.Background()
+	f := newOIDCLoginFixture()
+	defer f.server.Close()
+
+	params := f.login(t, ctx, jwtgo.MapClaims{"sub": "42", "email": "johndoe@mail.com", "email_verified": true, "given_name": "John", "family_name": "Doe"})
+
+	f.accountRepository.On("FindIdentity", ctx, f.server.URL, "42").Return(account.AccountIdentity{}, exception.ErrNotFound)
+	f.accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{}, exception.ErrNotFound)
+	f.accountRepository.On("Save", ctx, mock.MatchedBy(func(newAccount account.Account) bool {
+		return newAccount.Email == "johndoe@mail.com" && newAccount.FirstName == "John" && newAccount.Password == nil
+	})).Return(21, nil)
+	f.accountRepository.On("UpdateVerifiedAt", ctx, int64(21), mock.AnythingOfType("time.Time")).Return(nil)
+	f.accountRepository.On("SaveIdentity", ctx, mock.MatchedBy(func(identity account.AccountIdentity) bool {
+		return identity.AccountID == 21 && identity.Issuer == f.server.URL && identity.Subject == "42"
+	})).Return(1, nil)
+	f.expectAuthentication(ctx)
+
+	resp := f.accountUsecase.CompleteOIDCLogin(ctx, params)
+
+	assert.NoError(t, resp.Err())
+	var authentication account.AccountAuthenticationResponse
+	decodeResponseData(t, resp, &authentication)
+	assert.Equal(t, "access-token", authentication.Token)
+	assert.Equal(t, int64(21), authentication.Profile.ID)
+	assert.NotNil(t, authentication.Profile.VerifiedAt)
+	f.accountRepository.AssertExpectations(t)
+	f.oidcStateSess.AssertExpectations(t)
+}
+
+func TestOIDCLogin_LinkedIdentityWithTOTP(t *testing.T) {
+	ctx := context.Background()
+	f := newOIDCLoginFixture()
+	defer f.server.Close()
+
+	totpEnabledAt := time.Now()
+	params := f.login(t, ctx, jwtgo.MapClaims{"sub": "42"})
+
+	f.accountRepository.On("FindIdentity", ctx, f.server.URL, "42").Return(account.AccountIdentity{ID: 1, AccountID: 14}, nil)
+	f.accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", TOTPEnabledAt: &totpEnabledAt}, nil)
+	f.mfaChallengeSess.On("Set", ctx, mock.AnythingOfType("string"), []byte(`{"accountId":14}`)).Return(nil)
+
+	resp := f.accountUsecase.CompleteOIDCLogin(ctx, params)
+
+	assert.NoError(t, resp.Err())
+	var challenge account.AccountMFAChallengeResponse
+	decodeResponseData(t, resp, &challenge)
+	assert.True(t, challenge.MFARequired)
+	f.jsonWebToken.AssertNotCalled(t, "Sign", mock.Anything, mock.Anything)
+}
+
+func TestOIDCLogin_ClaimsUnverifiedAccount(t *testing.T) {
+	ctx := context.Background()
+	f := newOIDCLoginFixture()
+	defer f.server.Close()
+
+	password := "$2a$04$hash"
+	params := f.login(t, ctx, jwtgo.MapClaims{"sub": "42", "email": "johndoe@mail.com", "email_verified": true})
+
+	f.accountRepository.On("FindIdentity", ctx, f.server.URL, "42").Return(account.AccountIdentity{}, exception.ErrNotFound)
+	f.accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &password}, nil)
+	f.accountRepository.On("ClearPassword", ctx, int64(14)).Return(nil)
+	f.accountRepository.On("SaveRecoveryCodes", ctx, int64(14), []string(nil), mock.AnythingOfType("time.Time")).Return(nil)
+	f.accountRepository.On("DeleteAPIKeysByAccountID", ctx, int64(14)).Return(nil)
+	f.emailVerificationSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangePendingKeyFormat, 14)).Return([]byte(nil), session.ErrSessionNotFound)
+	f.refreshTokenSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	f.deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+	f.accountRepository.On("UpdateVerifiedAt", ctx, int64(14), mock.AnythingOfType("time.Time")).Return(nil)
+	f.accountRepository.On("SaveIdentity", ctx, mock.AnythingOfType("account.AccountIdentity")).Return(1, nil)
+	f.expectAuthentication(ctx)
+
+	resp := f.accountUsecase.CompleteOIDCLogin(ctx, params)
+
+	// the account never enrolled a second factor, there is nothing to clear.
+	assert.NoError(t, resp.Err())
+	f.accountRepository.AssertNotCalled(t, "UpdateTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	f.accountRepository.AssertExpectations(t)
+	f.deviceSessionRepository.AssertExpectations(t)
+}
+
+func TestOIDCLogin_ClaimsUnverifiedAccountWithSecondFactor(t *testing.T) {
+	ctx := context.Background()
+	f := newOIDCLoginFixture()
+	defer f.server.Close()
+
+	password := "$2a$04$hash"
+	totpSecret := "squatter-secret"
+	totpEnabledAt := time.Now()
+	params := f.login(t, ctx, jwtgo.MapClaims{"sub": "42", "email": "johndoe@mail.com", "email_verified": true})
+
+	pending, _ := json.Marshal(account.EmailChange{AccountID: 14, OldEmail: "johndoe@mail.com", NewEmail: "squatter@mail.com", ConfirmTokenHash: "confirm-hash", UndoTokenHash: "undo-hash"})
+
+	f.accountRepository.On("FindIdentity", ctx, f.server.URL, "42").Return(account.AccountIdentity{}, exception.ErrNotFound)
+	f.accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &password, TOTPSecret: &totpSecret, TOTPEnabledAt: &totpEnabledAt}, nil)
+	f.accountRepository.On("ClearPassword", ctx, int64(14)).Return(nil)
+	f.accountRepository.On("UpdateTOTP", ctx, int64(14), (*string)(nil), (*time.Time)(nil)).Return(nil)
+	f.accountRepository.On("SaveRecoveryCodes", ctx, int64(14), []string(nil), mock.AnythingOfType("time.Time")).Return(nil)
+	f.accountRepository.On("DeleteAPIKeysByAccountID", ctx, int64(14)).Return(nil)
+	f.emailVerificationSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangePendingKeyFormat, 14)).Return(pending, nil)
+	f.emailVerificationSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangeKeyFormat, "confirm-hash")).Return(pending, nil)
+	f.emailChangeUndoSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangeUndoKeyFormat, "undo-hash")).Return(pending, nil)
+	f.refreshTokenSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	f.deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+	f.accountRepository.On("UpdateVerifiedAt", ctx, int64(14), mock.AnythingOfType("time.Time")).Return(nil)
+	f.accountRepository.On("SaveIdentity", ctx, mock.AnythingOfType("account.AccountIdentity")).Return(1, nil)
+	f.expectAuthentication(ctx)
+
+	resp := f.accountUsecase.CompleteOIDCLogin(ctx, params)
+
+	// the squatter's second factor is gone, so the owner is signed in without being challenged for it.
+	assert.NoError(t, resp.Err())
+	var authentication account.AccountAuthenticationResponse
+	decodeResponseData(t, resp, &authentication)
+	assert.Equal(t, "access-token", authentication.Token)
+	f.mfaChallengeSess.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything)
+	f.accountRepository.AssertExpectations(t)
+	f.emailVerificationSess.AssertExpectations(t)
+	f.emailChangeUndoSess.AssertExpectations(t)
+	f.deviceSessionRepository.AssertExpectations(t)
+}
+
+func TestOIDCLogin_FindByEmailFailure(t *testing.T) {
+	ctx := context.Background()
+	f := newOIDCLoginFixture()
+	defer f.server.Close()
+
+	params := f.login(t, ctx, jwtgo.MapClaims{"sub": "42", "email": "johndoe@mail.com", "email_verified": true})
+
+	f.accountRepository.On("FindIdentity", ctx, f.server.URL, "42").Return(account.AccountIdentity{}, exception.ErrNotFound)
+	f.accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{}, exception.ErrInternalServer)
+
+	resp := f.accountUsecase.CompleteOIDCLogin(ctx, params)
+
+	// a failed lookup must not be taken for a new email.
+	assert.Equal(t, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer), resp)
+	f.accountRepository.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestOIDCLogin_UnverifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	f := newOIDCLoginFixture()
+	defer f.server.Close()
+
+	params := f.login(t, ctx, jwtgo.MapClaims{"sub": "42", "email": "johndoe@mail.com", "email_verified": false})
+
+	f.accountRepository.On("FindIdentity", ctx, f.server.URL, "42").Return(account.AccountIdentity{}, exception.ErrNotFound)
+
+	resp := f.accountUsecase.CompleteOIDCLogin(ctx, params)
+
+	assert.Equal(t, response.Error(response.StatusForbiddend, nil, account.ErrOIDCEmailNotVerified), resp)
+	f.accountRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+}
+
+func TestOIDCLogin_WrongNonce(t *testing.T) {
+	ctx := context.Background()
+	f := newOIDCLoginFixture()
+	defer f.server.Close()
+
+	params := f.login(t, ctx, jwtgo.MapClaims{"sub": "42", "nonce": "another nonce"})
+
+	resp := f.accountUsecase.CompleteOIDCLogin(ctx, params)
+
+	assert.Equal(t, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized), resp)
+	f.accountRepository.AssertNotCalled(t, "FindIdentity", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOIDCLogin_UnknownState(t *testing.T) {
+	ctx := context.Background()
+	f := newOIDCLoginFixture()
+	defer f.server.Close()
+
+	f.oidcStateSess.On("Consume", ctx, mock.AnythingOfType("string")).Return([]byte(nil), session.ErrSessionNotFound)
+
+	resp := f.accountUsecase.CompleteOIDCLogin(ctx, account.AccountOIDCCallbackRequest{Code: "code", State: "state"})
+
+	assert.Equal(t, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized), resp)
+}
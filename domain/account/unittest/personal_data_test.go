@@ -0,0 +1,282 @@
+package unittest
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/crypto"
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+)
+
+var encryptedAccountColumns = []string{"id", "email", "password", "firstName", "lastName", "role", "verifiedAt", "totpSecret", "totpEnabledAt", "createdAt", "lastModified", "deletedAt", "articleDisposition", "suspendedAt", "passwordResetRequiredAt", "personalDataEncrypted"}
+
+func newPersonalDataCrypto(t *testing.T) (crypto.Cipher, crypto.BlindIndex) {
+	cipher, err := crypto.NewAEADCipher(crypto.VersionAES256GCM, "abcdefghijklmnopqrstuvwxyz123456", "", "")
+	assert.NoError(t, err)
+	blindIndex, err := crypto.NewHMACBlindIndex("an index key that is not an encryption key")
+	assert.NoError(t, err)
+
+	return cipher, blindIndex
+}
+
+// isEncrypted matches an argument encrypting the plaintext.
+type isEncrypted struct {
+	cipher    crypto.Cipher
+	plaintext string
+}
+
+func (m isEncrypted) Match(value driver.Value) bool {
+	encrypted, ok := value.(string)
+	if !ok || !strings.HasPrefix(encrypted, crypto.VersionAES256GCM+":") {
+		return false
+	}
+	plaintext, err := m.cipher.Decrypt(context.Background(), encrypted)
+	return err == nil && plaintext == m.plaintext
+}
+
+func TestEncryptedAccountRepository_Save(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cipher, blindIndex := newPersonalDataCrypto(t)
+	password := "hashed"
+	newAccount := account.Account{Email: "JohnDoe@mail.com", Password: &password, FirstName: "John", LastName: "Doe", Role: entity.RoleUser, CreatedAt: time.Now()}
+
+	dbMock.ExpectPrepare(`INSERT INTO account \(email, emailIndex, personalDataEncrypted, password, firstName, lastName, role, createdAt\) VALUES \(\?, \?, TRUE,`).
+		ExpectExec().
+		WithArgs(isEncrypted{cipher, "JohnDoe@mail.com"}, blindIndex.Index("johndoe@mail.com"), password, isEncrypted{cipher, "John"}, isEncrypted{cipher, "Doe"}, newAccount.Role, newAccount.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(14, 1))
+
+	ID, err := account.NewEncryptedAccountRepository(db, "account", cipher, blindIndex).Save(context.Background(), newAccount)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(14), ID)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestEncryptedAccountRepository_FindByEmail(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cipher, blindIndex := newPersonalDataCrypto(t)
+	email, _ := cipher.Encrypt(context.Background(), "johndoe@mail.com")
+	firstName, _ := cipher.Encrypt(context.Background(), "John")
+	lastName, _ := cipher.Encrypt(context.Background(), "Doe")
+	createdAt := time.Now()
+
+	dbMock.ExpectPrepare(`SELECT .+, personalDataEncrypted FROM account WHERE emailIndex = \? OR \(emailIndex IS NULL AND email = \?\) LIMIT 1`).
+		ExpectQuery().
+		WithArgs(blindIndex.Index("johndoe@mail.com"), " JohnDoe@Mail.com").
+		WillReturnRows(sqlmock.NewRows(encryptedAccountColumns).AddRow(14, email, "hashed", firstName, lastName, entity.RoleUser, nil, nil, nil, createdAt, nil, nil, nil, nil, nil, true))
+	// an account written before the encryption is read as it is.
+	dbMock.ExpectPrepare(`SELECT .+, personalDataEncrypted FROM account WHERE id = \?`).
+		ExpectQuery().
+		WithArgs(15).
+		WillReturnRows(sqlmock.NewRows(encryptedAccountColumns).AddRow(15, "janedoe@mail.com", "hashed", "Jane", "Doe", entity.RoleUser, nil, nil, nil, createdAt, nil, nil, nil, nil, nil, false))
+	dbMock.ExpectPrepare(`SELECT .+ FROM account WHERE id = \?`).
+		ExpectQuery().
+		WithArgs(16).
+		WillReturnRows(sqlmock.NewRows(encryptedAccountColumns).AddRow(16, "v1:tampered", "hashed", firstName, lastName, entity.RoleUser, nil, nil, nil, createdAt, nil, nil, nil, nil, nil, true))
+
+	repository := account.NewEncryptedAccountRepository(db, "account", cipher, blindIndex)
+
+	found, err := repository.FindByEmail(context.Background(), " JohnDoe@Mail.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "johndoe@mail.com", found.Email)
+	assert.Equal(t, "John", found.FirstName)
+	assert.Equal(t, "Doe", found.LastName)
+
+	found, err = repository.FindByID(context.Background(), 15)
+	assert.NoError(t, err)
+	assert.Equal(t, "janedoe@mail.com", found.Email)
+	assert.Equal(t, "Jane", found.FirstName)
+
+	_, err = repository.FindByID(context.Background(), 16)
+	assert.Equal(t, exception.ErrInternalServer, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestEncryptedAccountRepository_UpdateEmail(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cipher, blindIndex := newPersonalDataCrypto(t)
+	verifiedAt := time.Now()
+
+	dbMock.ExpectPrepare(`UPDATE account SET email = IF\(personalDataEncrypted, \?, \?\), emailIndex = \?, verifiedAt = \? WHERE id = \? AND \(emailIndex = \? OR \(emailIndex IS NULL AND email = \?\)\)`).
+		ExpectExec().
+		WithArgs(isEncrypted{cipher, "new@mail.com"}, "new@mail.com", blindIndex.Index("new@mail.com"), verifiedAt, 14, blindIndex.Index("old@mail.com"), "old@mail.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = account.NewEncryptedAccountRepository(db, "account", cipher, blindIndex).UpdateEmail(context.Background(), 14, "old@mail.com", "new@mail.com", verifiedAt)
+
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestEncryptedAccountRepository_Purge(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cipher, blindIndex := newPersonalDataCrypto(t)
+	email, _ := cipher.Encrypt(context.Background(), "johndoe@mail.com")
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("DELETE FROM account_recovery_code").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 2))
+	dbMock.ExpectExec("DELETE FROM account_api_key").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec("DELETE FROM account_identity").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectQuery(`SELECT email, personalDataEncrypted FROM account WHERE id = \?`).WithArgs(14).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "personalDataEncrypted"}).AddRow(email, true))
+	dbMock.ExpectExec(`DELETE FROM account_login_lockout WHERE emailIndex = \? OR email = \?`).WithArgs(blindIndex.Index("johndoe@mail.com"), "johndoe@mail.com").WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec("DELETE FROM account WHERE id = \\? AND deletedAt IS NOT NULL").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+
+	err = account.NewEncryptedAccountRepository(db, "account", cipher, blindIndex).Purge(context.Background(), 14)
+
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestEncryptedAccountRepository_EncryptPersonalData(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cipher, blindIndex := newPersonalDataCrypto(t)
+
+	dbMock.ExpectQuery(`SELECT id, email, firstName, lastName FROM account WHERE id > \? AND personalDataEncrypted = FALSE ORDER BY id ASC LIMIT \?`).
+		WithArgs(0, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "firstName", "lastName"}).AddRow(3, "johndoe@mail.com", "John", "Doe").AddRow(5, "janedoe@mail.com", "Jane", "Doe"))
+	dbMock.ExpectExec(`UPDATE account SET email = \?, emailIndex = \?, firstName = \?, lastName = \?, personalDataEncrypted = TRUE WHERE id = \? AND personalDataEncrypted = FALSE AND email = \? AND firstName = \? AND lastName = \?`).
+		WithArgs(isEncrypted{cipher, "johndoe@mail.com"}, blindIndex.Index("johndoe@mail.com"), isEncrypted{cipher, "John"}, isEncrypted{cipher, "Doe"}, 3, "johndoe@mail.com", "John", "Doe").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// changed meanwhile, it is left for the next run.
+	dbMock.ExpectExec(`UPDATE account SET email`).
+		WithArgs(isEncrypted{cipher, "janedoe@mail.com"}, blindIndex.Index("janedoe@mail.com"), isEncrypted{cipher, "Jane"}, isEncrypted{cipher, "Doe"}, 5, "janedoe@mail.com", "Jane", "Doe").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	lastID, scanned, err := account.NewEncryptedAccountRepository(db, "account", cipher, blindIndex).EncryptPersonalData(context.Background(), 0, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), lastID)
+	assert.Equal(t, 2, scanned)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+
+	lastID, scanned, err = account.NewAccountRepository(db, "account").EncryptPersonalData(context.Background(), 0, 2)
+	assert.NoError(t, err)
+	assert.Zero(t, scanned)
+	assert.Zero(t, lastID)
+}
+
+func TestEncryptedAccountRepository_IndexEmails(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cipher, blindIndex := newPersonalDataCrypto(t)
+
+	dbMock.ExpectQuery(`SELECT id, email FROM account WHERE id > \? AND emailIndex IS NULL ORDER BY id ASC LIMIT \?`).
+		WithArgs(0, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(3, "JohnDoe@mail.com").AddRow(5, "johndoe@mail.com"))
+	dbMock.ExpectExec(`UPDATE account SET emailIndex = \? WHERE id = \? AND emailIndex IS NULL AND email = \?`).
+		WithArgs(blindIndex.Index("johndoe@mail.com"), 3, "JohnDoe@mail.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// the same email but for its case, it is left without index.
+	dbMock.ExpectExec(`UPDATE account SET emailIndex`).
+		WithArgs(blindIndex.Index("johndoe@mail.com"), 5, "johndoe@mail.com").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+	lastID, scanned, err := account.NewEncryptedAccountRepository(db, "account", cipher, blindIndex).IndexEmails(context.Background(), 0, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), lastID)
+	assert.Equal(t, 2, scanned)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+// TestEncryptedAccountRepository_MixedState covers accounts still in plaintext once they have their email index.
+func TestEncryptedAccountRepository_MixedState(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cipher, blindIndex := newPersonalDataCrypto(t)
+	password := "hashed"
+	createdAt := time.Now()
+	verifiedAt := time.Now()
+
+	dbMock.ExpectPrepare(`SELECT .+, personalDataEncrypted FROM account WHERE emailIndex = \? OR \(emailIndex IS NULL AND email = \?\) LIMIT 1`).
+		ExpectQuery().
+		WithArgs(blindIndex.Index("janedoe@mail.com"), "JaneDoe@mail.com").
+		WillReturnRows(sqlmock.NewRows(encryptedAccountColumns).AddRow(15, "janedoe@mail.com", "hashed", "Jane", "Doe", entity.RoleUser, nil, nil, nil, createdAt, nil, nil, nil, nil, nil, false))
+	// the email of the account in plaintext is taken by its index, whatever its case.
+	dbMock.ExpectPrepare(`INSERT INTO account \(email, emailIndex, personalDataEncrypted,`).
+		ExpectExec().
+		WithArgs(isEncrypted{cipher, "JaneDoe@mail.com"}, blindIndex.Index("janedoe@mail.com"), password, isEncrypted{cipher, "Jane"}, isEncrypted{cipher, "Doe"}, entity.RoleUser, createdAt).
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	// the account in plaintext keeps its email in plaintext along with the index of the new one.
+	dbMock.ExpectPrepare(`UPDATE account SET email = IF\(personalDataEncrypted, \?, \?\), emailIndex = \?`).
+		ExpectExec().
+		WithArgs(isEncrypted{cipher, "jane@mail.com"}, "jane@mail.com", blindIndex.Index("jane@mail.com"), verifiedAt, 15, blindIndex.Index("janedoe@mail.com"), "janedoe@mail.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repository := account.NewEncryptedAccountRepository(db, "account", cipher, blindIndex)
+
+	found, err := repository.FindByEmail(context.Background(), "JaneDoe@mail.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "janedoe@mail.com", found.Email)
+	assert.Equal(t, "Jane", found.FirstName)
+
+	_, err = repository.Save(context.Background(), account.Account{Email: "JaneDoe@mail.com", Password: &password, FirstName: "Jane", LastName: "Doe", Role: entity.RoleUser, CreatedAt: createdAt})
+	assert.Equal(t, exception.ErrConflicted, err)
+
+	err = repository.UpdateEmail(context.Background(), 15, "janedoe@mail.com", "jane@mail.com", verifiedAt)
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestEncryptedAccountRepository_LoginLockout(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	cipher, blindIndex := newPersonalDataCrypto(t)
+	lockout := account.LoginLockout{Email: "JohnDoe@mail.com", ClientIP: "127.0.0.1", Subject: "email", Failures: 5, LockedUntil: time.Now(), CreatedAt: time.Now()}
+
+	dbMock.ExpectPrepare(`INSERT INTO account_login_lockout \(emailIndex, clientIP, subject, failures, lockedUntil, createdAt\)`).
+		ExpectExec().
+		WithArgs(blindIndex.Index("johndoe@mail.com"), lockout.ClientIP, lockout.Subject, lockout.Failures, lockout.LockedUntil, lockout.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	// the records written before drop their email for its index.
+	dbMock.ExpectQuery(`SELECT id, email FROM account_login_lockout WHERE id > \? AND emailIndex IS NULL ORDER BY id ASC LIMIT \?`).
+		WithArgs(0, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(2, "janedoe@mail.com"))
+	dbMock.ExpectExec(`UPDATE account_login_lockout SET emailIndex = \?, email = NULL WHERE id = \? AND emailIndex IS NULL AND email = \?`).
+		WithArgs(blindIndex.Index("janedoe@mail.com"), 2, "janedoe@mail.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repository := account.NewEncryptedAccountRepository(db, "account", cipher, blindIndex)
+
+	ID, err := repository.SaveLoginLockout(context.Background(), lockout)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), ID)
+
+	lastID, scanned, err := repository.IndexLoginLockouts(context.Background(), 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), lastID)
+	assert.Equal(t, 1, scanned)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,178 @@
+package unittest
+
+import (
+	"context"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+func (d *MockAccountRepository) Save(ctx context.Context, newAccount account.Account) (ID int64, err error) {
+	args := d.Called(ctx, newAccount)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (d *MockAccountRepository) Update(ctx context.Context, ID int64, updatedAccount account.Account) (err error) {
+	args := d.Called(ctx, ID, updatedAccount)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) UpdatePassword(ctx context.Context, ID int64, password string) (err error) {
+	args := d.Called(ctx, ID, password)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) ClearPassword(ctx context.Context, ID int64) (err error) {
+	args := d.Called(ctx, ID)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) UpdateVerifiedAt(ctx context.Context, ID int64, verifiedAt time.Time) (err error) {
+	args := d.Called(ctx, ID, verifiedAt)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) UpdateTOTP(ctx context.Context, ID int64, secret *string, enabledAt *time.Time) (err error) {
+	args := d.Called(ctx, ID, secret, enabledAt)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) SaveRecoveryCodes(ctx context.Context, ID int64, codeHashes []string, createdAt time.Time) (err error) {
+	args := d.Called(ctx, ID, codeHashes, createdAt)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) ConsumeRecoveryCode(ctx context.Context, ID int64, codeHash string) (err error) {
+	args := d.Called(ctx, ID, codeHash)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) SaveLoginLockout(ctx context.Context, lockout account.LoginLockout) (ID int64, err error) {
+	args := d.Called(ctx, lockout)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (d *MockAccountRepository) FindByEmail(ctx context.Context, email string) (foundAccount account.Account, err error) {
+	args := d.Called(ctx, email)
+	return args.Get(0).(account.Account), args.Error(1)
+}
+
+func (d *MockAccountRepository) FindByID(ctx context.Context, ID int64) (foundAccount account.Account, err error) {
+	args := d.Called(ctx, ID)
+	return args.Get(0).(account.Account), args.Error(1)
+}
+
+func (d *MockAccountRepository) MarkDeleted(ctx context.Context, ID int64, deletedAt time.Time, articleDisposition account.ArticleDisposition) (err error) {
+	args := d.Called(ctx, ID, deletedAt, articleDisposition)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) Restore(ctx context.Context, ID int64, deletedAfter time.Time) (err error) {
+	args := d.Called(ctx, ID, deletedAfter)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) Purge(ctx context.Context, ID int64) (err error) {
+	args := d.Called(ctx, ID)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) FindDeletedBefore(ctx context.Context, before time.Time, limit int) (bunchOfAccounts []account.Account, err error) {
+	args := d.Called(ctx, before, limit)
+	return args.Get(0).([]account.Account), args.Error(1)
+}
+
+func (d *MockAccountRepository) EncryptPersonalData(ctx context.Context, afterID int64, limit int) (lastID int64, scanned int, err error) {
+	args := d.Called(ctx, afterID, limit)
+	return args.Get(0).(int64), args.Int(1), args.Error(2)
+}
+
+func (d *MockAccountRepository) IndexEmails(ctx context.Context, afterID int64, limit int) (lastID int64, scanned int, err error) {
+	args := d.Called(ctx, afterID, limit)
+	return args.Get(0).(int64), args.Int(1), args.Error(2)
+}
+
+func (d *MockAccountRepository) IndexLoginLockouts(ctx context.Context, afterID int64, limit int) (lastID int64, scanned int, err error) {
+	args := d.Called(ctx, afterID, limit)
+	return args.Get(0).(int64), args.Int(1), args.Error(2)
+}
+
+func (d *MockAccountRepository) UpdateEmail(ctx context.Context, ID int64, currentEmail string, newEmail string, verifiedAt time.Time) (err error) {
+	args := d.Called(ctx, ID, currentEmail, newEmail, verifiedAt)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) UpdateSuspendedAt(ctx context.Context, ID int64, suspendedAt *time.Time) (err error) {
+	args := d.Called(ctx, ID, suspendedAt)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) UpdatePasswordResetRequiredAt(ctx context.Context, ID int64, requiredAt *time.Time) (err error) {
+	args := d.Called(ctx, ID, requiredAt)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) UpdateRole(ctx context.Context, ID int64, role string) (err error) {
+	args := d.Called(ctx, ID, role)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) SaveAuditLog(ctx context.Context, auditLog account.AuditLog) (ID int64, err error) {
+	args := d.Called(ctx, auditLog)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (d *MockAccountRepository) FindAuditLogsByTargetID(ctx context.Context, targetID int64, limit int) (bunchOfAuditLogs []account.AuditLog, err error) {
+	args := d.Called(ctx, targetID, limit)
+	return args.Get(0).([]account.AuditLog), args.Error(1)
+}
+
+func (d *MockAccountRepository) FindMany(ctx context.Context, filter account.AccountFilter) (bunchOfAccounts []account.Account, err error) {
+	args := d.Called(ctx, filter)
+	return args.Get(0).([]account.Account), args.Error(1)
+}
+
+func (d *MockAccountRepository) SaveAPIKey(ctx context.Context, apiKey account.APIKey) (ID int64, err error) {
+	args := d.Called(ctx, apiKey)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (d *MockAccountRepository) UpdateAPIKeyLastUsedAt(ctx context.Context, ID int64, lastUsedAt time.Time) (err error) {
+	args := d.Called(ctx, ID, lastUsedAt)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) DeleteAPIKey(ctx context.Context, accountID int64, ID int64) (err error) {
+	args := d.Called(ctx, accountID, ID)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) DeleteAPIKeysByAccountID(ctx context.Context, accountID int64) (err error) {
+	args := d.Called(ctx, accountID)
+	return args.Error(0)
+}
+
+func (d *MockAccountRepository) FindAPIKeyByHash(ctx context.Context, keyHash string) (apiKey account.APIKey, err error) {
+	args := d.Called(ctx, keyHash)
+	return args.Get(0).(account.APIKey), args.Error(1)
+}
+
+func (d *MockAccountRepository) FindAPIKeysByAccountID(ctx context.Context, accountID int64) (bunchOfAPIKeys []account.APIKey, err error) {
+	args := d.Called(ctx, accountID)
+	return args.Get(0).([]account.APIKey), args.Error(1)
+}
+
+func (d *MockAccountRepository) SaveIdentity(ctx context.Context, identity account.AccountIdentity) (ID int64, err error) {
+	args := d.Called(ctx, identity)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (d *MockAccountRepository) FindIdentity(ctx context.Context, issuer string, subject string) (identity account.AccountIdentity, err error) {
+	args := d.Called(ctx, issuer, subject)
+	return args.Get(0).(account.AccountIdentity), args.Error(1)
+}
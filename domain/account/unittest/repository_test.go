@@ -0,0 +1,240 @@
+package unittest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+)
+
+func TestAccountRepository_Save(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	password := "hashed"
+	newAccount := account.Account{Email: "johndoe@mail.com", Password: &password, FirstName: "John", LastName: "Doe", Role: entity.RoleUser, CreatedAt: time.Now()}
+
+	dbMock.ExpectPrepare("INSERT INTO account").
+		ExpectExec().
+		WithArgs(newAccount.Email, password, newAccount.FirstName, newAccount.LastName, newAccount.Role, newAccount.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(14, 1))
+
+	ID, err := account.NewAccountRepository(db, "account").Save(context.Background(), newAccount)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(14), ID)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_Save_DuplicateEmail(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	password := "hashed"
+	dbMock.ExpectPrepare("INSERT INTO account").
+		ExpectExec().
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'johndoe@mail.com' for key 'account_email_unique'"})
+
+	_, err = account.NewAccountRepository(db, "account").Save(context.Background(), account.Account{Email: "johndoe@mail.com", Password: &password})
+
+	assert.Equal(t, exception.ErrConflicted, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_Save_UnexpectedError(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	password := "hashed"
+	dbMock.ExpectPrepare("INSERT INTO account").
+		ExpectExec().
+		WillReturnError(fmt.Errorf("connection reset"))
+
+	_, err = account.NewAccountRepository(db, "account").Save(context.Background(), account.Account{Email: "johndoe@mail.com", Password: &password})
+
+	assert.Equal(t, exception.ErrInternalServer, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_Purge(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("DELETE FROM account_recovery_code").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 2))
+	dbMock.ExpectExec("DELETE FROM account_api_key").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec("DELETE FROM account_identity").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec("DELETE FROM account_login_lockout").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("DELETE FROM account WHERE id = \\? AND deletedAt IS NOT NULL").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+
+	err = account.NewAccountRepository(db, "account").Purge(context.Background(), 14)
+
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_Purge_NotDeleted(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("DELETE FROM account_recovery_code").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("DELETE FROM account_api_key").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("DELETE FROM account_identity").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("DELETE FROM account_login_lockout").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("DELETE FROM account WHERE id = \\? AND deletedAt IS NOT NULL").WithArgs(14).WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectRollback()
+
+	err = account.NewAccountRepository(db, "account").Purge(context.Background(), 14)
+
+	assert.Equal(t, exception.ErrNotFound, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_Restore(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	deletedAfter := time.Now().Add(-time.Hour * 24 * 30)
+	dbMock.ExpectPrepare(`UPDATE account SET deletedAt = NULL, articleDisposition = NULL WHERE id = \? AND deletedAt > \?`).
+		ExpectExec().
+		WithArgs(14, deletedAfter).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = account.NewAccountRepository(db, "account").Restore(context.Background(), 14, deletedAfter)
+
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_Restore_PurgeDue(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	deletedAfter := time.Now().Add(-time.Hour * 24 * 30)
+	dbMock.ExpectPrepare("UPDATE account SET deletedAt = NULL").
+		ExpectExec().
+		WithArgs(14, deletedAfter).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = account.NewAccountRepository(db, "account").Restore(context.Background(), 14, deletedAfter)
+
+	assert.Equal(t, exception.ErrNotFound, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_Update(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	password := "hashed"
+	lastModifiedAt := time.Now()
+	dbMock.ExpectPrepare(`UPDATE account SET firstName = \?, lastName = \?, lastModified = \? WHERE id = \?`).
+		ExpectExec().
+		WithArgs("Johnny", "Doe", &lastModifiedAt, 14).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = account.NewAccountRepository(db, "account").Update(context.Background(), 14, account.Account{Password: &password, FirstName: "Johnny", LastName: "Doe", LastModifiedAt: &lastModifiedAt})
+
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_UpdateEmail_Taken(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	verifiedAt := time.Now()
+	dbMock.ExpectPrepare("UPDATE account SET email").
+		ExpectExec().
+		WithArgs("john@doe.com", verifiedAt, 14, "johndoe@mail.com").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'john@doe.com' for key 'account_email_unique'"})
+
+	err = account.NewAccountRepository(db, "account").UpdateEmail(context.Background(), 14, "johndoe@mail.com", "john@doe.com", verifiedAt)
+
+	assert.Equal(t, exception.ErrConflicted, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_UpdateEmail_Changed(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	verifiedAt := time.Now()
+	dbMock.ExpectPrepare("UPDATE account SET email").
+		ExpectExec().
+		WithArgs("john@doe.com", verifiedAt, 14, "johndoe@mail.com").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = account.NewAccountRepository(db, "account").UpdateEmail(context.Background(), 14, "johndoe@mail.com", "john@doe.com", verifiedAt)
+
+	assert.Equal(t, exception.ErrNotFound, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_UpdateTOTP(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// with clientFoundRows an account left as it was still counts, only a missing one counts none.
+	dbMock.ExpectPrepare(`UPDATE account SET totpSecret = \?, totpEnabledAt = \? WHERE id = \?`).
+		ExpectExec().
+		WithArgs(nil, nil, 14).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectPrepare(`UPDATE account SET totpSecret = \?, totpEnabledAt = \? WHERE id = \?`).
+		ExpectExec().
+		WithArgs(nil, nil, 15).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repository := account.NewAccountRepository(db, "account")
+
+	err = repository.UpdateTOTP(context.Background(), 14, nil, nil)
+	assert.NoError(t, err)
+
+	err = repository.UpdateTOTP(context.Background(), 15, nil, nil)
+	assert.Equal(t, exception.ErrNotFound, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_FindMany(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	columns := []string{"id", "email", "password", "firstName", "lastName", "role", "verifiedAt", "totpSecret", "totpEnabledAt", "createdAt", "lastModified", "deletedAt", "articleDisposition", "suspendedAt", "passwordResetRequiredAt"}
+	createdAt := time.Now()
+	suspendedAt := time.Now()
+
+	dbMock.ExpectPrepare(`SELECT .+ FROM account WHERE id > \? AND \(email LIKE \? OR firstName LIKE \? OR lastName LIKE \?\) AND suspendedAt IS NOT NULL ORDER BY id ASC LIMIT \?`).
+		ExpectQuery().
+		WithArgs(10, `%50\%%`, `%50\%%`, `%50\%%`, 3).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(11, "john50%@mail.com", "hashed", "John", "Doe", entity.RoleUser, nil, nil, nil, createdAt, nil, nil, nil, suspendedAt, nil))
+
+	bunchOfAccounts, err := account.NewAccountRepository(db, "account").FindMany(context.Background(), account.AccountFilter{Query: "50%", Status: account.AccountStatusSuspended, Cursor: 10, Limit: 3})
+
+	assert.NoError(t, err)
+	assert.Len(t, bunchOfAccounts, 1)
+	assert.Equal(t, int64(11), bunchOfAccounts[0].ID)
+	assert.Equal(t, suspendedAt, *bunchOfAccounts[0].SuspendedAt)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,16 @@
+package unittest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (d *MockRoleRepository) RoleExists(ctx context.Context, role string) (exists bool, err error) {
+	args := d.Called(ctx, role)
+	return args.Bool(0), args.Error(1)
+}
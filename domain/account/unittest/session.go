@@ -0,0 +1,51 @@
+package unittest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSession struct {
+	mock.Mock
+}
+
+func (d *MockSession) Set(ctx context.Context, key string, value []byte) (err error) {
+	args := d.Called(ctx, key, value)
+	return args.Error(0)
+}
+
+func (d *MockSession) Get(ctx context.Context, key string) (value []byte, err error) {
+	args := d.Called(ctx, key)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (d *MockSession) Update(ctx context.Context, key string, value []byte) (err error) {
+	args := d.Called(ctx, key, value)
+	return args.Error(0)
+}
+
+func (d *MockSession) Delete(ctx context.Context, key string) (err error) {
+	args := d.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (d *MockSession) CompareAndSwap(ctx context.Context, key string, old []byte, new []byte) (swapped bool, err error) {
+	args := d.Called(ctx, key, old, new)
+	return args.Bool(0), args.Error(1)
+}
+
+func (d *MockSession) Consume(ctx context.Context, key string) (value []byte, err error) {
+	args := d.Called(ctx, key)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (d *MockSession) SetIfGreater(ctx context.Context, key string, value int64) (set bool, err error) {
+	args := d.Called(ctx, key, value)
+	return args.Bool(0), args.Error(1)
+}
+
+func (d *MockSession) Increment(ctx context.Context, key string) (count int64, err error) {
+	args := d.Called(ctx, key)
+	return int64(args.Int(0)), args.Error(1)
+}
@@ -0,0 +1,27 @@
+package unittest
+
+import (
+	"context"
+
+	"github.com/sangianpatrick/devoria-article-service/throttle"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockThrottle struct {
+	mock.Mock
+}
+
+func (d *MockThrottle) Attempt(ctx context.Context, subject string) (result throttle.Result, err error) {
+	args := d.Called(ctx, subject)
+	return args.Get(0).(throttle.Result), args.Error(1)
+}
+
+func (d *MockThrottle) Succeed(ctx context.Context, subject string, attempt throttle.Result) (err error) {
+	args := d.Called(ctx, subject, attempt)
+	return args.Error(0)
+}
+
+func (d *MockThrottle) Reset(ctx context.Context, subject string) (err error) {
+	args := d.Called(ctx, subject)
+	return args.Error(0)
+}
@@ -0,0 +1,1612 @@
+package unittest
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/sangianpatrick/devoria-article-service/crypto"
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/hasher"
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+	mailermodel "github.com/sangianpatrick/devoria-article-service/mailer"
+	"github.com/sangianpatrick/devoria-article-service/response"
+	"github.com/sangianpatrick/devoria-article-service/session"
+	"github.com/sangianpatrick/devoria-article-service/throttle"
+	"github.com/sangianpatrick/devoria-article-service/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func refreshTokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf(account.AccountRefreshTokenKeyFormat, hex.EncodeToString(sum[:]))
+}
+
+// decodeResponseData renders the response like a handler would and decodes its data.
+func decodeResponseData(t *testing.T, resp response.Response, data interface{}) {
+	recorder := httptest.NewRecorder()
+	resp.JSON(recorder)
+
+	body := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&body))
+}
+
+// newAccountUsecaseDependencies returns the dependencies of an account usecase on fresh mocks,
+// a test replaces those it sets expectations on.
+func newAccountUsecaseDependencies(accountRepository account.AccountRepository) account.AccountUsecaseDependencies {
+	location, _ := time.LoadLocation("Asia/Jakarta")
+
+	return account.AccountUsecaseDependencies{
+		FrontendURL:              "http://localhost",
+		TOTPIssuer:               "Devoria",
+		DeviceSessionRepository:  new(MockDeviceSessionRepository),
+		RefreshTokenSession:      new(MockSession),
+		PasswordResetSession:     new(MockSession),
+		EmailVerificationSession: new(MockSession),
+		EmailResendSession:       new(MockSession),
+		MFAChallengeSession:      new(MockSession),
+		EmailChangeUndoSession:   new(MockSession),
+		OIDCStateSession:         new(MockSession),
+		JSONWebToken:             new(MockJSONWebToken),
+		EmailThrottle:            new(MockThrottle),
+		ClientIPThrottle:         new(MockThrottle),
+		AccessTokenTTL:           time.Minute,
+		PasswordHasher:           hasher.NewBcryptHasher(4),
+		Mailer:                   new(MockMailer),
+		Location:                 location,
+		RoleRepository:           new(MockRoleRepository),
+		Repository:               accountRepository,
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	refreshTokenSess := new(MockSession)
+	jsonWebToken := new(MockJSONWebToken)
+	accountRepository := new(MockAccountRepository)
+
+	storedToken, _ := json.Marshal(account.RefreshToken{AccountID: 14, FamilyID: "family"})
+	usedToken, _ := json.Marshal(account.RefreshToken{AccountID: 14, FamilyID: "family", Used: true})
+	familyKey := fmt.Sprintf(account.AccountRefreshTokenFamilyKeyFormat, "family")
+
+	refreshTokenSess.On("Get", ctx, refreshTokenKey("old-token")).Return(storedToken, nil)
+	refreshTokenSess.On("Get", ctx, familyKey).Return([]byte("14"), nil)
+	refreshTokenSess.On("Get", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14")).Return([]byte(nil), session.ErrSessionNotFound)
+	refreshTokenSess.On("CompareAndSwap", ctx, refreshTokenKey("old-token"), storedToken, usedToken).Return(true, nil)
+	refreshTokenSess.On("Set", ctx, familyKey, []byte("14")).Return(nil)
+	refreshTokenSess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Return(nil)
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+	jsonWebToken.On("Sign", ctx, mock.MatchedBy(func(claims entity.AccountStandardJWTClaims) bool {
+		return claims.SessionID == "family"
+	})).Return("access-token", nil)
+	deviceSessionRepository.On("FindByID", ctx, "family").Return(account.DeviceSession{ID: "family", AccountID: 14}, nil)
+	deviceSessionRepository.On("Save", ctx, mock.MatchedBy(func(deviceSession account.DeviceSession) bool {
+		return deviceSession.ID == "family" && !deviceSession.LastSeenAt.IsZero()
+	})).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = refreshTokenSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(refreshTokenSess)
+	deps.JSONWebToken = jsonWebToken
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.RefreshToken(ctx, account.AccountRefreshTokenRequest{RefreshToken: "old-token"})
+
+	assert.NoError(t, resp.Err())
+	refreshTokenSess.AssertExpectations(t)
+	deviceSessionRepository.AssertExpectations(t)
+}
+
+func TestRefreshToken_ReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	refreshTokenSess := new(MockSession)
+	jsonWebToken := new(MockJSONWebToken)
+	accountRepository := new(MockAccountRepository)
+
+	usedToken, _ := json.Marshal(account.RefreshToken{AccountID: 14, FamilyID: "family", Used: true})
+	familyKey := fmt.Sprintf(account.AccountRefreshTokenFamilyKeyFormat, "family")
+
+	refreshTokenSess.On("Get", ctx, refreshTokenKey("replayed-token")).Return(usedToken, nil)
+	refreshTokenSess.On("Delete", ctx, familyKey).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.RefreshTokenSession = refreshTokenSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(refreshTokenSess)
+	deps.JSONWebToken = jsonWebToken
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.RefreshToken(ctx, account.AccountRefreshTokenRequest{RefreshToken: "replayed-token"})
+
+	assert.Equal(t, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized), resp)
+	refreshTokenSess.AssertExpectations(t)
+	jsonWebToken.AssertNotCalled(t, "Sign", mock.Anything, mock.Anything)
+}
+
+func TestRefreshToken_ConcurrentRotation(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	refreshTokenSess := new(MockSession)
+	jsonWebToken := new(MockJSONWebToken)
+	accountRepository := new(MockAccountRepository)
+
+	storedToken, _ := json.Marshal(account.RefreshToken{AccountID: 14, FamilyID: "family"})
+	usedToken, _ := json.Marshal(account.RefreshToken{AccountID: 14, FamilyID: "family", Used: true})
+	familyKey := fmt.Sprintf(account.AccountRefreshTokenFamilyKeyFormat, "family")
+
+	// another request rotated the same token between the read and the swap.
+	refreshTokenSess.On("Get", ctx, refreshTokenKey("raced-token")).Return(storedToken, nil)
+	refreshTokenSess.On("Get", ctx, familyKey).Return([]byte("14"), nil)
+	refreshTokenSess.On("Get", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14")).Return([]byte(nil), session.ErrSessionNotFound)
+	refreshTokenSess.On("CompareAndSwap", ctx, refreshTokenKey("raced-token"), storedToken, usedToken).Return(false, nil)
+	refreshTokenSess.On("Delete", ctx, familyKey).Return(nil)
+	deviceSessionRepository.On("FindByID", ctx, "family").Return(account.DeviceSession{ID: "family", AccountID: 14}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = refreshTokenSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(refreshTokenSess)
+	deps.JSONWebToken = jsonWebToken
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.RefreshToken(ctx, account.AccountRefreshTokenRequest{RefreshToken: "raced-token"})
+
+	assert.Equal(t, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized), resp)
+	refreshTokenSess.AssertExpectations(t)
+	jsonWebToken.AssertNotCalled(t, "Sign", mock.Anything, mock.Anything)
+}
+
+func TestRefreshToken_RevokedFamily(t *testing.T) {
+	ctx := context.Background()
+	refreshTokenSess := new(MockSession)
+	jsonWebToken := new(MockJSONWebToken)
+	accountRepository := new(MockAccountRepository)
+
+	storedToken, _ := json.Marshal(account.RefreshToken{AccountID: 14, FamilyID: "family"})
+	familyKey := fmt.Sprintf(account.AccountRefreshTokenFamilyKeyFormat, "family")
+
+	refreshTokenSess.On("Get", ctx, refreshTokenKey("sibling-token")).Return(storedToken, nil)
+	refreshTokenSess.On("Get", ctx, familyKey).Return([]byte(nil), session.ErrSessionNotFound)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.RefreshTokenSession = refreshTokenSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(refreshTokenSess)
+	deps.JSONWebToken = jsonWebToken
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.RefreshToken(ctx, account.AccountRefreshTokenRequest{RefreshToken: "sibling-token"})
+
+	assert.Equal(t, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized), resp)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	accountRepository := new(MockAccountRepository)
+
+	password := "hashed"
+	firstName := "Johnny"
+	claims := entity.AccountStandardJWTClaims{Email: "johndoe@mail.com"}
+	claims.Subject = "14"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &password, FirstName: "John", LastName: "Doe"}, nil)
+	accountRepository.On("Update", ctx, int64(14), mock.MatchedBy(func(updatedAccount account.Account) bool {
+		return updatedAccount.FirstName == "Johnny" && updatedAccount.LastName == "Doe" && updatedAccount.LastModifiedAt != nil
+	})).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.UpdateProfile(ctx, claims, account.AccountUpdateRequest{FirstName: &firstName})
+
+	assert.NoError(t, resp.Err())
+	accountRepository.AssertExpectations(t)
+}
+
+func TestUpdateProfile_NothingChanged(t *testing.T) {
+	ctx := context.Background()
+	accountRepository := new(MockAccountRepository)
+
+	firstName := "John"
+	claims := entity.AccountStandardJWTClaims{Email: "johndoe@mail.com"}
+	claims.Subject = "14"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", FirstName: "John", LastName: "Doe"}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.UpdateProfile(ctx, claims, account.AccountUpdateRequest{FirstName: &firstName})
+
+	assert.NoError(t, resp.Err())
+	accountRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	revocationSess := new(MockSession)
+	passwordResetSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	sum := sha256.Sum256([]byte("reset-token"))
+	resetKey := fmt.Sprintf(account.AccountPasswordResetKeyFormat, hex.EncodeToString(sum[:]))
+
+	passwordResetSess.On("Consume", ctx, resetKey).Return([]byte("14"), nil)
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+	accountRepository.On("UpdatePassword", ctx, int64(14), mock.AnythingOfType("string")).Return(nil)
+	revocationSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+	accountRepository.On("DeleteAPIKeysByAccountID", ctx, int64(14)).Return(nil)
+
+	emailThrottle := new(MockThrottle)
+	emailThrottle.On("Reset", ctx, "johndoe@mail.com").Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = revocationSess
+	deps.PasswordResetSession = passwordResetSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(revocationSess)
+	deps.EmailThrottle = emailThrottle
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.ResetPassword(ctx, account.AccountResetPasswordRequest{Token: "reset-token", NewPassword: "new-password"})
+
+	assert.NoError(t, resp.Err())
+	passwordResetSess.AssertExpectations(t)
+	accountRepository.AssertExpectations(t)
+	revocationSess.AssertExpectations(t)
+	deviceSessionRepository.AssertExpectations(t)
+}
+
+func TestResetPassword_UsedToken(t *testing.T) {
+	ctx := context.Background()
+	passwordResetSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	passwordResetSess.On("Consume", ctx, mock.AnythingOfType("string")).Return([]byte(nil), session.ErrSessionNotFound)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.PasswordResetSession = passwordResetSess
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.ResetPassword(ctx, account.AccountResetPasswordRequest{Token: "used-token", NewPassword: "new-password"})
+
+	assert.Equal(t, response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest), resp)
+	accountRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	emailVerificationSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	sum := sha256.Sum256([]byte("verification-token"))
+	verificationKey := fmt.Sprintf(account.AccountEmailVerificationKeyFormat, hex.EncodeToString(sum[:]))
+
+	emailVerificationSess.On("Consume", ctx, verificationKey).Return([]byte("14"), nil)
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+	accountRepository.On("UpdateVerifiedAt", ctx, int64(14), mock.AnythingOfType("time.Time")).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailVerificationSession = emailVerificationSess
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.VerifyEmail(ctx, account.AccountVerifyEmailRequest{Token: "verification-token"})
+
+	assert.NoError(t, resp.Err())
+	emailVerificationSess.AssertExpectations(t)
+	accountRepository.AssertExpectations(t)
+}
+
+func TestResendEmailVerification_Throttled(t *testing.T) {
+	ctx := context.Background()
+	emailResendSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	sum := sha256.Sum256([]byte("johndoe@mail.com"))
+	sentKey := fmt.Sprintf(account.AccountEmailVerificationSentKeyFormat, hex.EncodeToString(sum[:]))
+
+	emailResendSess.On("Increment", ctx, sentKey).Return(2, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailResendSession = emailResendSess
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.ResendEmailVerification(ctx, account.AccountResendEmailVerificationRequest{Email: " JohnDoe@Mail.com "})
+
+	assert.Equal(t, response.Error(response.StatusTooManyRequests, nil, account.ErrTooManyRequests), resp)
+	emailResendSess.AssertExpectations(t)
+	accountRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+}
+
+func TestLogin_UnverifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	accountRepository := new(MockAccountRepository)
+	policy := account.AccountPolicy{RequireVerifiedEmailToLogin: true}
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword}, nil)
+
+	emailThrottle := new(MockThrottle)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+	emailThrottle.On("Succeed", ctx, "johndoe@mail.com", throttle.Result{Failures: 1}).Return(nil)
+	emailThrottle.On("Reset", ctx, "johndoe@mail.com").Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.Policy = policy
+	deps.PasswordHasher = passwordHasher
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.Login(ctx, account.AccountAuthenticationRequest{Email: "johndoe@mail.com", Password: "password"})
+
+	assert.Equal(t, response.Error(response.StatusForbiddend, nil, account.ErrEmailNotVerified), resp)
+}
+
+func TestLogin_TOTPChallenge(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	mfaChallengeSess := new(MockSession)
+	jsonWebToken := new(MockJSONWebToken)
+	accountRepository := new(MockAccountRepository)
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	totpEnabledAt := time.Now()
+	accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword, TOTPEnabledAt: &totpEnabledAt}, nil)
+	mfaChallengeSess.On("Set", ctx, mock.AnythingOfType("string"), []byte(`{"accountId":14}`)).Return(nil)
+
+	emailThrottle := new(MockThrottle)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+	emailThrottle.On("Succeed", ctx, "johndoe@mail.com", throttle.Result{Failures: 1}).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.MFAChallengeSession = mfaChallengeSess
+	deps.JSONWebToken = jsonWebToken
+	deps.EmailThrottle = emailThrottle
+	deps.PasswordHasher = passwordHasher
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.Login(ctx, account.AccountAuthenticationRequest{Email: "johndoe@mail.com", Password: "password"})
+
+	assert.NoError(t, resp.Err())
+	var challenge account.AccountMFAChallengeResponse
+	decodeResponseData(t, resp, &challenge)
+	assert.True(t, challenge.MFARequired)
+	assert.NotEmpty(t, challenge.ChallengeToken)
+	jsonWebToken.AssertNotCalled(t, "Sign", mock.Anything, mock.Anything)
+	// the password alone does not forget the failures of the email.
+	emailThrottle.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+}
+
+func TestLoginMFA(t *testing.T) {
+	ctx := context.Background()
+	encryption := crypto.NewAES256CBC("12345678901234567890123456789012")
+	globalIV := "1234567890123456"
+	// the secret was enrolled before authenticated encryption, it is still read by the legacy fallback.
+	cipher, _ := crypto.NewAEADCipher(crypto.VersionAES256GCM, "abcdefghijklmnopqrstuvwxyz123456", "12345678901234567890123456789012", globalIV)
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	refreshTokenSess := new(MockSession)
+	mfaChallengeSess := new(MockSession)
+	jsonWebToken := new(MockJSONWebToken)
+	accountRepository := new(MockAccountRepository)
+
+	secret, _ := totp.GenerateSecret()
+	encryptedSecret := encryption.Encrypt(secret, globalIV)
+	totpEnabledAt := time.Now()
+	code, _ := totp.Code(secret, time.Now())
+
+	sum := sha256.Sum256([]byte("challenge-token"))
+	challengeKey := fmt.Sprintf(account.AccountMFAChallengeKeyFormat, hex.EncodeToString(sum[:]))
+	usedStepKey := fmt.Sprintf(account.AccountTOTPUsedStepKeyFormat, 14)
+
+	attemptsKey := fmt.Sprintf(account.AccountMFAChallengeAttemptsKeyFormat, hex.EncodeToString(sum[:]))
+
+	mfaChallengeSess.On("Get", ctx, challengeKey).Return([]byte(`{"accountId":14}`), nil)
+	mfaChallengeSess.On("Increment", ctx, attemptsKey).Return(1, nil)
+	mfaChallengeSess.On("SetIfGreater", ctx, usedStepKey, mock.AnythingOfType("int64")).Return(true, nil)
+	mfaChallengeSess.On("Consume", ctx, challengeKey).Return([]byte(`{"accountId":14}`), nil)
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", TOTPSecret: &encryptedSecret, TOTPEnabledAt: &totpEnabledAt}, nil)
+	jsonWebToken.On("Sign", ctx, mock.Anything).Return("access-token", nil)
+	refreshTokenSess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("Save", ctx, mock.AnythingOfType("account.DeviceSession")).Return(nil)
+
+	// the failures of the email are forgotten once the second factor is proven.
+	emailThrottle := new(MockThrottle)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 3}, nil)
+	emailThrottle.On("Reset", ctx, "johndoe@mail.com").Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.GlobalIV = globalIV
+	deps.EmailThrottle = emailThrottle
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = refreshTokenSess
+	deps.MFAChallengeSession = mfaChallengeSess
+	deps.JSONWebToken = jsonWebToken
+	deps.Crypto = encryption
+	deps.Cipher = cipher
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.LoginMFA(ctx, account.AccountMFALoginRequest{ChallengeToken: "challenge-token", Code: code})
+
+	assert.NoError(t, resp.Err())
+	var authentication account.AccountAuthenticationResponse
+	decodeResponseData(t, resp, &authentication)
+	assert.Equal(t, "access-token", authentication.Token)
+	mfaChallengeSess.AssertExpectations(t)
+	emailThrottle.AssertExpectations(t)
+}
+
+func TestLoginMFA_ConcurrentSameCode(t *testing.T) {
+	ctx := context.Background()
+	cipher, _ := crypto.NewAEADCipher(crypto.VersionAES256GCM, "abcdefghijklmnopqrstuvwxyz123456", "", "")
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	refreshTokenSess := new(MockSession)
+	mfaChallengeSess := new(MockSession)
+	jsonWebToken := new(MockJSONWebToken)
+	emailThrottle := new(MockThrottle)
+	accountRepository := new(MockAccountRepository)
+
+	secret, _ := totp.GenerateSecret()
+	encryptedSecret, _ := cipher.Encrypt(ctx, secret)
+	totpEnabledAt := time.Now()
+	code, _ := totp.Code(secret, time.Now())
+	usedStepKey := fmt.Sprintf(account.AccountTOTPUsedStepKeyFormat, 14)
+
+	// two challenges of the same account race with the same code, the step is only taken once.
+	mfaChallengeSess.On("Get", ctx, mock.AnythingOfType("string")).Return([]byte(`{"accountId":14}`), nil)
+	mfaChallengeSess.On("Increment", ctx, mock.AnythingOfType("string")).Return(1, nil)
+	mfaChallengeSess.On("SetIfGreater", ctx, usedStepKey, mock.AnythingOfType("int64")).Return(true, nil).Once()
+	mfaChallengeSess.On("SetIfGreater", ctx, usedStepKey, mock.AnythingOfType("int64")).Return(false, nil).Once()
+	mfaChallengeSess.On("Consume", ctx, mock.AnythingOfType("string")).Return([]byte(`{"accountId":14}`), nil)
+	// the losing challenge is given back for another code.
+	mfaChallengeSess.On("Set", ctx, mock.AnythingOfType("string"), []byte(`{"accountId":14}`)).Return(nil).Once()
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", TOTPSecret: &encryptedSecret, TOTPEnabledAt: &totpEnabledAt}, nil)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+	emailThrottle.On("Reset", ctx, "johndoe@mail.com").Return(nil)
+	jsonWebToken.On("Sign", ctx, mock.Anything).Return("access-token", nil)
+	refreshTokenSess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("Save", ctx, mock.AnythingOfType("account.DeviceSession")).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = refreshTokenSess
+	deps.MFAChallengeSession = mfaChallengeSess
+	deps.JSONWebToken = jsonWebToken
+	deps.Cipher = cipher
+	accountUsecase := account.NewAccountUsecase(deps)
+
+	var wg sync.WaitGroup
+	responses := make([]response.Response, 2)
+	for i, challengeToken := range []string{"challenge-token-a", "challenge-token-b"} {
+		wg.Add(1)
+		go func(i int, challengeToken string) {
+			defer wg.Done()
+			responses[i] = accountUsecase.LoginMFA(ctx, account.AccountMFALoginRequest{ChallengeToken: challengeToken, Code: code})
+		}(i, challengeToken)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, resp := range responses {
+		if resp.Err() == nil {
+			succeeded++
+			continue
+		}
+		assert.Equal(t, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized), resp)
+	}
+	assert.Equal(t, 1, succeeded)
+	jsonWebToken.AssertNumberOfCalls(t, "Sign", 1)
+	mfaChallengeSess.AssertExpectations(t)
+}
+
+func TestLoginMFA_WrongRecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	mfaChallengeSess := new(MockSession)
+	emailThrottle := new(MockThrottle)
+	accountRepository := new(MockAccountRepository)
+
+	sum := sha256.Sum256([]byte("challenge-token"))
+	challengeKey := fmt.Sprintf(account.AccountMFAChallengeKeyFormat, hex.EncodeToString(sum[:]))
+	attemptsKey := fmt.Sprintf(account.AccountMFAChallengeAttemptsKeyFormat, hex.EncodeToString(sum[:]))
+	codeSum := sha256.Sum256([]byte("abcde12345"))
+
+	// the last attempt of the challenge, it also locks the email out.
+	mfaChallengeSess.On("Get", ctx, challengeKey).Return([]byte(`{"accountId":14}`), nil)
+	mfaChallengeSess.On("Increment", ctx, attemptsKey).Return(5, nil)
+	mfaChallengeSess.On("Consume", ctx, challengeKey).Return([]byte(`{"accountId":14}`), nil)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 10, RetryAfter: time.Minute * 15, Locked: true}, nil)
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+	accountRepository.On("ConsumeRecoveryCode", ctx, int64(14), hex.EncodeToString(codeSum[:])).Return(exception.ErrNotFound)
+	accountRepository.On("SaveLoginLockout", ctx, mock.MatchedBy(func(lockout account.LoginLockout) bool {
+		return lockout.Subject == account.LoginSubjectEmail && lockout.Email == "johndoe@mail.com" && lockout.Failures == 10
+	})).Return(1, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.MFAChallengeSession = mfaChallengeSess
+	deps.EmailThrottle = emailThrottle
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.LoginMFA(ctx, account.AccountMFALoginRequest{ChallengeToken: "challenge-token", RecoveryCode: "ABCDE-12345"})
+
+	assert.Equal(t, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized), resp)
+	mfaChallengeSess.AssertExpectations(t)
+	accountRepository.AssertExpectations(t)
+	emailThrottle.AssertNotCalled(t, "Reset", mock.Anything, mock.Anything)
+	mfaChallengeSess.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoginMFA_LostChallenge(t *testing.T) {
+	ctx := context.Background()
+	mfaChallengeSess := new(MockSession)
+	emailThrottle := new(MockThrottle)
+	accountRepository := new(MockAccountRepository)
+
+	// a concurrent request took the challenge first, the recovery code is left unused.
+	mfaChallengeSess.On("Get", ctx, mock.AnythingOfType("string")).Return([]byte(`{"accountId":14}`), nil)
+	mfaChallengeSess.On("Increment", ctx, mock.AnythingOfType("string")).Return(2, nil)
+	mfaChallengeSess.On("Consume", ctx, mock.AnythingOfType("string")).Return([]byte(nil), session.ErrSessionNotFound)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.MFAChallengeSession = mfaChallengeSess
+	deps.EmailThrottle = emailThrottle
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.LoginMFA(ctx, account.AccountMFALoginRequest{ChallengeToken: "challenge-token", RecoveryCode: "ABCDE-12345"})
+
+	assert.Equal(t, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized), resp)
+	accountRepository.AssertNotCalled(t, "ConsumeRecoveryCode", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoginMFA_AccountChangedMeanwhile(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	for name, testCase := range map[string]struct {
+		account account.Account
+		err     error
+	}{
+		"deleted":                 {account.Account{ID: 14, Email: "johndoe@mail.com", DeletedAt: &now}, account.ErrAccountDeleted},
+		"suspended":               {account.Account{ID: 14, Email: "johndoe@mail.com", SuspendedAt: &now}, account.ErrAccountSuspended},
+		"password reset required": {account.Account{ID: 14, Email: "johndoe@mail.com", PasswordResetRequiredAt: &now}, account.ErrPasswordResetRequired},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mfaChallengeSess := new(MockSession)
+			emailThrottle := new(MockThrottle)
+			accountRepository := new(MockAccountRepository)
+
+			mfaChallengeSess.On("Get", ctx, mock.AnythingOfType("string")).Return([]byte(`{"accountId":14}`), nil)
+			mfaChallengeSess.On("Increment", ctx, mock.AnythingOfType("string")).Return(1, nil)
+			emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+			accountRepository.On("FindByID", ctx, int64(14)).Return(testCase.account, nil)
+
+			deps := newAccountUsecaseDependencies(accountRepository)
+			deps.MFAChallengeSession = mfaChallengeSess
+			deps.EmailThrottle = emailThrottle
+			accountUsecase := account.NewAccountUsecase(deps)
+			resp := accountUsecase.LoginMFA(ctx, account.AccountMFALoginRequest{ChallengeToken: "challenge-token", RecoveryCode: "ABCDE-12345"})
+
+			assert.Equal(t, response.Error(response.StatusForbiddend, nil, testCase.err), resp)
+			accountRepository.AssertNotCalled(t, "ConsumeRecoveryCode", mock.Anything, mock.Anything, mock.Anything)
+			mfaChallengeSess.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestLoginMFA_TooManyAttempts(t *testing.T) {
+	ctx := context.Background()
+	mfaChallengeSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	sum := sha256.Sum256([]byte("challenge-token"))
+	challengeKey := fmt.Sprintf(account.AccountMFAChallengeKeyFormat, hex.EncodeToString(sum[:]))
+	attemptsKey := fmt.Sprintf(account.AccountMFAChallengeAttemptsKeyFormat, hex.EncodeToString(sum[:]))
+
+	// a concurrent guess already took the last attempt, the challenge is not dropped yet.
+	mfaChallengeSess.On("Get", ctx, challengeKey).Return([]byte(`{"accountId":14}`), nil)
+	mfaChallengeSess.On("Increment", ctx, attemptsKey).Return(6, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.MFAChallengeSession = mfaChallengeSess
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.LoginMFA(ctx, account.AccountMFALoginRequest{ChallengeToken: "challenge-token", Code: "123456"})
+
+	assert.Equal(t, response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized), resp)
+	accountRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+}
+
+func TestLoginMFA_ThrottledEmail(t *testing.T) {
+	ctx := context.Background()
+	mfaChallengeSess := new(MockSession)
+	emailThrottle := new(MockThrottle)
+	accountRepository := new(MockAccountRepository)
+
+	mfaChallengeSess.On("Get", ctx, mock.AnythingOfType("string")).Return([]byte(`{"accountId":14}`), nil)
+	mfaChallengeSess.On("Increment", ctx, mock.AnythingOfType("string")).Return(1, nil)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 10, RetryAfter: time.Minute, Refused: true}, nil)
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "JohnDoe@mail.com"}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.MFAChallengeSession = mfaChallengeSess
+	deps.EmailThrottle = emailThrottle
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.LoginMFA(ctx, account.AccountMFALoginRequest{ChallengeToken: "challenge-token", Code: "123456"})
+
+	assert.Equal(t, response.ErrorWithRetryAfter(response.StatusTooManyRequests, nil, account.ErrTooManyRequests, time.Minute), resp)
+	accountRepository.AssertNotCalled(t, "ConsumeRecoveryCode", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLogin_Throttled(t *testing.T) {
+	ctx := context.Background()
+	emailThrottle := new(MockThrottle)
+	clientIPThrottle := new(MockThrottle)
+	accountRepository := new(MockAccountRepository)
+
+	// the email is free, the client address is still blocked.
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 2}, nil)
+	emailThrottle.On("Succeed", ctx, "johndoe@mail.com", throttle.Result{Failures: 2}).Return(nil)
+	clientIPThrottle.On("Attempt", ctx, "10.0.0.1").Return(throttle.Result{Failures: 6, RetryAfter: time.Second * 8, Refused: true}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.ClientIPThrottle = clientIPThrottle
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.Login(ctx, account.AccountAuthenticationRequest{Email: "JohnDoe@mail.com", Password: "password", Device: account.Device{ClientIP: "10.0.0.1"}})
+
+	assert.Equal(t, response.ErrorWithRetryAfter(response.StatusTooManyRequests, nil, account.ErrTooManyRequests, time.Second*8), resp)
+	recorder := httptest.NewRecorder()
+	resp.JSON(recorder)
+	assert.Equal(t, "8", recorder.Header().Get("Retry-After"))
+	accountRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	emailThrottle.AssertExpectations(t)
+}
+
+func TestLogin_ThrottledEmail(t *testing.T) {
+	ctx := context.Background()
+	emailThrottle := new(MockThrottle)
+	clientIPThrottle := new(MockThrottle)
+	accountRepository := new(MockAccountRepository)
+
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 6, RetryAfter: time.Second * 2, Refused: true}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.ClientIPThrottle = clientIPThrottle
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.Login(ctx, account.AccountAuthenticationRequest{Email: "johndoe@mail.com", Password: "password", Device: account.Device{ClientIP: "10.0.0.1"}})
+
+	assert.Equal(t, response.ErrorWithRetryAfter(response.StatusTooManyRequests, nil, account.ErrTooManyRequests, time.Second*2), resp)
+	clientIPThrottle.AssertNotCalled(t, "Attempt", mock.Anything, mock.Anything)
+	accountRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+}
+
+func TestLogin_LockoutAudit(t *testing.T) {
+	ctx := context.Background()
+	emailThrottle := new(MockThrottle)
+	clientIPThrottle := new(MockThrottle)
+	accountRepository := new(MockAccountRepository)
+
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 10, RetryAfter: time.Minute * 15, Locked: true}, nil)
+	clientIPThrottle.On("Attempt", ctx, "10.0.0.1").Return(throttle.Result{Failures: 10}, nil)
+	accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{}, exception.ErrNotFound)
+	accountRepository.On("SaveLoginLockout", ctx, mock.MatchedBy(func(lockout account.LoginLockout) bool {
+		return lockout.Subject == account.LoginSubjectEmail && lockout.Failures == 10 && lockout.ClientIP == "10.0.0.1"
+	})).Return(1, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.ClientIPThrottle = clientIPThrottle
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.Login(ctx, account.AccountAuthenticationRequest{Email: "johndoe@mail.com", Password: "password", Device: account.Device{ClientIP: "10.0.0.1"}})
+
+	assert.Equal(t, response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest), resp)
+	accountRepository.AssertExpectations(t)
+	clientIPThrottle.AssertExpectations(t)
+}
+
+func TestRegister_ConcurrentSameEmail(t *testing.T) {
+	ctx := context.Background()
+	sess := new(MockSession)
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	jsonWebToken := new(MockJSONWebToken)
+	mailer := new(MockMailer)
+
+	const registrations = 8
+
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	// a statement prepared on one connection would be prepared again on another one.
+	db.SetMaxOpenConns(1)
+
+	// the registrations reach the database in any order, the unique email index lets only the first insert through.
+	dbMock.MatchExpectationsInOrder(false)
+	for i := 0; i < registrations; i++ {
+		expectedInsert := dbMock.ExpectPrepare("INSERT INTO account").ExpectExec().WithArgs("johndoe@mail.com", sqlmock.AnyArg(), "John", "Doe", entity.RoleUser, sqlmock.AnyArg())
+		if i == 0 {
+			expectedInsert.WillReturnResult(sqlmock.NewResult(14, 1))
+			continue
+		}
+		expectedInsert.WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'johndoe@mail.com' for key 'account_email_unique'"})
+	}
+	accountRepository := account.NewAccountRepository(db, "account")
+
+	sess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("Save", ctx, mock.AnythingOfType("account.DeviceSession")).Return(nil)
+	jsonWebToken.On("Sign", ctx, mock.Anything).Return("access-token", nil)
+	mailer.On("Send", ctx, mock.Anything).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = sess
+	deps.PasswordResetSession = sess
+	deps.EmailVerificationSession = sess
+	deps.MFAChallengeSession = sess
+	deps.JSONWebToken = jsonWebToken
+	deps.Mailer = mailer
+	accountUsecase := account.NewAccountUsecase(deps)
+
+	responses := make(chan response.Response, registrations)
+	var wg sync.WaitGroup
+	for i := 0; i < registrations; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses <- accountUsecase.Register(ctx, account.AccountRegistrationRequest{Email: "johndoe@mail.com", Password: "password", FirstName: "John", LastName: "Doe"})
+		}()
+	}
+	wg.Wait()
+	close(responses)
+
+	created := 0
+	for resp := range responses {
+		if resp.Err() == nil {
+			created++
+			continue
+		}
+		assert.Equal(t, response.Error(response.StatusConflicted, nil, exception.ErrConflicted), resp)
+	}
+	assert.Equal(t, 1, created)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestRegister_NewEmail(t *testing.T) {
+	ctx := context.Background()
+	sess := new(MockSession)
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	jsonWebToken := new(MockJSONWebToken)
+	mailer := new(MockMailer)
+	accountRepository := new(MockAccountRepository)
+
+	accountRepository.On("Save", ctx, mock.AnythingOfType("account.Account")).Return(14, nil)
+	sess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("Save", ctx, mock.AnythingOfType("account.DeviceSession")).Return(nil)
+	jsonWebToken.On("Sign", ctx, mock.Anything).Return("access-token", nil)
+	mailer.On("Send", ctx, mock.Anything).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = sess
+	deps.PasswordResetSession = sess
+	deps.EmailVerificationSession = sess
+	deps.MFAChallengeSession = sess
+	deps.JSONWebToken = jsonWebToken
+	deps.Mailer = mailer
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.Register(ctx, account.AccountRegistrationRequest{Email: "johndoe@mail.com", Password: "password", FirstName: "John", LastName: "Doe"})
+
+	assert.NoError(t, resp.Err())
+	accountRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	mailer.AssertNumberOfCalls(t, "Send", 1)
+}
+
+func TestListSessions(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{SessionID: "current"}
+	claims.Subject = "14"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+	deviceSessionRepository.On("FindByAccountID", ctx, int64(14)).Return([]account.DeviceSession{{ID: "current", AccountID: 14}, {ID: "other", AccountID: 14}}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.ListSessions(ctx, claims)
+
+	assert.NoError(t, resp.Err())
+	var sessions []account.AccountSessionResponse
+	decodeResponseData(t, resp, &sessions)
+	assert.Len(t, sessions, 2)
+	assert.True(t, sessions[0].Current)
+	assert.False(t, sessions[1].Current)
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	refreshTokenSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{SessionID: "current"}
+	claims.Subject = "14"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+	deviceSessionRepository.On("FindByID", ctx, "other").Return(account.DeviceSession{ID: "other", AccountID: 14}, nil)
+	deviceSessionRepository.On("Delete", ctx, int64(14), "other").Return(nil)
+	refreshTokenSess.On("Delete", ctx, fmt.Sprintf(account.AccountRefreshTokenFamilyKeyFormat, "other")).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = refreshTokenSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(refreshTokenSess)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.RevokeSession(ctx, claims, "other")
+
+	assert.NoError(t, resp.Err())
+	deviceSessionRepository.AssertExpectations(t)
+	refreshTokenSess.AssertExpectations(t)
+}
+
+func TestRevokeSession_OtherAccount(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{SessionID: "current"}
+	claims.Subject = "14"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+	deviceSessionRepository.On("FindByID", ctx, "foreign").Return(account.DeviceSession{ID: "foreign", AccountID: 15}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.RevokeSession(ctx, claims, "foreign")
+
+	assert.Equal(t, response.Error(response.StatusNotFound, nil, exception.ErrNotFound), resp)
+	deviceSessionRepository.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	revocationSess := new(MockSession)
+	mailer := new(MockMailer)
+	accountRepository := new(MockAccountRepository)
+	policy := account.AccountPolicy{DeletionGracePeriod: time.Hour * 24 * 30}
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "14"
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword}, nil)
+	accountRepository.On("MarkDeleted", ctx, int64(14), mock.AnythingOfType("time.Time"), account.ArticleDispositionAnonymise).Return(nil)
+	revocationSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+	mailer.On("Send", ctx, mock.Anything).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = revocationSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(revocationSess)
+	deps.Policy = policy
+	deps.PasswordHasher = passwordHasher
+	deps.Mailer = mailer
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.DeleteAccount(ctx, claims, account.AccountDeletionRequest{Password: "password", Articles: account.ArticleDispositionAnonymise})
+
+	assert.NoError(t, resp.Err())
+	var deletion account.AccountDeletionResponse
+	decodeResponseData(t, resp, &deletion)
+	assert.WithinDuration(t, time.Now().Add(policy.DeletionGracePeriod), deletion.PurgeAt, time.Minute)
+	accountRepository.AssertExpectations(t)
+	revocationSess.AssertExpectations(t)
+	deviceSessionRepository.AssertExpectations(t)
+}
+
+func TestDeleteAccount_AlreadyDeleted(t *testing.T) {
+	ctx := context.Background()
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "14"
+
+	deletedAt := time.Now()
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", DeletedAt: &deletedAt}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.DeleteAccount(ctx, claims, account.AccountDeletionRequest{Password: "password", Articles: account.ArticleDispositionDelete})
+
+	assert.Equal(t, response.Error(response.StatusConflicted, nil, exception.ErrConflicted), resp)
+	accountRepository.AssertNotCalled(t, "MarkDeleted", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLogin_DeletedAccount(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	accountRepository := new(MockAccountRepository)
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	deletedAt := time.Now()
+	accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword, DeletedAt: &deletedAt}, nil)
+
+	emailThrottle := new(MockThrottle)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+	emailThrottle.On("Succeed", ctx, "johndoe@mail.com", throttle.Result{Failures: 1}).Return(nil)
+	emailThrottle.On("Reset", ctx, "johndoe@mail.com").Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.PasswordHasher = passwordHasher
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.Login(ctx, account.AccountAuthenticationRequest{Email: "johndoe@mail.com", Password: "password"})
+
+	assert.Equal(t, response.Error(response.StatusForbiddend, nil, account.ErrAccountDeleted), resp)
+}
+
+func TestRestoreAccount(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	mailer := new(MockMailer)
+	accountRepository := new(MockAccountRepository)
+	policy := account.AccountPolicy{DeletionGracePeriod: time.Hour * 24 * 30}
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	deletedAt := time.Now().Add(-time.Hour * 24)
+	accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword, DeletedAt: &deletedAt}, nil)
+	accountRepository.On("Restore", ctx, int64(14), mock.MatchedBy(func(deletedAfter time.Time) bool {
+		return time.Since(deletedAfter.Add(policy.DeletionGracePeriod)) < time.Minute
+	})).Return(nil)
+	mailer.On("Send", ctx, mock.MatchedBy(func(mail mailermodel.Mail) bool { return mail.To == "johndoe@mail.com" })).Return(nil)
+
+	emailThrottle := new(MockThrottle)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+	emailThrottle.On("Succeed", ctx, "johndoe@mail.com", throttle.Result{Failures: 1}).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.Policy = policy
+	deps.PasswordHasher = passwordHasher
+	deps.Mailer = mailer
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.RestoreAccount(ctx, account.AccountAuthenticationRequest{Email: "johndoe@mail.com", Password: "password"})
+
+	assert.NoError(t, resp.Err())
+	accountRepository.AssertExpectations(t)
+	emailThrottle.AssertExpectations(t)
+	mailer.AssertExpectations(t)
+}
+
+func TestRestoreAccount_WrongPassword(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	accountRepository := new(MockAccountRepository)
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	deletedAt := time.Now()
+	accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword, DeletedAt: &deletedAt}, nil)
+
+	emailThrottle := new(MockThrottle)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.PasswordHasher = passwordHasher
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.RestoreAccount(ctx, account.AccountAuthenticationRequest{Email: "johndoe@mail.com", Password: "wrong"})
+
+	assert.Equal(t, response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest), resp)
+	accountRepository.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything, mock.Anything)
+	emailThrottle.AssertNotCalled(t, "Succeed", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRestoreAccount_NotDeleted(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	accountRepository := new(MockAccountRepository)
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword}, nil)
+
+	emailThrottle := new(MockThrottle)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+	emailThrottle.On("Succeed", ctx, "johndoe@mail.com", throttle.Result{Failures: 1}).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.PasswordHasher = passwordHasher
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.RestoreAccount(ctx, account.AccountAuthenticationRequest{Email: "johndoe@mail.com", Password: "password"})
+
+	assert.Equal(t, response.Error(response.StatusConflicted, nil, account.ErrAccountNotDeleted), resp)
+	accountRepository.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRestoreAccount_GracePeriodOver(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	accountRepository := new(MockAccountRepository)
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	deletedAt := time.Now().Add(-time.Hour * 24 * 31)
+	accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword, DeletedAt: &deletedAt}, nil)
+	accountRepository.On("Restore", ctx, int64(14), mock.AnythingOfType("time.Time")).Return(exception.ErrNotFound)
+
+	emailThrottle := new(MockThrottle)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+	emailThrottle.On("Succeed", ctx, "johndoe@mail.com", throttle.Result{Failures: 1}).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.Policy = account.AccountPolicy{DeletionGracePeriod: time.Hour * 24 * 30}
+	deps.PasswordHasher = passwordHasher
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.RestoreAccount(ctx, account.AccountAuthenticationRequest{Email: "johndoe@mail.com", Password: "password"})
+
+	assert.Equal(t, response.Error(response.StatusConflicted, nil, account.ErrAccountPurgeDue), resp)
+}
+
+func TestExportData_ZIP(t *testing.T) {
+	ctx := context.Background()
+	authoredArticleRepository := new(MockAuthoredArticleRepository)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "14"
+
+	password := "hashed"
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &password}, nil)
+	authoredArticleRepository.On("FindByAuthorID", ctx, int64(14)).Return([]account.AuthoredArticle{{ID: 1, Title: "Hello"}}, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.AuthoredArticleRepository = authoredArticleRepository
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.ExportData(ctx, claims, account.AccountExportRequest{Format: "zip"})
+
+	assert.NoError(t, resp.Err())
+	recorder := httptest.NewRecorder()
+	resp.JSON(recorder)
+	assert.Equal(t, "application/zip", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="account-14-export.zip"`, recorder.Header().Get("Content-Disposition"))
+
+	archive, err := zip.NewReader(bytes.NewReader(recorder.Body.Bytes()), int64(recorder.Body.Len()))
+	assert.NoError(t, err)
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		r, _ := file.Open()
+		files[file.Name], _ = ioutil.ReadAll(r)
+		r.Close()
+	}
+
+	var profile account.Account
+	assert.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, "johndoe@mail.com", profile.Email)
+	assert.Nil(t, profile.Password)
+
+	var articles []account.AuthoredArticle
+	assert.NoError(t, json.Unmarshal(files["articles.json"], &articles))
+	assert.Equal(t, []account.AuthoredArticle{{ID: 1, Title: "Hello"}}, articles)
+}
+
+func TestAccountPurger_Purge(t *testing.T) {
+	ctx := context.Background()
+	location, _ := time.LoadLocation("Asia/Jakarta")
+	authoredArticleRepository := new(MockAuthoredArticleRepository)
+	accountRepository := new(MockAccountRepository)
+
+	accountRepository.On("FindDeletedBefore", ctx, mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= time.Hour*24*30
+	}), 100).Return([]account.Account{
+		{ID: 14, ArticleDisposition: account.ArticleDispositionDelete},
+		{ID: 15, ArticleDisposition: account.ArticleDispositionAnonymise},
+		{ID: 16, ArticleDisposition: account.ArticleDispositionDelete},
+	}, nil)
+	authoredArticleRepository.On("DeleteByAuthorID", ctx, int64(14)).Return(nil)
+	authoredArticleRepository.On("AnonymiseByAuthorID", ctx, int64(15)).Return(nil)
+	authoredArticleRepository.On("DeleteByAuthorID", ctx, int64(16)).Return(exception.ErrInternalServer)
+	accountRepository.On("Purge", ctx, int64(14)).Return(nil)
+	accountRepository.On("Purge", ctx, int64(15)).Return(nil)
+
+	purger := account.NewAccountPurger(time.Hour*24*30, location, authoredArticleRepository, accountRepository)
+	purged, err := purger.Purge(ctx)
+
+	assert.Equal(t, 2, purged)
+	assert.Equal(t, exception.ErrInternalServer, err)
+	accountRepository.AssertNotCalled(t, "Purge", ctx, int64(16))
+	authoredArticleRepository.AssertExpectations(t)
+}
+
+func TestChangeEmail(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	emailVerificationSess := new(MockSession)
+	emailChangeUndoSess := new(MockSession)
+	mailer := new(MockMailer)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "14"
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword}, nil)
+	accountRepository.On("FindByEmail", ctx, "john@doe.com").Return(account.Account{}, exception.ErrNotFound)
+	emailVerificationSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangePendingKeyFormat, 14)).Return([]byte(nil), session.ErrSessionNotFound)
+	emailVerificationSess.On("Set", ctx, fmt.Sprintf(account.AccountEmailChangePendingKeyFormat, 14), mock.AnythingOfType("[]uint8")).Return(nil)
+	emailVerificationSess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Return(nil)
+	emailChangeUndoSess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Return(nil)
+	mailer.On("Send", ctx, mock.MatchedBy(func(mail mailermodel.Mail) bool { return mail.To == "john@doe.com" })).Return(nil).Once()
+	mailer.On("Send", ctx, mock.MatchedBy(func(mail mailermodel.Mail) bool { return mail.To == "johndoe@mail.com" })).Return(nil).Once()
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailVerificationSession = emailVerificationSess
+	deps.EmailChangeUndoSession = emailChangeUndoSess
+	deps.PasswordHasher = passwordHasher
+	deps.Mailer = mailer
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.ChangeEmail(ctx, claims, account.AccountChangeEmailRequest{NewEmail: "john@doe.com", Password: "password"})
+
+	assert.NoError(t, resp.Err())
+	emailVerificationSess.AssertExpectations(t)
+	emailChangeUndoSess.AssertExpectations(t)
+	mailer.AssertExpectations(t)
+}
+
+func TestChangeEmail_SupersedesPending(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	emailVerificationSess := new(MockSession)
+	emailChangeUndoSess := new(MockSession)
+	mailer := new(MockMailer)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "14"
+
+	pendingKey := fmt.Sprintf(account.AccountEmailChangePendingKeyFormat, 14)
+	pending, _ := json.Marshal(account.EmailChange{AccountID: 14, OldEmail: "johndoe@mail.com", NewEmail: "jd@mail.com", ConfirmTokenHash: "confirm-hash", UndoTokenHash: "undo-hash"})
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword}, nil)
+	accountRepository.On("FindByEmail", ctx, "john@doe.com").Return(account.Account{}, exception.ErrNotFound)
+	emailVerificationSess.On("Consume", ctx, pendingKey).Return(pending, nil).Once()
+	emailVerificationSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangeKeyFormat, "confirm-hash")).Return(pending, nil).Once()
+	emailChangeUndoSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangeUndoKeyFormat, "undo-hash")).Return(pending, nil).Once()
+	emailVerificationSess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Return(nil)
+	emailChangeUndoSess.On("Set", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8")).Return(nil)
+	mailer.On("Send", ctx, mock.Anything).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailVerificationSession = emailVerificationSess
+	deps.EmailChangeUndoSession = emailChangeUndoSess
+	deps.PasswordHasher = passwordHasher
+	deps.Mailer = mailer
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.ChangeEmail(ctx, claims, account.AccountChangeEmailRequest{NewEmail: "john@doe.com", Password: "password"})
+
+	assert.NoError(t, resp.Err())
+	emailVerificationSess.AssertExpectations(t)
+	emailChangeUndoSess.AssertExpectations(t)
+	emailVerificationSess.AssertCalled(t, "Set", ctx, pendingKey, mock.AnythingOfType("[]uint8"))
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	revocationSess := new(MockSession)
+	emailVerificationSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	sum := sha256.Sum256([]byte("confirm-token"))
+	changeKey := fmt.Sprintf(account.AccountEmailChangeKeyFormat, hex.EncodeToString(sum[:]))
+	change, _ := json.Marshal(account.EmailChange{AccountID: 14, OldEmail: "johndoe@mail.com", NewEmail: "john@doe.com"})
+
+	emailVerificationSess.On("Consume", ctx, changeKey).Return(change, nil)
+	emailVerificationSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangePendingKeyFormat, 14)).Return(change, nil)
+	accountRepository.On("UpdateEmail", ctx, int64(14), "johndoe@mail.com", "john@doe.com", mock.AnythingOfType("time.Time")).Return(nil)
+	revocationSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = revocationSess
+	deps.EmailVerificationSession = emailVerificationSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(revocationSess)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.ConfirmEmailChange(ctx, account.AccountConfirmEmailChangeRequest{Token: "confirm-token"})
+
+	assert.NoError(t, resp.Err())
+	emailVerificationSess.AssertExpectations(t)
+	accountRepository.AssertExpectations(t)
+	revocationSess.AssertExpectations(t)
+	deviceSessionRepository.AssertExpectations(t)
+}
+
+func TestConfirmEmailChange_EmailTaken(t *testing.T) {
+	ctx := context.Background()
+	emailVerificationSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	change, _ := json.Marshal(account.EmailChange{AccountID: 14, OldEmail: "johndoe@mail.com", NewEmail: "john@doe.com"})
+
+	emailVerificationSess.On("Consume", ctx, mock.AnythingOfType("string")).Return(change, nil)
+	accountRepository.On("UpdateEmail", ctx, int64(14), "johndoe@mail.com", "john@doe.com", mock.AnythingOfType("time.Time")).Return(exception.ErrConflicted)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailVerificationSession = emailVerificationSess
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.ConfirmEmailChange(ctx, account.AccountConfirmEmailChangeRequest{Token: "confirm-token"})
+
+	assert.Equal(t, response.Error(response.StatusConflicted, nil, exception.ErrConflicted), resp)
+}
+
+func TestUndoEmailChange_Pending(t *testing.T) {
+	ctx := context.Background()
+	emailVerificationSess := new(MockSession)
+	emailChangeUndoSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	sum := sha256.Sum256([]byte("undo-token"))
+	undoKey := fmt.Sprintf(account.AccountEmailChangeUndoKeyFormat, hex.EncodeToString(sum[:]))
+	change, _ := json.Marshal(account.EmailChange{AccountID: 14, OldEmail: "johndoe@mail.com", NewEmail: "john@doe.com", ConfirmTokenHash: "confirm-hash"})
+
+	emailChangeUndoSess.On("Consume", ctx, undoKey).Return(change, nil)
+	emailVerificationSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangeKeyFormat, "confirm-hash")).Return(change, nil)
+	emailVerificationSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangePendingKeyFormat, 14)).Return(change, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailVerificationSession = emailVerificationSess
+	deps.EmailChangeUndoSession = emailChangeUndoSess
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.UndoEmailChange(ctx, account.AccountUndoEmailChangeRequest{Token: "undo-token"})
+
+	assert.NoError(t, resp.Err())
+	emailVerificationSess.AssertExpectations(t)
+	accountRepository.AssertNotCalled(t, "UpdateEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUndoEmailChange_Confirmed(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	revocationSess := new(MockSession)
+	emailVerificationSess := new(MockSession)
+	emailChangeUndoSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	change, _ := json.Marshal(account.EmailChange{AccountID: 14, OldEmail: "johndoe@mail.com", NewEmail: "john@doe.com", ConfirmTokenHash: "confirm-hash"})
+
+	emailChangeUndoSess.On("Consume", ctx, mock.AnythingOfType("string")).Return(change, nil)
+	emailVerificationSess.On("Consume", ctx, fmt.Sprintf(account.AccountEmailChangeKeyFormat, "confirm-hash")).Return([]byte(nil), session.ErrSessionNotFound)
+	accountRepository.On("UpdateEmail", ctx, int64(14), "john@doe.com", "johndoe@mail.com", mock.AnythingOfType("time.Time")).Return(nil)
+	revocationSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = revocationSess
+	deps.EmailVerificationSession = emailVerificationSess
+	deps.EmailChangeUndoSession = emailChangeUndoSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(revocationSess)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.UndoEmailChange(ctx, account.AccountUndoEmailChangeRequest{Token: "undo-token"})
+
+	assert.NoError(t, resp.Err())
+	accountRepository.AssertExpectations(t)
+	revocationSess.AssertExpectations(t)
+}
+
+func TestLogin_SuspendedAccount(t *testing.T) {
+	ctx := context.Background()
+	passwordHasher := hasher.NewBcryptHasher(4)
+	accountRepository := new(MockAccountRepository)
+
+	hashedPassword, _ := passwordHasher.Hash("password")
+	suspendedAt := time.Now()
+	accountRepository.On("FindByEmail", ctx, "johndoe@mail.com").Return(account.Account{ID: 14, Email: "johndoe@mail.com", Password: &hashedPassword, SuspendedAt: &suspendedAt}, nil)
+
+	emailThrottle := new(MockThrottle)
+	emailThrottle.On("Attempt", ctx, "johndoe@mail.com").Return(throttle.Result{Failures: 1}, nil)
+	emailThrottle.On("Succeed", ctx, "johndoe@mail.com", throttle.Result{Failures: 1}).Return(nil)
+	emailThrottle.On("Reset", ctx, "johndoe@mail.com").Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.EmailThrottle = emailThrottle
+	deps.PasswordHasher = passwordHasher
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.Login(ctx, account.AccountAuthenticationRequest{Email: "johndoe@mail.com", Password: "password"})
+
+	assert.Equal(t, response.Error(response.StatusForbiddend, nil, account.ErrAccountSuspended), resp)
+}
+
+func TestAdminListAccounts(t *testing.T) {
+	ctx := context.Background()
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "1"
+
+	password := "hashed"
+	accountRepository.On("FindMany", ctx, account.AccountFilter{Query: "john", Cursor: 10, Limit: 3}).Return([]account.Account{
+		{ID: 11, Email: "john@mail.com", Password: &password},
+		{ID: 12, Email: "johnny@mail.com", Password: &password},
+		{ID: 13, Email: "johndoe@mail.com", Password: &password},
+	}, nil)
+	accountRepository.On("SaveAuditLog", ctx, mock.MatchedBy(func(auditLog account.AuditLog) bool {
+		return auditLog.ActorID == 1 && auditLog.Action == account.AuditActionListAccounts && auditLog.Detail == `{"query":"john","cursor":10,"limit":2}`
+	})).Return(1, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.AdminListAccounts(ctx, claims, account.AdminListAccountsRequest{Query: "john", Cursor: 10, Limit: 2})
+
+	recorder := httptest.NewRecorder()
+	resp.JSON(recorder)
+	var body struct {
+		Data       []account.Account   `json:"data"`
+		Pagination response.Pagination `json:"pagination"`
+	}
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&body))
+
+	assert.Len(t, body.Data, 2)
+	assert.Nil(t, body.Data[0].Password)
+	assert.Equal(t, response.Pagination{NextCursor: "12", HasMore: true}, body.Pagination)
+	accountRepository.AssertExpectations(t)
+}
+
+func TestAdminSuspendAccount(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	refreshTokenSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "1"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+	accountRepository.On("UpdateSuspendedAt", ctx, int64(14), mock.AnythingOfType("*time.Time")).Return(nil)
+	refreshTokenSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+	accountRepository.On("SaveAuditLog", ctx, mock.MatchedBy(func(auditLog account.AuditLog) bool {
+		return auditLog.ActorID == 1 && auditLog.TargetID == 14 && auditLog.Action == account.AuditActionSuspendAccount && auditLog.Detail == `{"reason":"spam"}`
+	})).Return(1, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = refreshTokenSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(refreshTokenSess)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.AdminSuspendAccount(ctx, claims, 14, account.AdminSuspendAccountRequest{Reason: "spam"})
+
+	assert.NoError(t, resp.Err())
+	accountRepository.AssertExpectations(t)
+	refreshTokenSess.AssertExpectations(t)
+	deviceSessionRepository.AssertExpectations(t)
+}
+
+func TestAdminSuspendAccount_Self(t *testing.T) {
+	ctx := context.Background()
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "1"
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.AdminSuspendAccount(ctx, claims, 1, account.AdminSuspendAccountRequest{Reason: "oops"})
+
+	assert.Equal(t, response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest), resp)
+	accountRepository.AssertNotCalled(t, "UpdateSuspendedAt", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAdminAssignRole(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	refreshTokenSess := new(MockSession)
+	roleRepository := new(MockRoleRepository)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "1"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Role: entity.RoleUser}, nil)
+	roleRepository.On("RoleExists", ctx, entity.RoleEditor).Return(true, nil)
+	accountRepository.On("UpdateRole", ctx, int64(14), entity.RoleEditor).Return(nil)
+	refreshTokenSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+	accountRepository.On("SaveAuditLog", ctx, mock.MatchedBy(func(auditLog account.AuditLog) bool {
+		return auditLog.Action == account.AuditActionAssignRole && auditLog.Detail == `{"from":"user","to":"editor"}`
+	})).Return(1, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = refreshTokenSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(refreshTokenSess)
+	deps.RoleRepository = roleRepository
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.AdminAssignRole(ctx, claims, 14, account.AdminAssignRoleRequest{Role: entity.RoleEditor})
+
+	assert.NoError(t, resp.Err())
+	accountRepository.AssertExpectations(t)
+	refreshTokenSess.AssertExpectations(t)
+}
+
+func TestAdminAssignRole_UnknownRole(t *testing.T) {
+	ctx := context.Background()
+	roleRepository := new(MockRoleRepository)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "1"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Role: entity.RoleUser}, nil)
+	roleRepository.On("RoleExists", ctx, "moderator").Return(false, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.RoleRepository = roleRepository
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.AdminAssignRole(ctx, claims, 14, account.AdminAssignRoleRequest{Role: "moderator"})
+
+	assert.Equal(t, response.Error(response.StatusInvalidPayload, nil, account.ErrUnknownRole), resp)
+	accountRepository.AssertNotCalled(t, "UpdateRole", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAdminForcePasswordReset(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	revocationSess := new(MockSession)
+	passwordResetSess := new(MockSession)
+	mailer := new(MockMailer)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "1"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+	accountRepository.On("UpdatePasswordResetRequiredAt", ctx, int64(14), mock.AnythingOfType("*time.Time")).Return(nil)
+	revocationSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+	accountRepository.On("DeleteAPIKeysByAccountID", ctx, int64(14)).Return(nil)
+	accountRepository.On("SaveAuditLog", ctx, mock.AnythingOfType("account.AuditLog")).Return(1, nil)
+	passwordResetSess.On("Set", ctx, mock.AnythingOfType("string"), []byte("14")).Return(nil)
+	mailer.On("Send", ctx, mock.MatchedBy(func(mail mailermodel.Mail) bool { return mail.To == "johndoe@mail.com" })).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = revocationSess
+	deps.PasswordResetSession = passwordResetSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(revocationSess)
+	deps.Mailer = mailer
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.AdminForcePasswordReset(ctx, claims, 14)
+
+	assert.NoError(t, resp.Err())
+	accountRepository.AssertExpectations(t)
+	passwordResetSess.AssertExpectations(t)
+	mailer.AssertExpectations(t)
+}
+
+func TestLogoutAll_RevokesAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	revocationSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "14"
+
+	revocationSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+	accountRepository.On("DeleteAPIKeysByAccountID", ctx, int64(14)).Return(nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = revocationSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(revocationSess)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.LogoutAll(ctx, claims)
+
+	assert.NoError(t, resp.Err())
+	accountRepository.AssertExpectations(t)
+	deviceSessionRepository.AssertExpectations(t)
+}
+
+func TestAdminForceLogout_RevokesAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	deviceSessionRepository := new(MockDeviceSessionRepository)
+	revocationSess := new(MockSession)
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "1"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, Email: "johndoe@mail.com"}, nil)
+	revocationSess.On("Set", ctx, fmt.Sprintf(jwt.RevokedSubjectBeforeKeyFormat, "14"), mock.AnythingOfType("[]uint8")).Return(nil)
+	deviceSessionRepository.On("DeleteByAccountID", ctx, int64(14)).Return(nil)
+	accountRepository.On("DeleteAPIKeysByAccountID", ctx, int64(14)).Return(exception.ErrInternalServer).Once()
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	deps.DeviceSessionRepository = deviceSessionRepository
+	deps.RefreshTokenSession = revocationSess
+	deps.TokenRevocation = jwt.NewTokenRevocation(revocationSess)
+	accountUsecase := account.NewAccountUsecase(deps)
+
+	// a key left behind must not be reported as signed out.
+	resp := accountUsecase.AdminForceLogout(ctx, claims, 14)
+	assert.Equal(t, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer), resp)
+
+	accountRepository.On("DeleteAPIKeysByAccountID", ctx, int64(14)).Return(nil).Once()
+	accountRepository.On("SaveAuditLog", ctx, mock.MatchedBy(func(auditLog account.AuditLog) bool {
+		return auditLog.Action == account.AuditActionForceLogout
+	})).Return(1, nil)
+
+	resp = accountUsecase.AdminForceLogout(ctx, claims, 14)
+	assert.NoError(t, resp.Err())
+	accountRepository.AssertExpectations(t)
+}
+
+func hashOf(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "14"
+
+	var saved account.APIKey
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14}, nil)
+	accountRepository.On("FindAPIKeysByAccountID", ctx, int64(14)).Return([]account.APIKey{}, nil)
+	accountRepository.On("SaveAPIKey", ctx, mock.AnythingOfType("account.APIKey")).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(account.APIKey)
+	}).Return(7, nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.CreateAPIKey(ctx, claims, account.AccountCreateAPIKeyRequest{Name: "ci", Scopes: []string{entity.ScopeArticlesWrite}})
+	assert.NoError(t, resp.Err())
+
+	var created struct {
+		ID     int64    `json:"id"`
+		Key    string   `json:"key"`
+		Prefix string   `json:"prefix"`
+		Scopes []string `json:"scopes"`
+	}
+	decodeResponseData(t, resp, &created)
+
+	assert.Equal(t, int64(7), created.ID)
+	assert.True(t, strings.HasPrefix(created.Key, account.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+	assert.Equal(t, []string{entity.ScopeArticlesWrite}, created.Scopes)
+	assert.Equal(t, hashOf(created.Key), saved.KeyHash)
+}
+
+func TestCreateAPIKey_LimitReached(t *testing.T) {
+	ctx := context.Background()
+	accountRepository := new(MockAccountRepository)
+
+	claims := entity.AccountStandardJWTClaims{}
+	claims.Subject = "14"
+
+	accountRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14}, nil)
+	accountRepository.On("FindAPIKeysByAccountID", ctx, int64(14)).Return(make([]account.APIKey, 20), nil)
+
+	deps := newAccountUsecaseDependencies(accountRepository)
+	accountUsecase := account.NewAccountUsecase(deps)
+	resp := accountUsecase.CreateAPIKey(ctx, claims, account.AccountCreateAPIKeyRequest{Name: "ci", Scopes: []string{entity.ScopeArticlesRead}})
+
+	assert.Equal(t, response.Error(response.StatusConflicted, nil, account.ErrAPIKeyLimitReached), resp)
+	accountRepository.AssertNotCalled(t, "SaveAPIKey", mock.Anything, mock.Anything)
+}
@@ -3,49 +3,153 @@ package account
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
-	"encoding/json"
-	"fmt"
 	"github.com/sangianpatrick/devoria-article-service/entity"
+	"log"
+	"strconv"
 	"time"
 
 	"github.com/sangianpatrick/devoria-article-service/crypto"
 	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/hasher"
 	"github.com/sangianpatrick/devoria-article-service/jwt"
+	"github.com/sangianpatrick/devoria-article-service/mailer"
+	"github.com/sangianpatrick/devoria-article-service/oidc"
 	"github.com/sangianpatrick/devoria-article-service/response"
 	"github.com/sangianpatrick/devoria-article-service/session"
+	"github.com/sangianpatrick/devoria-article-service/throttle"
 )
 
 type AccountUsecase interface {
 	Register(ctx context.Context, params AccountRegistrationRequest) (resp response.Response)
 	Login(ctx context.Context, params AccountAuthenticationRequest) (resp response.Response)
 	GetProfile(ctx context.Context, claims entity.AccountStandardJWTClaims) (resp response.Response)
+	UpdateProfile(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountUpdateRequest) (resp response.Response)
+	ChangePassword(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountChangePasswordRequest) (resp response.Response)
+	ForgotPassword(ctx context.Context, params AccountForgotPasswordRequest) (resp response.Response)
+	ResetPassword(ctx context.Context, params AccountResetPasswordRequest) (resp response.Response)
+	VerifyEmail(ctx context.Context, params AccountVerifyEmailRequest) (resp response.Response)
+	ResendEmailVerification(ctx context.Context, params AccountResendEmailVerificationRequest) (resp response.Response)
+	EnrolTOTP(ctx context.Context, claims entity.AccountStandardJWTClaims) (resp response.Response)
+	ConfirmTOTP(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountConfirmTOTPRequest) (resp response.Response)
+	DisableTOTP(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountDisableTOTPRequest) (resp response.Response)
+	LoginMFA(ctx context.Context, params AccountMFALoginRequest) (resp response.Response)
+	StartOIDCLogin(ctx context.Context) (resp response.Response)
+	CompleteOIDCLogin(ctx context.Context, params AccountOIDCCallbackRequest) (resp response.Response)
+	RefreshToken(ctx context.Context, params AccountRefreshTokenRequest) (resp response.Response)
+	Logout(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountLogoutRequest) (resp response.Response)
+	LogoutAll(ctx context.Context, claims entity.AccountStandardJWTClaims) (resp response.Response)
+	ListSessions(ctx context.Context, claims entity.AccountStandardJWTClaims) (resp response.Response)
+	RevokeSession(ctx context.Context, claims entity.AccountStandardJWTClaims, ID string) (resp response.Response)
+	CreateAPIKey(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountCreateAPIKeyRequest) (resp response.Response)
+	ListAPIKeys(ctx context.Context, claims entity.AccountStandardJWTClaims) (resp response.Response)
+	RevokeAPIKey(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response)
+	DeleteAccount(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountDeletionRequest) (resp response.Response)
+	RestoreAccount(ctx context.Context, params AccountAuthenticationRequest) (resp response.Response)
+	ExportData(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountExportRequest) (resp response.Response)
+	ChangeEmail(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountChangeEmailRequest) (resp response.Response)
+	ConfirmEmailChange(ctx context.Context, params AccountConfirmEmailChangeRequest) (resp response.Response)
+	UndoEmailChange(ctx context.Context, params AccountUndoEmailChangeRequest) (resp response.Response)
+	AdminListAccounts(ctx context.Context, claims entity.AccountStandardJWTClaims, params AdminListAccountsRequest) (resp response.Response)
+	AdminGetAccount(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response)
+	AdminSuspendAccount(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64, params AdminSuspendAccountRequest) (resp response.Response)
+	AdminUnsuspendAccount(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response)
+	AdminForceLogout(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response)
+	AdminForcePasswordReset(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response)
+	AdminAssignRole(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64, params AdminAssignRoleRequest) (resp response.Response)
+	AdminListAuditLogs(ctx context.Context, claims entity.AccountStandardJWTClaims, ID int64) (resp response.Response)
 }
 
 type accountUsecaseImpl struct {
-	globalIV     string
-	session      session.Session
-	jsonWebToken jwt.JSONWebToken
-	crypto       crypto.Crypto
-	location     *time.Location
-	repository   AccountRepository
+	globalIV                  string
+	frontendURL               string
+	totpIssuer                string
+	deviceSessionRepository   DeviceSessionRepository
+	refreshTokenSession       session.Session
+	passwordResetSession      session.Session
+	emailVerificationSession  session.Session
+	emailResendSession        session.Session
+	mfaChallengeSession       session.Session
+	emailChangeUndoSession    session.Session
+	oidcStateSession          session.Session
+	jsonWebToken              jwt.JSONWebToken
+	tokenRevocation           jwt.TokenRevocation
+	oidcProvider              oidc.Provider
+	emailThrottle             throttle.Throttle
+	clientIPThrottle          throttle.Throttle
+	accessTokenTTL            time.Duration
+	policy                    AccountPolicy
+	crypto                    crypto.Crypto
+	cipher                    crypto.Cipher
+	passwordHasher            hasher.PasswordHasher
+	mailer                    mailer.Mailer
+	location                  *time.Location
+	authoredArticleRepository AuthoredArticleRepository
+	roleRepository            RoleRepository
+	repository                AccountRepository
 }
 
-func NewAccountUsecase(
-	globalIV string,
-	session session.Session,
-	jsonWebToken jwt.JSONWebToken,
-	crypto crypto.Crypto,
-	location *time.Location,
-	repository AccountRepository,
-) AccountUsecase {
+// AccountUsecaseDependencies is a collection of what the account usecase is built on.
+// OIDCProvider is nil when no external provider is configured. EmailResendSession expires after the interval
+// between two verification mails to the same email.
+type AccountUsecaseDependencies struct {
+	GlobalIV                  string
+	FrontendURL               string
+	TOTPIssuer                string
+	DeviceSessionRepository   DeviceSessionRepository
+	RefreshTokenSession       session.Session
+	PasswordResetSession      session.Session
+	EmailVerificationSession  session.Session
+	EmailResendSession        session.Session
+	MFAChallengeSession       session.Session
+	EmailChangeUndoSession    session.Session
+	OIDCStateSession          session.Session
+	JSONWebToken              jwt.JSONWebToken
+	TokenRevocation           jwt.TokenRevocation
+	OIDCProvider              oidc.Provider
+	EmailThrottle             throttle.Throttle
+	ClientIPThrottle          throttle.Throttle
+	AccessTokenTTL            time.Duration
+	Policy                    AccountPolicy
+	Crypto                    crypto.Crypto
+	Cipher                    crypto.Cipher
+	PasswordHasher            hasher.PasswordHasher
+	Mailer                    mailer.Mailer
+	Location                  *time.Location
+	AuthoredArticleRepository AuthoredArticleRepository
+	RoleRepository            RoleRepository
+	Repository                AccountRepository
+}
+
+func NewAccountUsecase(deps AccountUsecaseDependencies) AccountUsecase {
 	return &accountUsecaseImpl{
-		globalIV:     globalIV,
-		session:      session,
-		jsonWebToken: jsonWebToken,
-		crypto:       crypto,
-		location:     location,
-		repository:   repository,
+		globalIV:                  deps.GlobalIV,
+		frontendURL:               deps.FrontendURL,
+		totpIssuer:                deps.TOTPIssuer,
+		deviceSessionRepository:   deps.DeviceSessionRepository,
+		refreshTokenSession:       deps.RefreshTokenSession,
+		passwordResetSession:      deps.PasswordResetSession,
+		emailVerificationSession:  deps.EmailVerificationSession,
+		emailResendSession:        deps.EmailResendSession,
+		mfaChallengeSession:       deps.MFAChallengeSession,
+		emailChangeUndoSession:    deps.EmailChangeUndoSession,
+		oidcStateSession:          deps.OIDCStateSession,
+		jsonWebToken:              deps.JSONWebToken,
+		tokenRevocation:           deps.TokenRevocation,
+		oidcProvider:              deps.OIDCProvider,
+		emailThrottle:             deps.EmailThrottle,
+		clientIPThrottle:          deps.ClientIPThrottle,
+		accessTokenTTL:            deps.AccessTokenTTL,
+		policy:                    deps.Policy,
+		crypto:                    deps.Crypto,
+		cipher:                    deps.Cipher,
+		passwordHasher:            deps.PasswordHasher,
+		mailer:                    deps.Mailer,
+		location:                  deps.Location,
+		authoredArticleRepository: deps.AuthoredArticleRepository,
+		roleRepository:            deps.RoleRepository,
+		repository:                deps.Repository,
 	}
 }
 
@@ -63,88 +167,133 @@ func (u *accountUsecaseImpl) generateBase64String(byteSize int) string {
 	return encoded
 }
 
+// Register creates the account. The uniqueness of the email is left to the unique index of the table,
+// a lookup beforehand could not stop two concurrent registrations of the same email.
 func (u *accountUsecaseImpl) Register(ctx context.Context, params AccountRegistrationRequest) (resp response.Response) {
-	fmt.Println("len", len(u.generateBase64String(8)))
-	fmt.Println(u.globalIV)
-	_, err := u.repository.FindByEmail(ctx, params.Email)
+	hashedPassword, err := u.passwordHasher.Hash(params.Password)
 	if err != nil {
-		return response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
-	}
-
-	if err == exception.ErrNotFound {
 		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
 	}
-	encryptedPassword := u.crypto.Encrypt(params.Password, u.globalIV)
 	newAccount := Account{}
 	newAccount.Email = params.Email
-	newAccount.Password = &encryptedPassword
+	newAccount.Password = &hashedPassword
 	newAccount.FirstName = params.FirstName
 	newAccount.LastName = params.LastName
+	newAccount.Role = entity.RoleUser
 	newAccount.CreatedAt = time.Now().In(u.location)
 
 	ID, err := u.repository.Save(ctx, newAccount)
 	if err != nil {
+		if err == exception.ErrConflicted {
+			return response.Error(response.StatusConflicted, nil, exception.ErrConflicted)
+		}
 		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
 	}
 	newAccount.ID = ID
 
-	claims := entity.AccountStandardJWTClaims{}
-	claims.Email = newAccount.Email
-	claims.Subject = fmt.Sprintf("%d", newAccount.ID)
-	claims.IssuedAt = time.Now().Unix()
-	claims.ExpiresAt = time.Now().Add(time.Hour * 24 * 1).Unix()
-
-	token, err := u.jsonWebToken.Sign(ctx, claims)
-	if err != nil {
-		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	// the account is usable right away, a verification mail can be asked again when this one is lost.
+	if err = u.sendEmailVerification(ctx, newAccount); err != nil {
+		log.Println(err)
 	}
 
-	newAccountBuff, _ := json.Marshal(newAccount)
+	return u.authenticate(ctx, newAccount, params.Device, response.StatusCreated)
+}
 
-	err = u.session.Set(ctx, fmt.Sprintf(AccountSessionKeyFormat, newAccount.Email), newAccountBuff)
+func (u *accountUsecaseImpl) Login(ctx context.Context, params AccountAuthenticationRequest) (resp response.Response) {
+	attempt, retryAfter, err := u.attemptLogin(ctx, params)
 	if err != nil {
 		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
 	}
+	if retryAfter > 0 {
+		return response.ErrorWithRetryAfter(response.StatusTooManyRequests, nil, ErrTooManyRequests, retryAfter)
+	}
 
-	// publish to kafke if availabe
-
-	newAccount.Password = nil
-
-	accountAuthenticationResponse := AccountAuthenticationResponse{}
-	accountAuthenticationResponse.Token = token
-	accountAuthenticationResponse.Profile = newAccount
-
-	return response.Success(response.StatusCreated, accountAuthenticationResponse)
-}
-
-func (u *accountUsecaseImpl) Login(ctx context.Context, params AccountAuthenticationRequest) (resp response.Response) {
 	account, err := u.repository.FindByEmail(ctx, params.Email)
 	if err != nil {
 		if err == exception.ErrNotFound {
+			u.failLogin(ctx, attempt)
 			return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
 		}
 		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
 	}
 
-	encryptedPassword := u.crypto.Encrypt(params.Password, u.globalIV)
-	if encryptedPassword != *account.Password {
+	err = u.verifyPassword(ctx, account, params.Password)
+	if err != nil {
+		u.failLogin(ctx, attempt)
 		return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
 	}
 
-	claims := entity.AccountStandardJWTClaims{}
-	claims.Email = account.Email
-	claims.Subject = fmt.Sprintf("%d", account.ID)
-	claims.IssuedAt = time.Now().Unix()
-	claims.ExpiresAt = time.Now().Add(time.Hour * 24 * 1).Unix()
+	u.passLogin(ctx, attempt)
+
+	if resp = u.checkLoginAllowed(account); resp != nil {
+		return
+	}
+
+	if account.TOTPEnabledAt != nil {
+		challengeToken, err := u.issueMFAChallenge(ctx, account)
+		if err != nil {
+			return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+		}
+
+		return response.Success(response.StatusOK, AccountMFAChallengeResponse{
+			MFARequired:    true,
+			ChallengeToken: challengeToken,
+		})
+	}
+
+	// the failures of the email are forgotten only once every factor is proven.
+	u.unlockLogin(ctx, account.Email)
+
+	return u.authenticate(ctx, account, params.Device, response.StatusOK)
+}
+
+// checkLoginAllowed returns the response refusing an account that may not sign in, otherwise nil.
+func (u *accountUsecaseImpl) checkLoginAllowed(account Account) (resp response.Response) {
+	if account.DeletedAt != nil {
+		return response.Error(response.StatusForbiddend, nil, ErrAccountDeleted)
+	}
+
+	if account.SuspendedAt != nil {
+		return response.Error(response.StatusForbiddend, nil, ErrAccountSuspended)
+	}
+
+	if account.PasswordResetRequiredAt != nil {
+		return response.Error(response.StatusForbiddend, nil, ErrPasswordResetRequired)
+	}
+
+	if u.policy.RequireVerifiedEmailToLogin && account.VerifiedAt == nil {
+		return response.Error(response.StatusForbiddend, nil, ErrEmailNotVerified)
+	}
+
+	return nil
+}
+
+// authenticate starts a device session of an account that proved every required factor and issues its tokens.
+func (u *accountUsecaseImpl) authenticate(ctx context.Context, account Account, device Device, status string) (resp response.Response) {
+	now := time.Now().In(u.location)
+	deviceSession := DeviceSession{
+		ID:         u.generateBase64String(16),
+		AccountID:  account.ID,
+		UserAgent:  device.UserAgent,
+		ClientIP:   device.ClientIP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if deviceSession.ID == "" {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
 
-	token, err := u.jsonWebToken.Sign(ctx, claims)
+	err := u.deviceSessionRepository.Save(ctx, deviceSession)
 	if err != nil {
 		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
 	}
 
-	accountBuff, _ := json.Marshal(account)
+	token, err := u.signAccessToken(ctx, account, deviceSession.ID)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
 
-	err = u.session.Set(ctx, fmt.Sprintf(AccountSessionKeyFormat, account.Email), accountBuff)
+	refreshToken, err := u.issueRefreshToken(ctx, account.ID, deviceSession.ID)
 	if err != nil {
 		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
 	}
@@ -155,10 +304,59 @@ func (u *accountUsecaseImpl) Login(ctx context.Context, params AccountAuthentica
 
 	accountAuthenticationResponse := AccountAuthenticationResponse{}
 	accountAuthenticationResponse.Token = token
+	accountAuthenticationResponse.RefreshToken = refreshToken
 	accountAuthenticationResponse.Profile = account
 
-	return response.Success(response.StatusOK, accountAuthenticationResponse)
+	return response.Success(status, accountAuthenticationResponse)
+}
+
+// verifyPassword compares the password with the stored one and, when it matches,
+// upgrades a legacy AES value or an outdated hash to the current password hasher.
+func (u *accountUsecaseImpl) verifyPassword(ctx context.Context, account Account, password string) (err error) {
+	if account.Password == nil {
+		return hasher.ErrMismatchedPassword
+	}
+	storedPassword := *account.Password
+
+	err = u.passwordHasher.Verify(password, storedPassword)
+	if err == hasher.ErrUnsupportedHash {
+		err = u.verifyLegacyPassword(password, storedPassword)
+	}
+	if err != nil {
+		return
+	}
+
+	if u.passwordHasher.NeedsRehash(storedPassword) {
+		u.rehashPassword(ctx, account.ID, password)
+	}
+
+	return
+}
+
+// verifyLegacyPassword compares the password with a value stored by the former AES-CBC scheme.
+func (u *accountUsecaseImpl) verifyLegacyPassword(password string, storedPassword string) (err error) {
+	encryptedPassword := u.crypto.Encrypt(password, u.globalIV)
+	if subtle.ConstantTimeCompare([]byte(encryptedPassword), []byte(storedPassword)) != 1 {
+		return hasher.ErrMismatchedPassword
+	}
+
+	return
+}
+
+// rehashPassword stores the password with the current hasher. A failure must not block the login,
+// the value will simply be upgraded on the next successful attempt.
+func (u *accountUsecaseImpl) rehashPassword(ctx context.Context, ID int64, password string) {
+	hashedPassword, err := u.passwordHasher.Hash(password)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	if err := u.repository.UpdatePassword(ctx, ID, hashedPassword); err != nil {
+		log.Println(err)
+	}
 }
+
 func (u *accountUsecaseImpl) GetProfile(ctx context.Context, claims entity.AccountStandardJWTClaims) (resp response.Response) {
 
 	account, err := u.repository.FindByEmail(ctx, claims.Email)
@@ -170,5 +368,47 @@ func (u *accountUsecaseImpl) GetProfile(ctx context.Context, claims entity.Accou
 	newAccount.Email = account.Email
 	newAccount.FirstName = account.FirstName
 	newAccount.LastName = account.LastName
+	newAccount.Role = account.Role
+	newAccount.VerifiedAt = account.VerifiedAt
+	newAccount.TOTPEnabledAt = account.TOTPEnabledAt
 	return response.Success(response.StatusOK, newAccount)
 }
+
+func (u *accountUsecaseImpl) UpdateProfile(ctx context.Context, claims entity.AccountStandardJWTClaims, params AccountUpdateRequest) (resp response.Response) {
+	ID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+	}
+
+	account, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusNotFound, nil, exception.ErrNotFound)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	changed := false
+	if params.FirstName != nil && *params.FirstName != account.FirstName {
+		account.FirstName = *params.FirstName
+		changed = true
+	}
+	if params.LastName != nil && *params.LastName != account.LastName {
+		account.LastName = *params.LastName
+		changed = true
+	}
+
+	if changed {
+		lastModifiedAt := time.Now().In(u.location)
+		account.LastModifiedAt = &lastModifiedAt
+
+		err = u.repository.Update(ctx, account.ID, account)
+		if err != nil {
+			return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+		}
+	}
+
+	account.Password = nil
+
+	return response.Success(response.StatusOK, account)
+}
@@ -0,0 +1,120 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/mailer"
+	"github.com/sangianpatrick/devoria-article-service/response"
+	"github.com/sangianpatrick/devoria-article-service/session"
+)
+
+const emailVerificationTokenByteSize = 32
+
+// Errors of email verification and login throttling.
+var (
+	ErrEmailNotVerified = fmt.Errorf("email is not verified")
+	ErrTooManyRequests  = fmt.Errorf("too many requests")
+)
+
+// VerifyEmail consumes the verification token and marks the account email as verified.
+func (u *accountUsecaseImpl) VerifyEmail(ctx context.Context, params AccountVerifyEmailRequest) (resp response.Response) {
+	key := fmt.Sprintf(AccountEmailVerificationKeyFormat, hashToken(params.Token))
+
+	value, err := u.emailVerificationSession.Consume(ctx, key)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	ID, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	account, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if account.VerifiedAt != nil {
+		return response.Success(response.StatusOK, nil)
+	}
+
+	verifiedAt := time.Now().In(u.location)
+	err = u.repository.UpdateVerifiedAt(ctx, account.ID, verifiedAt)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// ResendEmailVerification mails a new verification token. It answers the same way whether the email
+// exists or is already verified, and the throttling is keyed by the email for the same reason.
+// The email is counted in a single step, only the first request of every resend interval gets through.
+func (u *accountUsecaseImpl) ResendEmailVerification(ctx context.Context, params AccountResendEmailVerificationRequest) (resp response.Response) {
+	// emails differing only by case or surrounding spaces belong to the same account.
+	sentKey := fmt.Sprintf(AccountEmailVerificationSentKeyFormat, hashToken(strings.ToLower(strings.TrimSpace(params.Email))))
+
+	count, err := u.emailResendSession.Increment(ctx, sentKey)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+	if count > 1 {
+		return response.Error(response.StatusTooManyRequests, nil, ErrTooManyRequests)
+	}
+
+	account, err := u.repository.FindByEmail(ctx, params.Email)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Success(response.StatusOK, nil)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if account.VerifiedAt != nil {
+		return response.Success(response.StatusOK, nil)
+	}
+
+	err = u.sendEmailVerification(ctx, account)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, nil)
+}
+
+// sendEmailVerification stores a single-use verification token of the account and mails it.
+func (u *accountUsecaseImpl) sendEmailVerification(ctx context.Context, account Account) (err error) {
+	token := u.generateBase64String(emailVerificationTokenByteSize)
+	if token == "" {
+		return exception.ErrInternalServer
+	}
+
+	err = u.emailVerificationSession.Set(ctx, fmt.Sprintf(AccountEmailVerificationKeyFormat, hashToken(token)), []byte(fmt.Sprintf("%d", account.ID)))
+	if err != nil {
+		return
+	}
+
+	err = u.mailer.Send(ctx, mailer.Mail{
+		To:      account.Email,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("Hi %s,\n\nuse the link below to verify your email:\n%s/verify-email?token=%s\n\nIf you did not create an account, you can ignore this mail.", account.FirstName, u.frontendURL, token),
+	})
+	if err != nil {
+		log.Println(err)
+	}
+
+	return
+}
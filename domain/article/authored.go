@@ -0,0 +1,48 @@
+package article
+
+import (
+	"context"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+)
+
+// authoredArticleRepository exposes the articles of an author to the account domain, which cannot import this one.
+type authoredArticleRepository struct {
+	repository ArticleRepository
+}
+
+// NewAuthoredArticleRepository is a constructor.
+func NewAuthoredArticleRepository(repository ArticleRepository) account.AuthoredArticleRepository {
+	return &authoredArticleRepository{repository}
+}
+
+func (r *authoredArticleRepository) FindByAuthorID(ctx context.Context, authorID int64) (bunchOfArticles []account.AuthoredArticle, err error) {
+	articles, err := r.repository.FindByAuthorID(ctx, authorID)
+	if err != nil {
+		return
+	}
+
+	bunchOfArticles = make([]account.AuthoredArticle, 0, len(articles))
+	for _, article := range articles {
+		bunchOfArticles = append(bunchOfArticles, account.AuthoredArticle{
+			ID:             article.ID,
+			Title:          article.Title,
+			Subtitle:       article.Subtitle,
+			Content:        article.Content,
+			Status:         string(article.Status),
+			CreatedAt:      article.CreatedAt,
+			PublishedAt:    article.PublishedAt,
+			LastModifiedAt: article.LastModifiedAt,
+		})
+	}
+
+	return
+}
+
+func (r *authoredArticleRepository) DeleteByAuthorID(ctx context.Context, authorID int64) (err error) {
+	return r.repository.DeleteByAuthorID(ctx, authorID)
+}
+
+func (r *authoredArticleRepository) AnonymiseByAuthorID(ctx context.Context, authorID int64) (err error) {
+	return r.repository.AnonymiseByAuthorID(ctx, authorID)
+}
@@ -26,3 +26,31 @@ type Article struct {
 	LastModifiedAt *time.Time
 	Author         account.Account
 }
+
+// ArticleSortOrder is a direction of article listing.
+type ArticleSortOrder string
+
+const (
+	ArticleSortOrderAsc  ArticleSortOrder = "asc"
+	ArticleSortOrderDesc ArticleSortOrder = "desc"
+)
+
+// ArticleCursor is a position in the article listing.
+// Articles are ordered by their publish time, or creation time for drafts, then by ID.
+type ArticleCursor struct {
+	SortedAt time.Time `json:"sortedAt"`
+	ID       int64     `json:"id"`
+}
+
+// ArticleFilter is a collection of criteria of article listing.
+type ArticleFilter struct {
+	Status        ArticleStatus
+	AuthorID      int64
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	PublishedFrom *time.Time
+	PublishedTo   *time.Time
+	SortOrder     ArticleSortOrder
+	Cursor        *ArticleCursor
+	Limit         int
+}
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
+	"github.com/sangianpatrick/devoria-article-service/domain/authz"
 	"github.com/sangianpatrick/devoria-article-service/entity"
 	"github.com/sangianpatrick/devoria-article-service/jwt"
 	"github.com/sangianpatrick/devoria-article-service/middleware"
@@ -24,6 +27,7 @@ func NewAccountHTTPHandler(
 	router *mux.Router,
 	basicAuthMiddleware middleware.RouteMiddleware,
 	jwtAuth jwt.JwtMiddleware,
+	authorizer authz.Authorizer,
 	validate *validator.Validate,
 	usecase ArticleUsecase,
 ) {
@@ -32,11 +36,21 @@ func NewAccountHTTPHandler(
 		Usecase:  usecase,
 	}
 
-	router.HandleFunc("/v1/article/create", jwtAuth.VerifyToken(handler.Save)).Methods(http.MethodPost)
-	router.HandleFunc("/v1/article/update", jwtAuth.VerifyToken(handler.Update)).Methods(http.MethodPut)
-	router.HandleFunc("/v1/article/delete/{id}", jwtAuth.VerifyToken(handler.Delete)).Methods(http.MethodDelete)
-	router.HandleFunc("/v1/article/publish/{id}", jwtAuth.VerifyToken(handler.PublishArticleStatus)).Methods(http.MethodPut)
-	router.HandleFunc("/v1/article/findbyid/{id}", jwtAuth.VerifyToken(handler.FindByID)).Methods(http.MethodGet)
+	// a personal api key only reaches the routes its scopes allow.
+	canRead := middleware.NewScope(entity.ScopeArticlesRead)
+	canWrite := middleware.NewScope(entity.ScopeArticlesWrite)
+
+	// the role of the account must be granted the permission of the route.
+	mayRead := authorizer.Require(entity.PermissionArticlesRead)
+	mayWrite := authorizer.Require(entity.PermissionArticlesWrite)
+	mayPublish := authorizer.Require(entity.PermissionArticlesPublish)
+
+	router.HandleFunc("/v1/article/create", jwtAuth.VerifyToken(canWrite.Verify(mayWrite.Verify(handler.Save)))).Methods(http.MethodPost)
+	router.HandleFunc("/v1/article/update", jwtAuth.VerifyToken(canWrite.Verify(mayWrite.Verify(handler.Update)))).Methods(http.MethodPut)
+	router.HandleFunc("/v1/article/delete/{id}", jwtAuth.VerifyToken(canWrite.Verify(mayWrite.Verify(handler.Delete)))).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/article/publish/{id}", jwtAuth.VerifyToken(canWrite.Verify(mayPublish.Verify(handler.PublishArticleStatus)))).Methods(http.MethodPut)
+	router.HandleFunc("/v1/article/findbyid/{id}", jwtAuth.VerifyToken(canRead.Verify(mayRead.Verify(handler.FindByID)))).Methods(http.MethodGet)
+	router.HandleFunc("/v1/articles", jwtAuth.VerifyToken(canRead.Verify(mayRead.Verify(handler.FindMany)))).Methods(http.MethodGet)
 }
 
 func (handler *AccountHTTPHandler) Save(w http.ResponseWriter, r *http.Request) {
@@ -104,7 +118,14 @@ func (handler *AccountHTTPHandler) Update(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	resp = handler.Usecase.Update(ctx, params)
+	actor, err := getActor(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.Update(ctx, actor, params)
 	resp.JSON(w)
 }
 
@@ -121,7 +142,15 @@ func (handler *AccountHTTPHandler) Delete(w http.ResponseWriter, r *http.Request
 		resp.JSON(w)
 		return
 	}
-	resp = handler.Usecase.Delete(ctx, id)
+
+	actor, err := getActor(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.Delete(ctx, actor, id)
 	resp.JSON(w)
 }
 
@@ -138,7 +167,15 @@ func (handler *AccountHTTPHandler) PublishArticleStatus(w http.ResponseWriter, r
 		resp.JSON(w)
 		return
 	}
-	resp = handler.Usecase.PublishArticleStatus(ctx, id)
+
+	actor, err := getActor(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.PublishArticleStatus(ctx, actor, id)
 	resp.JSON(w)
 }
 
@@ -156,3 +193,96 @@ func (handler *AccountHTTPHandler) FindByID(w http.ResponseWriter, r *http.Reque
 	resp = handler.Usecase.FindByID(ctx, id)
 	resp.JSON(w)
 }
+
+func (handler *AccountHTTPHandler) FindMany(w http.ResponseWriter, r *http.Request) {
+	var resp response.Response
+	var ctx = r.Context()
+
+	params, err := parseListArticleRequest(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	actor, err := getActor(r)
+	if err != nil {
+		resp = response.Error(response.StatusInvalidPayload, nil, err)
+		resp.JSON(w)
+		return
+	}
+
+	resp = handler.Usecase.FindMany(ctx, actor.ID, params)
+	resp.JSON(w)
+}
+
+// getActor returns the account bound to the request by the jwt middleware, along with the permissions
+// the authz middleware resolved.
+func getActor(r *http.Request) (actor Actor, err error) {
+	var claims entity.AccountStandardJWTClaims
+	bind, ok := context.Get(r, "bind").([]byte)
+	if !ok {
+		err = fmt.Errorf("Error Bind Value")
+		return
+	}
+
+	err = json.Unmarshal(bind, &claims)
+	if err != nil {
+		return
+	}
+
+	actor.ID, err = strconv.ParseInt(claims.StandardClaims.Subject, 10, 64)
+	if err != nil {
+		return
+	}
+	actor.Permissions = claims.Permissions
+
+	return
+}
+
+func parseListArticleRequest(r *http.Request) (params ListArticleRequest, err error) {
+	query := r.URL.Query()
+
+	params.Status = ArticleStatus(strings.ToUpper(query.Get("status")))
+	params.Sort = ArticleSortOrder(strings.ToLower(query.Get("sort")))
+	params.Cursor = query.Get("cursor")
+
+	if v := query.Get("authorId"); v != "" {
+		if params.AuthorID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if params.Limit, err = strconv.Atoi(v); err != nil {
+			return
+		}
+	}
+
+	dates := map[string]**time.Time{
+		"createdFrom":   &params.CreatedFrom,
+		"createdTo":     &params.CreatedTo,
+		"publishedFrom": &params.PublishedFrom,
+		"publishedTo":   &params.PublishedTo,
+	}
+	for name, target := range dates {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return params, fmt.Errorf("%s must be an RFC3339 time", name)
+		}
+		*target = &t
+	}
+
+	return
+}
@@ -0,0 +1,59 @@
+package article
+
+import (
+	"github.com/sangianpatrick/devoria-article-service/domain/authz"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+)
+
+// Actor is the authenticated account performing an action on articles, Verified is read from its stored account.
+type Actor struct {
+	ID          int64
+	Permissions []string
+	Verified    bool
+}
+
+// ArticlePolicy decides whether the actor is allowed to act on the article.
+type ArticlePolicy func(actor Actor, article Article) (allowed bool)
+
+// Policies of article mutations, the articles of other authors need a permission granted to the role.
+var (
+	CanUpdateArticle  ArticlePolicy = anyOf(isAuthor, hasPermission(entity.PermissionArticlesWriteAny))
+	CanPublishArticle ArticlePolicy = anyOf(isAuthor, hasPermission(entity.PermissionArticlesPublishAny))
+	CanDeleteArticle  ArticlePolicy = anyOf(isAuthor, hasPermission(entity.PermissionArticlesDeleteAny))
+)
+
+func isAuthor(actor Actor, article Article) (allowed bool) {
+	return actor.ID != 0 && actor.ID == article.Author.ID
+}
+
+func isVerified(actor Actor, article Article) (allowed bool) {
+	return actor.Verified
+}
+
+func hasPermission(permission string) ArticlePolicy {
+	return func(actor Actor, article Article) (allowed bool) {
+		return authz.HasPermission(actor.Permissions, permission)
+	}
+}
+
+func anyOf(policies ...ArticlePolicy) ArticlePolicy {
+	return func(actor Actor, article Article) (allowed bool) {
+		for _, policy := range policies {
+			if policy(actor, article) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func allOf(policies ...ArticlePolicy) ArticlePolicy {
+	return func(actor Actor, article Article) (allowed bool) {
+		for _, policy := range policies {
+			if !policy(actor, article) {
+				return false
+			}
+		}
+		return true
+	}
+}
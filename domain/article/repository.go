@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/sangianpatrick/devoria-article-service/exception"
@@ -16,8 +17,10 @@ type ArticleRepository interface {
 	Delete(ctx context.Context, ID int64) (err error)
 	SetArticleStatus(ctx context.Context, ID int64, status string) (err error)
 	FindByID(ctx context.Context, ID int64) (article Article, err error)
-	// FindMany(ctx context.Context) (bunchOfArticles []Article, err error)
-	// FindManySpecificProfile(ctx context.Context, articleID int64) (bunchOfArticles []Article, err error)
+	FindMany(ctx context.Context, filter ArticleFilter) (bunchOfArticles []Article, err error)
+	FindByAuthorID(ctx context.Context, authorID int64) (bunchOfArticles []Article, err error)
+	DeleteByAuthorID(ctx context.Context, authorID int64) (err error)
+	AnonymiseByAuthorID(ctx context.Context, authorID int64) (err error)
 }
 
 type articleRepositoryImpl struct {
@@ -154,9 +157,11 @@ func (r *articleRepositoryImpl) FindByID(ctx context.Context, ID int64) (article
 
 	row := stmt.QueryRowContext(ctx, ID)
 
+	var authorID sql.NullInt64
+
 	err = row.Scan(
 		&article.ID,
-		&article.Author.ID,
+		&authorID,
 		&article.Title,
 		&article.Subtitle,
 		&article.Content,
@@ -172,5 +177,197 @@ func (r *articleRepositoryImpl) FindByID(ctx context.Context, ID int64) (article
 		return
 	}
 
+	article.Author.ID = authorID.Int64
+
+	return
+}
+
+func (r *articleRepositoryImpl) FindMany(ctx context.Context, filter ArticleFilter) (bunchOfArticles []Article, err error) {
+	sortedAt := "COALESCE(publishedAt, createdAt)"
+	conditions := []string{}
+	args := []interface{}{}
+
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.AuthorID != 0 {
+		conditions = append(conditions, "authorId = ?")
+		args = append(args, filter.AuthorID)
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "createdAt >= ?")
+		args = append(args, *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "createdAt <= ?")
+		args = append(args, *filter.CreatedTo)
+	}
+	if filter.PublishedFrom != nil {
+		conditions = append(conditions, "publishedAt >= ?")
+		args = append(args, *filter.PublishedFrom)
+	}
+	if filter.PublishedTo != nil {
+		conditions = append(conditions, "publishedAt <= ?")
+		args = append(args, *filter.PublishedTo)
+	}
+
+	direction, comparator := "DESC", "<"
+	if filter.SortOrder == ArticleSortOrderAsc {
+		direction, comparator = "ASC", ">"
+	}
+
+	if filter.Cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sortedAt, comparator))
+		args = append(args, filter.Cursor.SortedAt, filter.Cursor.SortedAt, filter.Cursor.ID)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`SELECT id, authorId, title, subtitle, content, status, createdAt, publishedAt, lastModifiedAt 
+	FROM %s %s ORDER BY %s %s, id %s LIMIT ?`, r.tableName, where, sortedAt, direction, direction)
+	args = append(args, filter.Limit)
+
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var article Article
+		var authorID sql.NullInt64
+		err = rows.Scan(
+			&article.ID,
+			&authorID,
+			&article.Title,
+			&article.Subtitle,
+			&article.Content,
+			&article.Status,
+			&article.CreatedAt,
+			&article.PublishedAt,
+			&article.LastModifiedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+
+		article.Author.ID = authorID.Int64
+		bunchOfArticles = append(bunchOfArticles, article)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	return
+}
+
+// FindByAuthorID returns every article of the author whatever its status, the oldest first.
+func (r *articleRepositoryImpl) FindByAuthorID(ctx context.Context, authorID int64) (bunchOfArticles []Article, err error) {
+	query := fmt.Sprintf(`SELECT id, title, subtitle, content, status, createdAt, publishedAt, lastModifiedAt 
+	FROM %s WHERE authorId = ? ORDER BY createdAt ASC, id ASC`, r.tableName)
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, authorID)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var article Article
+		err = rows.Scan(
+			&article.ID,
+			&article.Title,
+			&article.Subtitle,
+			&article.Content,
+			&article.Status,
+			&article.CreatedAt,
+			&article.PublishedAt,
+			&article.LastModifiedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+
+		article.Author.ID = authorID
+		bunchOfArticles = append(bunchOfArticles, article)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	return
+}
+
+func (r *articleRepositoryImpl) DeleteByAuthorID(ctx context.Context, authorID int64) (err error) {
+	command := fmt.Sprintf(`DELETE FROM %s WHERE authorId = ?`, r.tableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, authorID)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	return
+}
+
+// AnonymiseByAuthorID keeps the articles of the author but detaches them from it.
+func (r *articleRepositoryImpl) AnonymiseByAuthorID(ctx context.Context, authorID int64) (err error) {
+	command := fmt.Sprintf(`UPDATE %s SET authorId = NULL WHERE authorId = ?`, r.tableName)
+	stmt, err := r.db.PrepareContext(ctx, command)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, authorID)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
 	return
 }
@@ -1,5 +1,7 @@
 package article
 
+import "time"
+
 // CreateArticleRequest is model for creating article.
 type CreateArticleRequest struct {
 	Title    string `json:"title"`
@@ -21,3 +23,16 @@ type EditArticleRequest struct {
 	Subtitle string `json:"subtitle"`
 	Content  string `json:"content"`
 }
+
+// ListArticleRequest is model for listing articles.
+type ListArticleRequest struct {
+	Status        ArticleStatus `validate:"omitempty,oneof=DRAFT PUBLISHED"`
+	AuthorID      int64         `validate:"gte=0"`
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	PublishedFrom *time.Time
+	PublishedTo   *time.Time
+	Sort          ArticleSortOrder `validate:"omitempty,oneof=asc desc"`
+	Cursor        string
+	Limit         int `validate:"omitempty,min=1,max=100"`
+}
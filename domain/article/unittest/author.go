@@ -0,0 +1,17 @@
+package unittest
+
+import (
+	"context"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/account"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuthorRepository struct {
+	mock.Mock
+}
+
+func (d *MockAuthorRepository) FindByID(ctx context.Context, ID int64) (foundAccount account.Account, err error) {
+	args := d.Called(ctx, ID)
+	return args.Get(0).(account.Account), args.Error(1)
+}
@@ -35,3 +35,23 @@ func (d *MockNewArticleRepository) FindByID(ctx context.Context, ID int64) (arti
 	args := d.Called(ctx, ID)
 	return args.Get(0).(article.Article), args.Error(1)
 }
+
+func (d *MockNewArticleRepository) FindMany(ctx context.Context, filter article.ArticleFilter) (bunchOfArticles []article.Article, err error) {
+	args := d.Called(ctx, filter)
+	return args.Get(0).([]article.Article), args.Error(1)
+}
+
+func (d *MockNewArticleRepository) FindByAuthorID(ctx context.Context, authorID int64) (bunchOfArticles []article.Article, err error) {
+	args := d.Called(ctx, authorID)
+	return args.Get(0).([]article.Article), args.Error(1)
+}
+
+func (d *MockNewArticleRepository) DeleteByAuthorID(ctx context.Context, authorID int64) (err error) {
+	args := d.Called(ctx, authorID)
+	return args.Error(0)
+}
+
+func (d *MockNewArticleRepository) AnonymiseByAuthorID(ctx context.Context, authorID int64) (err error) {
+	args := d.Called(ctx, authorID)
+	return args.Error(0)
+}
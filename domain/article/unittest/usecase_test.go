@@ -2,12 +2,16 @@ package unittest
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"log"
 	"testing"
 	"time"
 
 	"github.com/sangianpatrick/devoria-article-service/domain/account"
 	"github.com/sangianpatrick/devoria-article-service/domain/article"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
 	"github.com/sangianpatrick/devoria-article-service/response"
 	"github.com/stretchr/testify/assert"
 )
@@ -61,7 +65,7 @@ func TestSave(t *testing.T) {
 		Subtitle: "Indonesia",
 		Content:  "Animasi",
 	}).Return(13, nil)
-	articleUsecase := article.NewArticleUsecase(nil, location, articleRepository)
+	articleUsecase := article.NewArticleUsecase(nil, false, location, nil, articleRepository)
 	resp = articleUsecase.Save(ctx, 0, request)
 	log.Println(resp)
 
@@ -80,14 +84,15 @@ func TestUpdate(t *testing.T) {
 
 	var resp response.Response
 
+	articleRepository.On("FindByID", ctx, int64(1)).Return(article.Article{ID: 1, Author: account.Account{ID: 14}}, nil)
 	articleRepository.On("Update", ctx, article.Article{
 		ID:       1,
 		Title:    "title1",
 		Subtitle: "Indonesia",
 		Content:  "Animasi",
 	}).Return(nil)
-	articleUsecase := article.NewArticleUsecase(nil, location, articleRepository)
-	resp = articleUsecase.Update(ctx, article.UpdateArticleRequest{
+	articleUsecase := article.NewArticleUsecase(nil, false, location, nil, articleRepository)
+	resp = articleUsecase.Update(ctx, article.Actor{ID: 14}, article.UpdateArticleRequest{
 		ID:       1,
 		Title:    "title1",
 		Subtitle: "Indonesia",
@@ -109,9 +114,10 @@ func TestDelete(t *testing.T) {
 
 	var resp response.Response
 
+	articleRepository.On("FindByID", ctx, int64(1)).Return(article.Article{ID: 1, Author: account.Account{ID: 14}}, nil)
 	articleRepository.On("Delete", ctx, int64(1)).Return(nil)
-	articleUsecase := article.NewArticleUsecase(nil, location, articleRepository)
-	resp = articleUsecase.Delete(ctx, int64(1))
+	articleUsecase := article.NewArticleUsecase(nil, false, location, nil, articleRepository)
+	resp = articleUsecase.Delete(ctx, article.Actor{ID: 14}, int64(1))
 	assert.Equal(t, resp, response.Success(response.StatusOK, nil))
 }
 
@@ -122,12 +128,50 @@ func TestSetArticleStatus(t *testing.T) {
 
 	var resp response.Response
 
+	articleRepository.On("FindByID", ctx, int64(1)).Return(article.Article{ID: 1, Author: account.Account{ID: 14}}, nil)
 	articleRepository.On("SetArticleStatus", ctx, int64(1), "published").Return(nil)
-	articleUsecase := article.NewArticleUsecase(nil, location, articleRepository)
-	resp = articleUsecase.PublishArticleStatus(ctx, int64(1))
+	articleUsecase := article.NewArticleUsecase(nil, false, location, nil, articleRepository)
+	resp = articleUsecase.PublishArticleStatus(ctx, article.Actor{ID: 20, Permissions: []string{entity.PermissionArticlesPublishAny}}, int64(1))
 	assert.Equal(t, resp, response.Success(response.StatusOK, nil))
 }
 
+func TestPublishArticleStatusUnverified(t *testing.T) {
+	location, _ := time.LoadLocation("Asia/Jakarta")
+	authorRepository := new(MockAuthorRepository)
+	articleRepository := new(MockNewArticleRepository)
+	ctx := context.Background()
+
+	articleRepository.On("FindByID", ctx, int64(1)).Return(article.Article{ID: 1, Author: account.Account{ID: 14}}, nil)
+	authorRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14}, nil).Once()
+	articleUsecase := article.NewArticleUsecase(nil, true, location, authorRepository, articleRepository)
+
+	resp := articleUsecase.PublishArticleStatus(ctx, article.Actor{ID: 14}, int64(1))
+	assert.Equal(t, resp, response.Error(response.StatusForbiddend, nil, exception.ErrForbidden))
+	articleRepository.AssertNotCalled(t, "SetArticleStatus", ctx, int64(1), "published")
+
+	// verified since the token was issued, the stored account is what counts.
+	verifiedAt := time.Now()
+	authorRepository.On("FindByID", ctx, int64(14)).Return(account.Account{ID: 14, VerifiedAt: &verifiedAt}, nil).Once()
+	articleRepository.On("SetArticleStatus", ctx, int64(1), "published").Return(nil)
+	resp = articleUsecase.PublishArticleStatus(ctx, article.Actor{ID: 14}, int64(1))
+	assert.Equal(t, resp, response.Success(response.StatusOK, nil))
+	authorRepository.AssertExpectations(t)
+}
+
+func TestDeleteForbidden(t *testing.T) {
+	location, _ := time.LoadLocation("Asia/Jakarta")
+	articleRepository := new(MockNewArticleRepository)
+	ctx := context.Background()
+
+	var resp response.Response
+
+	articleRepository.On("FindByID", ctx, int64(1)).Return(article.Article{ID: 1, Author: account.Account{ID: 14}}, nil)
+	articleUsecase := article.NewArticleUsecase(nil, false, location, nil, articleRepository)
+	resp = articleUsecase.Delete(ctx, article.Actor{ID: 20, Permissions: []string{entity.PermissionArticlesWriteAny, entity.PermissionArticlesPublishAny}}, int64(1))
+	assert.Equal(t, resp, response.Error(response.StatusForbiddend, nil, exception.ErrForbidden))
+	articleRepository.AssertNotCalled(t, "Delete", ctx, int64(1))
+}
+
 func TestSFindByID(t *testing.T) {
 	location, _ := time.LoadLocation("Asia/Jakarta")
 	articleRepository := new(MockNewArticleRepository)
@@ -150,7 +194,7 @@ func TestSFindByID(t *testing.T) {
 			ID: 14,
 		},
 	}, nil)
-	articleUsecase := article.NewArticleUsecase(nil, location, articleRepository)
+	articleUsecase := article.NewArticleUsecase(nil, false, location, nil, articleRepository)
 	resp = articleUsecase.FindByID(ctx, int64(1))
 
 	assert.Equal(t, resp, response.Success(response.StatusOK, article.ArticleResponses{
@@ -167,3 +211,33 @@ func TestSFindByID(t *testing.T) {
 		},
 	}))
 }
+
+func TestFindMany(t *testing.T) {
+	location, _ := time.LoadLocation("Asia/Jakarta")
+	articleRepository := new(MockNewArticleRepository)
+	ctx := context.Background()
+
+	var resp response.Response
+
+	createdAt := time.Now().In(location)
+
+	articleRepository.On("FindMany", ctx, article.ArticleFilter{
+		Status:    article.ArticleStatusPublished,
+		SortOrder: article.ArticleSortOrderDesc,
+		Limit:     2,
+	}).Return([]article.Article{
+		{ID: 3, Title: "title3", Status: article.ArticleStatusPublished, CreatedAt: createdAt, Author: account.Account{ID: 14}},
+		{ID: 2, Title: "title2", Status: article.ArticleStatusPublished, CreatedAt: createdAt, Author: account.Account{ID: 14}},
+	}, nil)
+	articleUsecase := article.NewArticleUsecase(nil, false, location, nil, articleRepository)
+	resp = articleUsecase.FindMany(ctx, int64(1), article.ListArticleRequest{Limit: 1})
+
+	nextCursor, _ := json.Marshal(article.ArticleCursor{SortedAt: createdAt, ID: 3})
+
+	assert.Equal(t, resp, response.SuccessWithPagination(response.StatusOK, []article.ArticleResponses{
+		{ID: 3, Title: "title3", Status: article.ArticleStatusPublished, CreatedAt: createdAt, Author: account.Account{ID: 14}},
+	}, response.Pagination{
+		NextCursor: base64.RawURLEncoding.EncodeToString(nextCursor),
+		HasMore:    true,
+	}))
+}
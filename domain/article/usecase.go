@@ -2,6 +2,8 @@ package article
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"time"
 
 	"github.com/sangianpatrick/devoria-article-service/domain/account"
@@ -13,29 +15,51 @@ import (
 
 type ArticleUsecase interface {
 	Save(ctx context.Context, AuthorID int64, request CreateArticleRequest) (resp response.Response)
-	Update(ctx context.Context, request UpdateArticleRequest) (resp response.Response)
-	Delete(ctx context.Context, ID int64) (resp response.Response)
-	PublishArticleStatus(ctx context.Context, articleID int64) (resp response.Response)
+	Update(ctx context.Context, actor Actor, request UpdateArticleRequest) (resp response.Response)
+	Delete(ctx context.Context, actor Actor, ID int64) (resp response.Response)
+	PublishArticleStatus(ctx context.Context, actor Actor, articleID int64) (resp response.Response)
 	FindByID(ctx context.Context, articleID int64) (resp response.Response)
+	FindMany(ctx context.Context, requesterID int64, request ListArticleRequest) (resp response.Response)
+}
+
+const defaultArticleListLimit = 10
+
+// AuthorRepository is the part of the account storage an article needs to know the current state of its actor.
+// It is implemented by the account domain.
+type AuthorRepository interface {
+	FindByID(ctx context.Context, ID int64) (foundAccount account.Account, err error)
 }
 
 type articleUsecaseImpl struct {
-	globalIV     string
-	session      session.Session
-	jsonWebToken jwt.JSONWebToken
-	location     *time.Location
-	repository   ArticleRepository
+	globalIV                      string
+	session                       session.Session
+	jsonWebToken                  jwt.JSONWebToken
+	requireVerifiedEmailToPublish bool
+	publishPolicy                 ArticlePolicy
+	location                      *time.Location
+	authorRepository              AuthorRepository
+	repository                    ArticleRepository
 }
 
 func NewArticleUsecase(
 	session session.Session,
+	requireVerifiedEmailToPublish bool,
 	location *time.Location,
+	authorRepository AuthorRepository,
 	repository ArticleRepository,
 ) ArticleUsecase {
+	publishPolicy := CanPublishArticle
+	if requireVerifiedEmailToPublish {
+		publishPolicy = allOf(isVerified, CanPublishArticle)
+	}
+
 	return &articleUsecaseImpl{
-		session:    session,
-		location:   location,
-		repository: repository,
+		session:                       session,
+		requireVerifiedEmailToPublish: requireVerifiedEmailToPublish,
+		publishPolicy:                 publishPolicy,
+		location:                      location,
+		authorRepository:              authorRepository,
+		repository:                    repository,
 	}
 }
 
@@ -60,7 +84,11 @@ func (u *articleUsecaseImpl) Save(ctx context.Context, AuthorID int64, article C
 	})
 }
 
-func (u *articleUsecaseImpl) Update(ctx context.Context, article UpdateArticleRequest) (resp response.Response) {
+func (u *articleUsecaseImpl) Update(ctx context.Context, actor Actor, article UpdateArticleRequest) (resp response.Response) {
+	if resp = u.authorize(ctx, actor, article.ID, CanUpdateArticle); resp != nil {
+		return
+	}
+
 	err := u.repository.Update(ctx, Article{
 		ID:       article.ID,
 		Title:    article.Title,
@@ -79,7 +107,11 @@ func (u *articleUsecaseImpl) Update(ctx context.Context, article UpdateArticleRe
 	})
 }
 
-func (u *articleUsecaseImpl) Delete(ctx context.Context, ID int64) (resp response.Response) {
+func (u *articleUsecaseImpl) Delete(ctx context.Context, actor Actor, ID int64) (resp response.Response) {
+	if resp = u.authorize(ctx, actor, ID, CanDeleteArticle); resp != nil {
+		return
+	}
+
 	err := u.repository.Delete(ctx, ID)
 	if err != nil {
 		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
@@ -88,7 +120,17 @@ func (u *articleUsecaseImpl) Delete(ctx context.Context, ID int64) (resp respons
 	return response.Success(response.StatusOK, nil)
 }
 
-func (u *articleUsecaseImpl) PublishArticleStatus(ctx context.Context, articleID int64) (resp response.Response) {
+func (u *articleUsecaseImpl) PublishArticleStatus(ctx context.Context, actor Actor, articleID int64) (resp response.Response) {
+	if u.requireVerifiedEmailToPublish {
+		if actor, resp = u.withVerification(ctx, actor); resp != nil {
+			return
+		}
+	}
+
+	if resp = u.authorize(ctx, actor, articleID, u.publishPolicy); resp != nil {
+		return
+	}
+
 	err := u.repository.SetArticleStatus(ctx, articleID, "published")
 	if err != nil {
 		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
@@ -96,6 +138,39 @@ func (u *articleUsecaseImpl) PublishArticleStatus(ctx context.Context, articleID
 	return response.Success(response.StatusOK, nil)
 }
 
+// authorize evaluates the policy against the actor and the stored article.
+// It returns nil when the action is allowed, otherwise the response to send back.
+func (u *articleUsecaseImpl) authorize(ctx context.Context, actor Actor, articleID int64, policy ArticlePolicy) (resp response.Response) {
+	article, err := u.repository.FindByID(ctx, articleID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return response.Error(response.StatusNotFound, nil, exception.ErrNotFound)
+		}
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	if !policy(actor, article) {
+		return response.Error(response.StatusForbiddend, nil, exception.ErrForbidden)
+	}
+
+	return nil
+}
+
+// withVerification tells whether the actor verified its email from the stored account, the claim of the token
+// stays false until the token is renewed.
+func (u *articleUsecaseImpl) withVerification(ctx context.Context, actor Actor) (verifiedActor Actor, resp response.Response) {
+	author, err := u.authorRepository.FindByID(ctx, actor.ID)
+	if err != nil {
+		if err == exception.ErrNotFound {
+			return actor, response.Error(response.StatusForbiddend, nil, exception.ErrForbidden)
+		}
+		return actor, response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	actor.Verified = author.VerifiedAt != nil
+	return actor, nil
+}
+
 func (u *articleUsecaseImpl) FindByID(ctx context.Context, articleID int64) (resp response.Response) {
 	article, err := u.repository.FindByID(ctx, articleID)
 	if err != nil {
@@ -115,3 +190,92 @@ func (u *articleUsecaseImpl) FindByID(ctx context.Context, articleID int64) (res
 		},
 	})
 }
+
+// FindMany lists articles page by page. Drafts are only listed when the requester asks for their own articles.
+func (u *articleUsecaseImpl) FindMany(ctx context.Context, requesterID int64, request ListArticleRequest) (resp response.Response) {
+	filter := ArticleFilter{
+		Status:        request.Status,
+		AuthorID:      request.AuthorID,
+		CreatedFrom:   request.CreatedFrom,
+		CreatedTo:     request.CreatedTo,
+		PublishedFrom: request.PublishedFrom,
+		PublishedTo:   request.PublishedTo,
+		SortOrder:     request.Sort,
+		Limit:         request.Limit,
+	}
+
+	if filter.AuthorID != requesterID {
+		filter.Status = ArticleStatusPublished
+	}
+	if filter.SortOrder == "" {
+		filter.SortOrder = ArticleSortOrderDesc
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultArticleListLimit
+	}
+
+	if request.Cursor != "" {
+		cursor, err := decodeArticleCursor(request.Cursor)
+		if err != nil {
+			return response.Error(response.StatusInvalidPayload, nil, exception.ErrBadRequest)
+		}
+		filter.Cursor = &cursor
+	}
+
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	bunchOfArticles, err := u.repository.FindMany(ctx, filter)
+	if err != nil {
+		return response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+	}
+
+	pagination := response.Pagination{}
+	if len(bunchOfArticles) > limit {
+		bunchOfArticles = bunchOfArticles[:limit]
+		pagination.HasMore = true
+		pagination.NextCursor = encodeArticleCursor(bunchOfArticles[limit-1])
+	}
+
+	bunchOfArticleResponses := make([]ArticleResponses, 0, len(bunchOfArticles))
+	for _, article := range bunchOfArticles {
+		bunchOfArticleResponses = append(bunchOfArticleResponses, ArticleResponses{
+			ID:             article.ID,
+			Title:          article.Title,
+			Subtitle:       article.Subtitle,
+			Content:        article.Content,
+			Status:         article.Status,
+			CreatedAt:      article.CreatedAt,
+			PublishedAt:    article.PublishedAt,
+			LastModifiedAt: article.LastModifiedAt,
+			Author: account.Account{
+				ID: article.Author.ID,
+			},
+		})
+	}
+
+	return response.SuccessWithPagination(response.StatusOK, bunchOfArticleResponses, pagination)
+}
+
+func encodeArticleCursor(article Article) string {
+	cursor := ArticleCursor{
+		SortedAt: article.CreatedAt,
+		ID:       article.ID,
+	}
+	if article.PublishedAt != nil {
+		cursor.SortedAt = *article.PublishedAt
+	}
+
+	buff, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(buff)
+}
+
+func decodeArticleCursor(encoded string) (cursor ArticleCursor, err error) {
+	buff, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(buff, &cursor)
+	return
+}
@@ -0,0 +1,66 @@
+package authz
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/middleware"
+)
+
+// Authorizer is collection of behavior of role based access control.
+type Authorizer interface {
+	Permissions(ctx context.Context, role string) (permissions []string, err error)
+	Require(permission string) middleware.RouteMiddleware
+}
+
+type cachedPermissions struct {
+	permissions []string
+	expiresAt   time.Time
+}
+
+type authorizerImpl struct {
+	cacheTTL   time.Duration
+	repository AuthzRepository
+
+	mutex sync.Mutex
+	cache map[string]cachedPermissions
+}
+
+// NewAuthorizer is a constructor, the permissions of a role are read from the repository at most once per cache ttl.
+func NewAuthorizer(cacheTTL time.Duration, repository AuthzRepository) Authorizer {
+	return &authorizerImpl{
+		cacheTTL:   cacheTTL,
+		repository: repository,
+		cache:      map[string]cachedPermissions{},
+	}
+}
+
+// Permissions returns the permissions granted to the role.
+func (a *authorizerImpl) Permissions(ctx context.Context, role string) (permissions []string, err error) {
+	now := time.Now()
+
+	a.mutex.Lock()
+	cached, ok := a.cache[role]
+	a.mutex.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.permissions, nil
+	}
+
+	permissions, err = a.repository.FindPermissionsByRole(ctx, role)
+	if err != nil {
+		return
+	}
+
+	a.mutex.Lock()
+	a.cache[role] = cachedPermissions{permissions: permissions, expiresAt: now.Add(a.cacheTTL)}
+	a.mutex.Unlock()
+
+	return
+}
+
+// Require returns a middleware letting the request through when its claims hold the permission.
+// It must run behind the jwt middleware, which binds the claims.
+func (a *authorizerImpl) Require(permission string) middleware.RouteMiddleware {
+	return &requirement{authorizer: a, permission: permission}
+}
@@ -0,0 +1,30 @@
+package authz
+
+import "github.com/sangianpatrick/devoria-article-service/entity"
+
+// DefaultRolePermissions are the grants seeded into an empty database, afterwards they are managed in the database.
+var DefaultRolePermissions = map[string][]string{
+	entity.RoleUser: {
+		entity.PermissionArticlesRead,
+		entity.PermissionArticlesWrite,
+		entity.PermissionArticlesPublish,
+	},
+	entity.RoleEditor: {
+		entity.PermissionArticlesRead,
+		entity.PermissionArticlesWrite,
+		entity.PermissionArticlesPublish,
+		entity.PermissionArticlesWriteAny,
+		entity.PermissionArticlesPublishAny,
+	},
+	entity.RoleAdmin: {
+		entity.PermissionArticlesRead,
+		entity.PermissionArticlesWrite,
+		entity.PermissionArticlesPublish,
+		entity.PermissionArticlesWriteAny,
+		entity.PermissionArticlesPublishAny,
+		entity.PermissionArticlesDeleteAny,
+		entity.PermissionAccountsRead,
+		entity.PermissionAccountsManage,
+		entity.PermissionRolesAssign,
+	},
+}
@@ -0,0 +1,50 @@
+package authz
+
+import (
+	"context"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+)
+
+type permissionJSONWebToken struct {
+	jwt.JSONWebToken
+	authorizer Authorizer
+}
+
+// NewJSONWebToken is a constructor, account claims are signed along with the permissions of their role.
+// A change of the grants reaches a token when it is renewed.
+func NewJSONWebToken(jsonWebToken jwt.JSONWebToken, authorizer Authorizer) jwt.JSONWebToken {
+	return &permissionJSONWebToken{jsonWebToken, authorizer}
+}
+
+// Sign embeds the permissions of the role into account claims that carry none, other claims are signed as they are.
+func (t *permissionJSONWebToken) Sign(ctx context.Context, claims jwtgo.Claims) (tokenString string, err error) {
+	switch accountClaims := claims.(type) {
+	case entity.AccountStandardJWTClaims:
+		if err = t.embedPermissions(ctx, &accountClaims); err != nil {
+			return
+		}
+		claims = accountClaims
+	case *entity.AccountStandardJWTClaims:
+		stamped := *accountClaims
+		if err = t.embedPermissions(ctx, &stamped); err != nil {
+			return
+		}
+		claims = &stamped
+	}
+
+	return t.JSONWebToken.Sign(ctx, claims)
+}
+
+func (t *permissionJSONWebToken) embedPermissions(ctx context.Context, claims *entity.AccountStandardJWTClaims) (err error) {
+	if claims.Role == "" || claims.Permissions != nil {
+		return
+	}
+
+	claims.Permissions, err = t.authorizer.Permissions(ctx, claims.Role)
+
+	return
+}
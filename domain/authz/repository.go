@@ -0,0 +1,154 @@
+package authz
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/exception"
+)
+
+type AuthzRepository interface {
+	FindPermissionsByRole(ctx context.Context, role string) (permissions []string, err error)
+	RoleExists(ctx context.Context, role string) (exists bool, err error)
+	Seed(ctx context.Context, rolePermissions map[string][]string, createdAt time.Time) (err error)
+}
+
+type authzRepositoryImpl struct {
+	db                      *sql.DB
+	roleTableName           string
+	permissionTableName     string
+	rolePermissionTableName string
+}
+
+func NewAuthzRepository(db *sql.DB, roleTableName string, permissionTableName string) AuthzRepository {
+	return &authzRepositoryImpl{
+		db:                      db,
+		roleTableName:           roleTableName,
+		permissionTableName:     permissionTableName,
+		rolePermissionTableName: fmt.Sprintf("%s_%s", roleTableName, permissionTableName),
+	}
+}
+
+// FindPermissionsByRole returns the permissions granted to the role, an unknown role has none.
+func (r *authzRepositoryImpl) FindPermissionsByRole(ctx context.Context, role string) (permissions []string, err error) {
+	query := fmt.Sprintf(
+		`SELECT p.name FROM %s r JOIN %s rp ON rp.roleId = r.id JOIN %s p ON p.id = rp.permissionId WHERE r.name = ? ORDER BY p.name`,
+		r.roleTableName, r.rolePermissionTableName, r.permissionTableName,
+	)
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, role)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer rows.Close()
+
+	permissions = []string{}
+	for rows.Next() {
+		var permission string
+		if err = rows.Scan(&permission); err != nil {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	return
+}
+
+// RoleExists tells whether the role is stored.
+func (r *authzRepositoryImpl) RoleExists(ctx context.Context, role string) (exists bool, err error) {
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE name = ?)`, r.roleTableName)
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer stmt.Close()
+
+	if err = stmt.QueryRowContext(ctx, role).Scan(&exists); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+	}
+
+	return
+}
+
+// Seed inserts the roles, the permissions and their grants when no role is stored yet. Once there is one the
+// grants are managed in the database, so a grant removed there is never seeded again.
+func (r *authzRepositoryImpl) Seed(ctx context.Context, rolePermissions map[string][]string, createdAt time.Time) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+	defer tx.Rollback()
+
+	// the lock keeps instances starting together from seeding twice.
+	var roleID int64
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT id FROM %s LIMIT 1 FOR UPDATE`, r.roleTableName)).Scan(&roleID)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		log.Println(err)
+		err = exception.ErrInternalServer
+		return
+	}
+
+	insertRole := fmt.Sprintf(`INSERT IGNORE INTO %s (name, createdAt) VALUES (?, ?)`, r.roleTableName)
+	insertPermission := fmt.Sprintf(`INSERT IGNORE INTO %s (name, createdAt) VALUES (?, ?)`, r.permissionTableName)
+	insertGrant := fmt.Sprintf(
+		`INSERT IGNORE INTO %s (roleId, permissionId) SELECT r.id, p.id FROM %s r, %s p WHERE r.name = ? AND p.name = ?`,
+		r.rolePermissionTableName, r.roleTableName, r.permissionTableName,
+	)
+
+	for role, permissions := range rolePermissions {
+		if _, err = tx.ExecContext(ctx, insertRole, role, createdAt); err != nil {
+			log.Println(err)
+			err = exception.ErrInternalServer
+			return
+		}
+
+		for _, permission := range permissions {
+			if _, err = tx.ExecContext(ctx, insertPermission, permission, createdAt); err != nil {
+				log.Println(err)
+				err = exception.ErrInternalServer
+				return
+			}
+			if _, err = tx.ExecContext(ctx, insertGrant, role, permission); err != nil {
+				log.Println(err)
+				err = exception.ErrInternalServer
+				return
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Println(err)
+		err = exception.ErrInternalServer
+	}
+
+	return
+}
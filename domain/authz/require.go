@@ -0,0 +1,65 @@
+package authz
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/context"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/response"
+)
+
+type requirement struct {
+	authorizer Authorizer
+	permission string
+}
+
+// Verify will verify the request to ensure its claims hold the permission. Claims without permissions,
+// those of an api key or of a token signed before permissions were embedded, are checked against their role
+// and bound again along with the permissions, so the handler reads them from the claims either way.
+func (m *requirement) Verify(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var claims entity.AccountStandardJWTClaims
+		bind, ok := context.Get(r, "bind").([]byte)
+		if !ok || json.Unmarshal(bind, &claims) != nil {
+			resp := response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+			resp.JSON(w)
+			return
+		}
+
+		permissions := claims.Permissions
+		if len(permissions) == 0 {
+			var err error
+			permissions, err = m.authorizer.Permissions(r.Context(), claims.Role)
+			if err != nil {
+				resp := response.Error(response.StatusUnexpectedError, nil, exception.ErrInternalServer)
+				resp.JSON(w)
+				return
+			}
+
+			claims.Permissions = permissions
+			bind, _ = json.Marshal(claims)
+			context.Set(r, "bind", bind)
+		}
+
+		if HasPermission(permissions, m.permission) {
+			next(w, r)
+			return
+		}
+
+		resp := response.Error(response.StatusForbiddend, nil, exception.ErrForbidden)
+		resp.JSON(w)
+	})
+}
+
+// HasPermission tells whether the permissions hold the permission.
+func HasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,85 @@
+package unittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/authz"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+)
+
+// serveRequired binds the claims like the jwt middleware would and runs the request through the requirement.
+func serveRequired(authorizer authz.Authorizer, permission string, claims *entity.AccountStandardJWTClaims) (recorder *httptest.ResponseRecorder, called bool) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	if claims != nil {
+		bind, _ := json.Marshal(claims)
+		context.Set(request, "bind", bind)
+	}
+
+	recorder = httptest.NewRecorder()
+	authorizer.Require(permission).Verify(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})(recorder, request)
+
+	return
+}
+
+func TestAuthorizer_Require(t *testing.T) {
+	authzRepository := new(MockAuthzRepository)
+	authorizer := authz.NewAuthorizer(time.Minute, authzRepository)
+
+	claims := entity.AccountStandardJWTClaims{Role: entity.RoleUser, Permissions: []string{entity.PermissionArticlesPublish}}
+	recorder, called := serveRequired(authorizer, entity.PermissionArticlesPublish, &claims)
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder, called = serveRequired(authorizer, entity.PermissionAccountsManage, &claims)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+	recorder, called = serveRequired(authorizer, entity.PermissionArticlesPublish, nil)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	authzRepository.AssertNotCalled(t, "FindPermissionsByRole", mock.Anything, mock.Anything)
+}
+
+func TestAuthorizer_Require_ClaimsWithoutPermissions(t *testing.T) {
+	authzRepository := new(MockAuthzRepository)
+	authzRepository.On("FindPermissionsByRole", mock.Anything, entity.RoleAdmin).Return([]string{entity.PermissionAccountsManage}, nil)
+	authzRepository.On("FindPermissionsByRole", mock.Anything, "ghost").Return([]string{}, exception.ErrInternalServer)
+
+	authorizer := authz.NewAuthorizer(time.Minute, authzRepository)
+
+	recorder, called := serveRequired(authorizer, entity.PermissionAccountsManage, &entity.AccountStandardJWTClaims{Role: entity.RoleAdmin})
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder, called = serveRequired(authorizer, entity.PermissionAccountsManage, &entity.AccountStandardJWTClaims{Role: "ghost"})
+	assert.False(t, called)
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestAuthorizer_Require_BindsResolvedPermissions(t *testing.T) {
+	authzRepository := new(MockAuthzRepository)
+	authzRepository.On("FindPermissionsByRole", mock.Anything, entity.RoleEditor).Return([]string{entity.PermissionArticlesWrite, entity.PermissionArticlesWriteAny}, nil)
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	bind, _ := json.Marshal(entity.AccountStandardJWTClaims{Role: entity.RoleEditor})
+	context.Set(request, "bind", bind)
+
+	var claims entity.AccountStandardJWTClaims
+	authz.NewAuthorizer(time.Minute, authzRepository).Require(entity.PermissionArticlesWrite).Verify(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.Unmarshal(context.Get(r, "bind").([]byte), &claims))
+	})(httptest.NewRecorder(), request)
+
+	assert.Equal(t, []string{entity.PermissionArticlesWrite, entity.PermissionArticlesWriteAny}, claims.Permissions)
+}
@@ -0,0 +1,22 @@
+package unittest
+
+import (
+	"context"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockJSONWebToken struct {
+	mock.Mock
+}
+
+func (d *MockJSONWebToken) Sign(ctx context.Context, claims jwt.Claims) (tokenString string, err error) {
+	args := d.Called(ctx, claims)
+	return args.String(0), args.Error(1)
+}
+
+func (d *MockJSONWebToken) Parse(ctx context.Context, tokenString string, claims jwt.Claims) (err error) {
+	args := d.Called(ctx, tokenString, claims)
+	return args.Error(0)
+}
@@ -0,0 +1,45 @@
+package unittest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/authz"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+)
+
+func TestAuthorizer_Permissions_Cached(t *testing.T) {
+	authzRepository := new(MockAuthzRepository)
+	authzRepository.On("FindPermissionsByRole", mock.Anything, entity.RoleEditor).Return([]string{entity.PermissionArticlesRead}, nil).Once()
+
+	authorizer := authz.NewAuthorizer(time.Minute, authzRepository)
+	for i := 0; i < 3; i++ {
+		permissions, err := authorizer.Permissions(context.Background(), entity.RoleEditor)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{entity.PermissionArticlesRead}, permissions)
+	}
+
+	authzRepository.AssertNumberOfCalls(t, "FindPermissionsByRole", 1)
+}
+
+func TestJSONWebToken_Sign_EmbedsPermissions(t *testing.T) {
+	authzRepository := new(MockAuthzRepository)
+	authzRepository.On("FindPermissionsByRole", mock.Anything, entity.RoleEditor).Return([]string{entity.PermissionArticlesRead, entity.PermissionArticlesWrite}, nil)
+	jsonWebToken := new(MockJSONWebToken)
+	jsonWebToken.On("Sign", mock.Anything, entity.AccountStandardJWTClaims{
+		Email:       "johndoe@mail.com",
+		Role:        entity.RoleEditor,
+		Permissions: []string{entity.PermissionArticlesRead, entity.PermissionArticlesWrite},
+	}).Return("access-token", nil)
+
+	signer := authz.NewJSONWebToken(jsonWebToken, authz.NewAuthorizer(time.Minute, authzRepository))
+	token, err := signer.Sign(context.Background(), entity.AccountStandardJWTClaims{Email: "johndoe@mail.com", Role: entity.RoleEditor})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token", token)
+	jsonWebToken.AssertExpectations(t)
+}
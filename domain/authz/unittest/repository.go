@@ -0,0 +1,27 @@
+package unittest
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuthzRepository struct {
+	mock.Mock
+}
+
+func (d *MockAuthzRepository) FindPermissionsByRole(ctx context.Context, role string) (permissions []string, err error) {
+	args := d.Called(ctx, role)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (d *MockAuthzRepository) RoleExists(ctx context.Context, role string) (exists bool, err error) {
+	args := d.Called(ctx, role)
+	return args.Bool(0), args.Error(1)
+}
+
+func (d *MockAuthzRepository) Seed(ctx context.Context, rolePermissions map[string][]string, createdAt time.Time) (err error) {
+	args := d.Called(ctx, rolePermissions, createdAt)
+	return args.Error(0)
+}
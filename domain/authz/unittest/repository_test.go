@@ -0,0 +1,86 @@
+package unittest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/domain/authz"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+)
+
+func TestAuthzRepository_FindPermissionsByRole(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	dbMock.ExpectPrepare("SELECT p.name FROM role r JOIN role_permission rp ON rp.roleId = r.id JOIN permission p ON p.id = rp.permissionId WHERE r.name = \\?").
+		ExpectQuery().
+		WithArgs(entity.RoleEditor).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow(entity.PermissionArticlesPublish).AddRow(entity.PermissionArticlesRead))
+
+	permissions, err := authz.NewAuthzRepository(db, "role", "permission").FindPermissionsByRole(context.Background(), entity.RoleEditor)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{entity.PermissionArticlesPublish, entity.PermissionArticlesRead}, permissions)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAuthzRepository_Seed(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	createdAt := time.Now()
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery("SELECT id FROM role LIMIT 1 FOR UPDATE").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	dbMock.ExpectExec("INSERT IGNORE INTO role ").WithArgs(entity.RoleAdmin, createdAt).WillReturnResult(sqlmock.NewResult(1, 1))
+	dbMock.ExpectExec("INSERT IGNORE INTO permission ").WithArgs(entity.PermissionAccountsManage, createdAt).WillReturnResult(sqlmock.NewResult(1, 1))
+	dbMock.ExpectExec("INSERT IGNORE INTO role_permission ").WithArgs(entity.RoleAdmin, entity.PermissionAccountsManage).WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+
+	err = authz.NewAuthzRepository(db, "role", "permission").Seed(context.Background(), map[string][]string{
+		entity.RoleAdmin: {entity.PermissionAccountsManage},
+	}, createdAt)
+
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAuthzRepository_Seed_AlreadySeeded(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// the grant was removed from the database, it is not seeded again.
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery("SELECT id FROM role LIMIT 1 FOR UPDATE").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	dbMock.ExpectRollback()
+
+	err = authz.NewAuthzRepository(db, "role", "permission").Seed(context.Background(), map[string][]string{
+		entity.RoleAdmin: {entity.PermissionAccountsManage},
+	}, time.Now())
+
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestAuthzRepository_RoleExists(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	dbMock.ExpectPrepare("SELECT EXISTS \\(SELECT 1 FROM role WHERE name = \\?\\)").
+		ExpectQuery().
+		WithArgs("moderator").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := authz.NewAuthzRepository(db, "role", "permission").RoleExists(context.Background(), "moderator")
+
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
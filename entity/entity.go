@@ -2,8 +2,42 @@ package entity
 
 import "github.com/dgrijalva/jwt-go"
 
+// Roles of account.
+const (
+	RoleUser   = "user"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Scopes of personal api keys.
+const (
+	ScopeArticlesRead  = "articles:read"
+	ScopeArticlesWrite = "articles:write"
+)
+
+// Permissions granted to roles, the grants themselves are stored in the database.
+// The any permissions extend an article permission to the articles of other authors.
+const (
+	PermissionArticlesRead       = "articles:read"
+	PermissionArticlesWrite      = "articles:write"
+	PermissionArticlesPublish    = "articles:publish"
+	PermissionArticlesWriteAny   = "articles:write-any"
+	PermissionArticlesPublishAny = "articles:publish-any"
+	PermissionArticlesDeleteAny  = "articles:delete-any"
+	PermissionAccountsRead       = "accounts:read"
+	PermissionAccountsManage     = "accounts:manage"
+	PermissionRolesAssign        = "roles:assign"
+)
+
 // CustomerStandardJWTClaims is a model.
+// Scopes restrict the claims to the listed scopes, claims without scopes are a user session allowed everything.
+// Permissions are those of the role when the token was signed.
 type AccountStandardJWTClaims struct {
 	jwt.StandardClaims
-	Email string `json:"email"`
+	Email         string   `json:"email"`
+	Role          string   `json:"role,omitempty"`
+	EmailVerified bool     `json:"emailVerified"`
+	SessionID     string   `json:"sid,omitempty"`
+	Scopes        []string `json:"scopes,omitempty"`
+	Permissions   []string `json:"permissions,omitempty"`
 }
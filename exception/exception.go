@@ -8,4 +8,5 @@ var (
 	ErrNotFound       = fmt.Errorf("not found error")
 	ErrBadRequest     = fmt.Errorf("bad request")
 	ErrUnauthorized   = fmt.Errorf("unauthorized")
+	ErrForbidden      = fmt.Errorf("forbidden")
 )
@@ -0,0 +1,134 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idPrefix  = "$argon2id$"
+	argon2idSaltLen = 16
+	argon2idKeyLen  = 32
+	// argon2idMaxMemory bounds in KiB what a stored hash may make a verification allocate.
+	argon2idMaxMemory = 4 * 1024 * 1024
+)
+
+// Argon2idHasher is a concrete struct of argon2id password hasher.
+type Argon2idHasher struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// NewArgon2idHasher is a constructor. Memory is in KiB.
+func NewArgon2idHasher(time, memory uint32, threads uint8) PasswordHasher {
+	if time == 0 {
+		time = 3
+	}
+	if memory == 0 {
+		memory = 64 * 1024
+	}
+	if threads == 0 {
+		threads = 2
+	}
+
+	return &Argon2idHasher{
+		time:    time,
+		memory:  memory,
+		threads: threads,
+	}
+}
+
+// Hash returns the argon2id hash of the password in the PHC string format.
+func (h *Argon2idHasher) Hash(password string) (hashed string, err error) {
+	salt := make([]byte, argon2idSaltLen)
+	if _, err = rand.Read(salt); err != nil {
+		return
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.time, h.memory, h.threads, argon2idKeyLen)
+
+	hashed = fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		h.memory,
+		h.time,
+		h.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return
+}
+
+// Verify compares the password with the hash in constant time.
+func (h *Argon2idHasher) Verify(password string, hashed string) (err error) {
+	return verify(password, hashed)
+}
+
+// NeedsRehash reports whether the hash was made by another algorithm or with other parameters.
+func (h *Argon2idHasher) NeedsRehash(hashed string) (needsRehash bool) {
+	params, _, _, err := decodeArgon2id(hashed)
+	if err != nil {
+		return true
+	}
+
+	return params.time != h.time || params.memory != h.memory || params.threads != h.threads
+}
+
+func verifyArgon2id(password string, hashed string) (err error) {
+	params, salt, key, err := decodeArgon2id(hashed)
+	if err != nil {
+		return
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return ErrMismatchedPassword
+	}
+
+	return
+}
+
+func decodeArgon2id(hashed string) (params Argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		err = ErrUnsupportedHash
+		return
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		err = ErrUnsupportedHash
+		return
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		err = ErrUnsupportedHash
+		return
+	}
+
+	// argon2 panics on no passes or no threads, and needs at least 8 KiB per thread.
+	if params.time < 1 || params.threads < 1 || params.memory < 8*uint32(params.threads) || params.memory > argon2idMaxMemory {
+		err = ErrUnsupportedHash
+		return
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		err = ErrUnsupportedHash
+		return
+	}
+
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		err = ErrUnsupportedHash
+		return
+	}
+
+	return
+}
@@ -0,0 +1,66 @@
+package hasher
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptHasher is a concrete struct of bcrypt password hasher.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher is a constructor.
+func NewBcryptHasher(cost int) PasswordHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+
+	return &BcryptHasher{cost: cost}
+}
+
+// Hash returns the bcrypt hash of the password.
+func (h *BcryptHasher) Hash(password string) (hashed string, err error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return
+	}
+
+	return string(b), nil
+}
+
+// Verify compares the password with the hash in constant time.
+func (h *BcryptHasher) Verify(password string, hashed string) (err error) {
+	return verify(password, hashed)
+}
+
+// NeedsRehash reports whether the hash was made by another algorithm or with another cost.
+func (h *BcryptHasher) NeedsRehash(hashed string) (needsRehash bool) {
+	if !isBcryptHash(hashed) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hashed))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
+
+func isBcryptHash(hashed string) bool {
+	return strings.HasPrefix(hashed, "$2a$") || strings.HasPrefix(hashed, "$2b$") || strings.HasPrefix(hashed, "$2y$")
+}
+
+func verifyBcrypt(password string, hashed string) (err error) {
+	err = bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrMismatchedPassword
+	}
+	if err != nil {
+		return ErrUnsupportedHash
+	}
+
+	return
+}
@@ -0,0 +1,32 @@
+package hasher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Errors.
+var (
+	ErrMismatchedPassword = fmt.Errorf("password does not match the hash")
+	ErrUnsupportedHash    = fmt.Errorf("unsupported password hash format")
+)
+
+// PasswordHasher is a collection of behavior of one-way password hashing.
+type PasswordHasher interface {
+	Hash(password string) (hashed string, err error)
+	Verify(password string, hashed string) (err error)
+	NeedsRehash(hashed string) (needsRehash bool)
+}
+
+// verify compares the password with a hash produced by any of the supported algorithms.
+// The parameters are read from the hash itself, so a hash made with an older cost can still be verified.
+func verify(password string, hashed string) (err error) {
+	switch {
+	case strings.HasPrefix(hashed, argon2idPrefix):
+		return verifyArgon2id(password, hashed)
+	case isBcryptHash(hashed):
+		return verifyBcrypt(password, hashed)
+	default:
+		return ErrUnsupportedHash
+	}
+}
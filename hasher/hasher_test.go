@@ -0,0 +1,67 @@
+package hasher_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/hasher"
+)
+
+func TestBcryptHasher_HashAndVerify(t *testing.T) {
+	h := hasher.NewBcryptHasher(4)
+	hashed, err := h.Hash("secret")
+
+	assert.NoError(t, err)
+	assert.NoError(t, h.Verify("secret", hashed))
+	assert.Equal(t, hasher.ErrMismatchedPassword, h.Verify("wrong", hashed))
+	assert.False(t, h.NeedsRehash(hashed))
+	assert.True(t, hasher.NewBcryptHasher(5).NeedsRehash(hashed))
+}
+
+func TestArgon2idHasher_HashAndVerify(t *testing.T) {
+	h := hasher.NewArgon2idHasher(1, 1024, 1)
+	hashed, err := h.Hash("secret")
+
+	assert.NoError(t, err)
+	assert.NoError(t, h.Verify("secret", hashed))
+	assert.Equal(t, hasher.ErrMismatchedPassword, h.Verify("wrong", hashed))
+	assert.False(t, h.NeedsRehash(hashed))
+	assert.True(t, hasher.NewArgon2idHasher(2, 1024, 1).NeedsRehash(hashed))
+}
+
+func TestArgon2idHasher_VerifyOutOfRangeParams(t *testing.T) {
+	h := hasher.NewArgon2idHasher(1, 1024, 1)
+	salt := "c2FsdHNhbHRzYWx0c2FsdA"
+	key := "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+
+	assert.Equal(t, hasher.ErrMismatchedPassword, h.Verify("secret", "$argon2id$v=19$m=1024,t=1,p=1$"+salt+"$"+key))
+
+	for _, params := range []string{
+		"m=1024,t=0,p=1",
+		"m=1024,t=1,p=0",
+		"m=4,t=1,p=1",
+		"m=4294967295,t=1,p=1",
+	} {
+		hashed := "$argon2id$v=19$" + params + "$" + salt + "$" + key
+
+		assert.Equal(t, hasher.ErrUnsupportedHash, h.Verify("secret", hashed), params)
+		assert.True(t, h.NeedsRehash(hashed), params)
+	}
+}
+
+func TestPasswordHasher_VerifyOtherAlgorithm(t *testing.T) {
+	bcryptHasher := hasher.NewBcryptHasher(4)
+	argon2idHasher := hasher.NewArgon2idHasher(1, 1024, 1)
+	hashed, _ := bcryptHasher.Hash("secret")
+
+	assert.NoError(t, argon2idHasher.Verify("secret", hashed))
+	assert.True(t, argon2idHasher.NeedsRehash(hashed))
+}
+
+func TestPasswordHasher_VerifyLegacyValue(t *testing.T) {
+	h := hasher.NewBcryptHasher(4)
+
+	assert.Equal(t, hasher.ErrUnsupportedHash, h.Verify("secret", "5f4dcc3b5aa765d61d8327deb882cf99"))
+	assert.True(t, h.NeedsRehash("5f4dcc3b5aa765d61d8327deb882cf99"))
+}
@@ -0,0 +1,114 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Supported signing algorithms.
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmPS256 = "PS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Errors.
+var (
+	ErrUnsupportedAlgorithm = fmt.Errorf("unsupported signing algorithm")
+	ErrKeyMismatch          = fmt.Errorf("key does not match the signing algorithm")
+)
+
+func signingMethod(algorithm string) (method jwt.SigningMethod, err error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgorithmPS256:
+		return jwt.SigningMethodPS256, nil
+	case AlgorithmES256:
+		return jwt.SigningMethodES256, nil
+	case AlgorithmEdDSA:
+		return SigningMethodEd25519, nil
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+// validateKey ensures the key can only be used with the algorithm it is pinned to.
+func validateKey(algorithm string, publicKey crypto.PublicKey) (err error) {
+	var ok bool
+	switch algorithm {
+	case AlgorithmRS256, AlgorithmPS256:
+		_, ok = publicKey.(*rsa.PublicKey)
+	case AlgorithmES256:
+		var key *ecdsa.PublicKey
+		key, ok = publicKey.(*ecdsa.PublicKey)
+		ok = ok && key.Curve == elliptic.P256()
+	case AlgorithmEdDSA:
+		_, ok = publicKey.(ed25519.PublicKey)
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+
+	if !ok {
+		return ErrKeyMismatch
+	}
+
+	return
+}
+
+// loadPrivateKey reads the PEM private key of the algorithm along with its public key.
+func loadPrivateKey(algorithm string, filename string) (privateKey crypto.PrivateKey, publicKey crypto.PublicKey, err error) {
+	switch algorithm {
+	case AlgorithmRS256, AlgorithmPS256:
+		if key := GetRSAPrivateKey(filename); key != nil {
+			return key, &key.PublicKey, nil
+		}
+	case AlgorithmES256:
+		if key := GetECDSAPrivateKey(filename); key != nil {
+			return key, &key.PublicKey, nil
+		}
+	case AlgorithmEdDSA:
+		if key := GetEd25519PrivateKey(filename); key != nil {
+			return key, key.Public(), nil
+		}
+	default:
+		return nil, nil, ErrUnsupportedAlgorithm
+	}
+
+	return nil, nil, fmt.Errorf("invalid %s private key %s", algorithm, filename)
+}
+
+// loadPublicKey reads the PEM public key of the algorithm.
+func loadPublicKey(algorithm string, filename string) (publicKey crypto.PublicKey, err error) {
+	switch algorithm {
+	case AlgorithmRS256, AlgorithmPS256:
+		if key := GetRSAPublicKey(filename); key != nil {
+			return key, nil
+		}
+	case AlgorithmES256:
+		if key := GetECDSAPublicKey(filename); key != nil {
+			return key, nil
+		}
+	case AlgorithmEdDSA:
+		if key := GetEd25519PublicKey(filename); key != nil {
+			return key, nil
+		}
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	return nil, fmt.Errorf("invalid %s public key %s", algorithm, filename)
+}
+
+// samePublicKey reports whether both public keys are the same key.
+func samePublicKey(publicKey crypto.PublicKey, otherPublicKey crypto.PublicKey) bool {
+	key, ok := publicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(otherPublicKey)
+}
@@ -0,0 +1,82 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Options is a collection of registered claims the tokens are issued with and validated against.
+// Empty issuer or audience disables its check, the leeway tolerates clock skew on exp, nbf and iat.
+type Options struct {
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+// registeredClaims is satisfied by *jwt.StandardClaims, types embedding it and jwt.MapClaims.
+type registeredClaims interface {
+	VerifyAudience(cmp string, req bool) bool
+	VerifyExpiresAt(cmp int64, req bool) bool
+	VerifyIssuedAt(cmp int64, req bool) bool
+	VerifyIssuer(cmp string, req bool) bool
+	VerifyNotBefore(cmp int64, req bool) bool
+}
+
+// stampedClaims writes the configured issuer and audience into the claims unless the caller already set them.
+type stampedClaims struct {
+	jwt.Claims
+	issuer   string
+	audience string
+}
+
+func (c stampedClaims) MarshalJSON() ([]byte, error) {
+	buff, err := json.Marshal(c.Claims)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(buff))
+	decoder.UseNumber()
+	if err = decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	if v, _ := fields["iss"].(string); v == "" && c.issuer != "" {
+		fields["iss"] = c.issuer
+	}
+	if v, _ := fields["aud"].(string); v == "" && c.audience != "" {
+		fields["aud"] = c.audience
+	}
+
+	return json.Marshal(fields)
+}
+
+// validateClaims checks the time based claims with the leeway, then the issuer and the audience.
+func (a *jsonWebToken) validateClaims(claims jwt.Claims) (err error) {
+	registered, ok := claims.(registeredClaims)
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	now := jwt.TimeFunc().Unix()
+	leeway := int64(a.options.Leeway / time.Second)
+
+	if !registered.VerifyExpiresAt(now-leeway, false) {
+		return ErrExpiredToken
+	}
+	if !registered.VerifyNotBefore(now+leeway, false) || !registered.VerifyIssuedAt(now+leeway, false) {
+		return ErrNotReadyToken
+	}
+	if a.options.Issuer != "" && !registered.VerifyIssuer(a.options.Issuer, true) {
+		return ErrInvalidIssuer
+	}
+	if a.options.Audience != "" && !registered.VerifyAudience(a.options.Audience, true) {
+		return ErrInvalidAudience
+	}
+
+	return
+}
@@ -0,0 +1,31 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"io/ioutil"
+	"log"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// GetECDSAPrivateKey will return ecdsa private key.
+func GetECDSAPrivateKey(filename string) *ecdsa.PrivateKey {
+	signBytes, _ := ioutil.ReadFile(filename)
+	signKey, err := jwt.ParseECPrivateKeyFromPEM(signBytes)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	return signKey
+}
+
+// GetECDSAPublicKey returns ecdsa key of verification.
+func GetECDSAPublicKey(filename string) *ecdsa.PublicKey {
+	verifyBytes, _ := ioutil.ReadFile(filename)
+	verifyKey, err := jwt.ParseECPublicKeyFromPEM(verifyBytes)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	return verifyKey
+}
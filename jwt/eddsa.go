@@ -0,0 +1,104 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningMethodEdDSA implements the EdDSA signing method of RFC 8037 with Ed25519 keys.
+type SigningMethodEdDSA struct{}
+
+// SigningMethodEd25519 is the EdDSA signing method instance.
+var SigningMethodEd25519 = &SigningMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEd25519.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEd25519
+	})
+}
+
+// Alg returns the name of the algorithm.
+func (m *SigningMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+// Verify will verify the signature with an ed25519.PublicKey.
+func (m *SigningMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok || len(publicKey) != ed25519.PublicKeySize {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+// Sign will sign the string with an ed25519.PrivateKey.
+func (m *SigningMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok || len(privateKey) != ed25519.PrivateKeySize {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+// GetEd25519PrivateKey will return ed25519 private key from a PKCS #8 PEM file.
+func GetEd25519PrivateKey(filename string) ed25519.PrivateKey {
+	signBytes, _ := ioutil.ReadFile(filename)
+	block, _ := pem.Decode(signBytes)
+	if block == nil {
+		log.Println(fmt.Errorf("invalid PEM file %s", filename))
+		return nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	signKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		log.Println(fmt.Errorf("%s is not an ed25519 private key", filename))
+		return nil
+	}
+	return signKey
+}
+
+// GetEd25519PublicKey returns ed25519 key of verification from a PKIX PEM file.
+func GetEd25519PublicKey(filename string) ed25519.PublicKey {
+	verifyBytes, _ := ioutil.ReadFile(filename)
+	block, _ := pem.Decode(verifyBytes)
+	if block == nil {
+		log.Println(fmt.Errorf("invalid PEM file %s", filename))
+		return nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	verifyKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		log.Println(fmt.Errorf("%s is not an ed25519 public key", filename))
+		return nil
+	}
+	return verifyKey
+}
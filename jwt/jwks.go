@@ -0,0 +1,123 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// JSONWebKey is a public key in the RFC 7517 format.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// ErrInvalidJSONWebKey is returned when a json web key cannot be turned into a public key.
+var ErrInvalidJSONWebKey = fmt.Errorf("invalid json web key")
+
+// PublicKey decodes the RSA, P-256 or Ed25519 public key of the json web key.
+func (jwk JSONWebKey) PublicKey() (publicKey crypto.PublicKey, err error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, ErrInvalidJSONWebKey
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Curve != elliptic.P256().Params().Name {
+			return nil, ErrInvalidJSONWebKey
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil {
+			return nil, ErrInvalidJSONWebKey
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, ErrInvalidJSONWebKey
+		}
+		return key, nil
+	case "OKP":
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		if jwk.Curve != "Ed25519" || errX != nil || len(x) != ed25519.PublicKeySize {
+			return nil, ErrInvalidJSONWebKey
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrInvalidJSONWebKey
+	}
+}
+
+// JSONWebKeySet is a set of public keys in the RFC 7517 format.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKSHTTPHandler exposes the public keys of the key ring.
+type JWKSHTTPHandler struct {
+	KeyRing KeyRing
+}
+
+// NewJWKSHTTPHandler is a constructor.
+func NewJWKSHTTPHandler(router *mux.Router, keyRing KeyRing) {
+	handler := &JWKSHTTPHandler{
+		KeyRing: keyRing,
+	}
+
+	router.HandleFunc("/.well-known/jwks.json", handler.JWKS).Methods(http.MethodGet)
+}
+
+// JWKS writes the key set as is, because verifiers expect the standard document rather than the response envelope.
+func (handler *JWKSHTTPHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	keySet := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range handler.KeyRing.PublicKeys() {
+		keySet.Keys = append(keySet.Keys, jsonWebKey(key))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(keySet)
+}
+
+func jsonWebKey(key SigningKey) (jwk JSONWebKey) {
+	jwk.Use = "sig"
+	jwk.Algorithm = key.Algorithm
+	jwk.KeyID = key.ID
+
+	switch publicKey := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	}
+
+	return
+}
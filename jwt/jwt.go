@@ -2,7 +2,6 @@ package jwt
 
 import (
 	"context"
-	"crypto/rsa"
 	"fmt"
 
 	"github.com/dgrijalva/jwt-go"
@@ -12,6 +11,11 @@ import (
 var (
 	ErrInvalidToken      error = fmt.Errorf("invalid token")
 	ErrExpiredOrNotReady error = fmt.Errorf("token is either expired or not ready to use")
+	ErrRevokedToken      error = fmt.Errorf("token has been revoked")
+	ErrExpiredToken      error = fmt.Errorf("token is expired: %w", ErrExpiredOrNotReady)
+	ErrNotReadyToken     error = fmt.Errorf("token is not ready to use: %w", ErrExpiredOrNotReady)
+	ErrInvalidIssuer     error = fmt.Errorf("token has an invalid issuer: %w", ErrInvalidToken)
+	ErrInvalidAudience   error = fmt.Errorf("token has an invalid audience: %w", ErrInvalidToken)
 )
 
 // JSONWebToken is a collection of behavior of JSON Web Token.
@@ -21,13 +25,13 @@ type JSONWebToken interface {
 }
 
 type jsonWebToken struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
+	keyRing KeyRing
+	options Options
 }
 
 // NewJSONWebToken is a constructor.
-func NewJSONWebToken(privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey) JSONWebToken {
-	return &jsonWebToken{privateKey, publicKey}
+func NewJSONWebToken(keyRing KeyRing, options Options) JSONWebToken {
+	return &jsonWebToken{keyRing, options}
 }
 
 // Sign will generate new jwt token.
@@ -35,8 +39,19 @@ func (a *jsonWebToken) Sign(ctx context.Context, claims jwt.Claims) (tokenString
 	// span, _ := apm.StartSpan(ctx, "JSONWebToken: Sign", "token.jwt")
 	// defer span.End()
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(a.privateKey)
+	key, err := a.keyRing.ActiveKey()
+	if err != nil {
+		return
+	}
+
+	method, err := signingMethod(key.Algorithm)
+	if err != nil {
+		return
+	}
+
+	token := jwt.NewWithClaims(method, stampedClaims{claims, a.options.Issuer, a.options.Audience})
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
 }
 
 // Parse will parse the token string to bearer claims.
@@ -44,7 +59,8 @@ func (a *jsonWebToken) Parse(ctx context.Context, tokenString string, claims jwt
 	// span, _ := apm.StartSpan(ctx, "JSONWebToken: Parse", "token.jwt")
 	// defer span.End()
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, a.keyFunc)
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.ParseWithClaims(tokenString, claims, a.keyFunc)
 	if err = a.checkError(err); err != nil {
 		return
 	}
@@ -53,14 +69,30 @@ func (a *jsonWebToken) Parse(ctx context.Context, tokenString string, claims jwt
 		return ErrInvalidToken
 	}
 
-	return
+	return a.validateClaims(claims)
 }
 
+// keyFunc picks the verification key by the kid header and only accepts the algorithm the key is pinned to,
+// so a token can never choose how its own signature is checked.
+// Tokens issued before the key ring existed carry no kid and are verified with the active key.
 func (a *jsonWebToken) keyFunc(token *jwt.Token) (interface{}, error) {
-	if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+	var key SigningKey
+	var err error
+
+	kid, ok := token.Header["kid"].(string)
+	if ok {
+		key, err = a.keyRing.VerificationKey(kid)
+	} else {
+		key, err = a.keyRing.ActiveKey()
+	}
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if token.Method.Alg() != key.Algorithm {
 		return nil, ErrInvalidToken
 	}
-	return a.publicKey, nil
+	return key.PublicKey, nil
 }
 
 func (a *jsonWebToken) checkError(err error) error {
@@ -7,6 +7,7 @@ import (
 	"github.com/sangianpatrick/devoria-article-service/response"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type JwtMiddleware interface {
@@ -14,7 +15,9 @@ type JwtMiddleware interface {
 }
 
 type JwtToken struct {
-	jsonWebToken JSONWebToken
+	jsonWebToken     JSONWebToken
+	tokenRevocation  TokenRevocation
+	sessionValidator SessionValidator
 }
 
 func (j *JwtToken) VerifyToken(next http.HandlerFunc) http.HandlerFunc {
@@ -38,12 +41,36 @@ func (j *JwtToken) VerifyToken(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		revoked, err := j.tokenRevocation.IsRevoked(request.Context(), claims.Id, claims.Subject, time.Unix(claims.IssuedAt, 0))
+		if err != nil {
+			resp = response.Error(response.StatusUnexpectedError, nil, err)
+			resp.JSON(writer)
+			return
+		}
+		if revoked {
+			resp = response.Error(response.StatusUnauthorized, nil, ErrRevokedToken)
+			resp.JSON(writer)
+			return
+		}
+
+		active, err := j.sessionValidator.IsSessionActive(request.Context(), claims.Subject, claims.SessionID)
+		if err != nil {
+			resp = response.Error(response.StatusUnexpectedError, nil, err)
+			resp.JSON(writer)
+			return
+		}
+		if !active {
+			resp = response.Error(response.StatusUnauthorized, nil, ErrRevokedToken)
+			resp.JSON(writer)
+			return
+		}
+
 		byt, _ := json.Marshal(claims)
 		context.Set(request, "bind", byt)
 		next.ServeHTTP(writer, request)
 	}
 }
 
-func NewJwtToken(jsonWebToken JSONWebToken) JwtMiddleware {
-	return &JwtToken{jsonWebToken:jsonWebToken}
+func NewJwtToken(jsonWebToken JSONWebToken, tokenRevocation TokenRevocation, sessionValidator SessionValidator) JwtMiddleware {
+	return &JwtToken{jsonWebToken:jsonWebToken, tokenRevocation: tokenRevocation, sessionValidator: sessionValidator}
 }
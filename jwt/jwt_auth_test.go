@@ -0,0 +1,87 @@
+package jwt_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	redismock "github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+	"github.com/sangianpatrick/devoria-article-service/session"
+)
+
+type activeSessions struct{}
+
+func (activeSessions) IsSessionActive(ctx context.Context, subject string, sessionID string) (active bool, err error) {
+	return sessionID != "", nil
+}
+
+// serveVerifiedToken sends a token of the subject 14 issued at the given time through the middleware.
+func serveVerifiedToken(t *testing.T, revocation jwt.TokenRevocation, issuedAt time.Time) int {
+	privateKey, publicKey := generateKey(t, jwt.AlgorithmEdDSA)
+	key, _ := jwt.NewSigningKey(jwt.AlgorithmEdDSA, privateKey, publicKey)
+	jsonWebToken := jwt.NewJSONWebToken(jwt.NewKeyRing(key), jwt.Options{})
+
+	claims := entity.AccountStandardJWTClaims{Email: "johndoe@mail.com", SessionID: "session-id"}
+	claims.Id = "token-id"
+	claims.Subject = "14"
+	claims.IssuedAt = issuedAt.Unix()
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+	tokenString, err := jsonWebToken.Sign(context.TODO(), claims)
+	assert.NoError(t, err)
+
+	handler := jwt.NewJwtToken(jsonWebToken, revocation, activeSessions{}).VerifyToken(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/account/profile", nil)
+	request.Header.Set("Authorization", "Bearer "+tokenString)
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+
+	return recorder.Code
+}
+
+func TestVerifyToken_Logout(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	mock.ExpectSetEX("jwt:revoked:token-id", []byte("1"), time.Minute).SetVal("OK")
+	mock.ExpectGet("jwt:revoked:token-id").SetVal("1")
+
+	revocation := jwt.NewTokenRevocation(session.NewRedisSessionStoreAdapter(rdb, time.Minute))
+	assert.NoError(t, revocation.Revoke(context.TODO(), "token-id"))
+
+	assert.Equal(t, http.StatusUnauthorized, serveVerifiedToken(t, revocation, time.Now()))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyToken_LogoutAll(t *testing.T) {
+	revokedAt := time.Now()
+	revokedBefore := fmt.Sprintf("%d", revokedAt.Unix())
+
+	rdb, mock := redismock.NewClientMock()
+	mock.ExpectSetEX("jwt:revoked-before:14", []byte(revokedBefore), time.Minute).SetVal("OK")
+	mock.ExpectGet("jwt:revoked:token-id").RedisNil()
+	mock.ExpectGet("jwt:revoked-before:14").SetVal(revokedBefore)
+	mock.ExpectGet("jwt:revoked:token-id").RedisNil()
+	mock.ExpectGet("jwt:revoked-before:14").SetVal(revokedBefore)
+
+	revocation := jwt.NewTokenRevocation(session.NewRedisSessionStoreAdapter(rdb, time.Minute))
+	assert.NoError(t, revocation.RevokeAll(context.TODO(), "14", revokedAt))
+
+	assert.Equal(t, http.StatusUnauthorized, serveVerifiedToken(t, revocation, revokedAt.Add(-time.Second)))
+	// signed in again right after signing out everywhere.
+	assert.Equal(t, http.StatusOK, serveVerifiedToken(t, revocation, revokedAt))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,149 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+)
+
+func generateKey(t *testing.T, algorithm string) (crypto.PrivateKey, crypto.PublicKey) {
+	switch algorithm {
+	case jwt.AlgorithmES256:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		return key, &key.PublicKey
+	case jwt.AlgorithmEdDSA:
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		return privateKey, publicKey
+	default:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		return key, &key.PublicKey
+	}
+}
+
+func TestJSONWebToken_Algorithms(t *testing.T) {
+	for _, algorithm := range []string{jwt.AlgorithmRS256, jwt.AlgorithmPS256, jwt.AlgorithmES256, jwt.AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			privateKey, publicKey := generateKey(t, algorithm)
+			key, err := jwt.NewSigningKey(algorithm, privateKey, publicKey)
+			assert.NoError(t, err)
+
+			claims := entity.AccountStandardJWTClaims{Email: "johndoe@mail.com"}
+			claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+
+			jsonWebToken := jwt.NewJSONWebToken(jwt.NewKeyRing(key), jwt.Options{})
+			tokenString, err := jsonWebToken.Sign(context.TODO(), claims)
+			assert.NoError(t, err)
+
+			var parsedClaims entity.AccountStandardJWTClaims
+			assert.NoError(t, jsonWebToken.Parse(context.TODO(), tokenString, &parsedClaims))
+			assert.Equal(t, "johndoe@mail.com", parsedClaims.Email)
+		})
+	}
+}
+
+func TestNewSigningKey_KeyMismatch(t *testing.T) {
+	privateKey, publicKey := generateKey(t, jwt.AlgorithmRS256)
+	_, err := jwt.NewSigningKey(jwt.AlgorithmES256, privateKey, publicKey)
+
+	assert.Equal(t, jwt.ErrKeyMismatch, err)
+}
+
+func TestJSONWebToken_Parse_AlgorithmConfusion(t *testing.T) {
+	privateKey, publicKey := generateKey(t, jwt.AlgorithmRS256)
+	key, _ := jwt.NewSigningKey(jwt.AlgorithmRS256, privateKey, publicKey)
+	jsonWebToken := jwt.NewJSONWebToken(jwt.NewKeyRing(key), jwt.Options{})
+
+	claims := entity.AccountStandardJWTClaims{Email: "johndoe@mail.com"}
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+
+	// HS256 keyed with the public key bytes is the classic confusion attack.
+	publicKeyBytes, _ := x509.MarshalPKIXPublicKey(publicKey)
+	forged := jwtgo.NewWithClaims(jwtgo.SigningMethodHS256, claims)
+	forged.Header["kid"] = key.ID
+	forgedString, _ := forged.SignedString(publicKeyBytes)
+	assert.Equal(t, jwt.ErrInvalidToken, jsonWebToken.Parse(context.TODO(), forgedString, &entity.AccountStandardJWTClaims{}))
+
+	// PS256 is a valid RSA algorithm but not the one the key is pinned to.
+	pss := jwtgo.NewWithClaims(jwtgo.SigningMethodPS256, claims)
+	pss.Header["kid"] = key.ID
+	pssString, _ := pss.SignedString(privateKey)
+	assert.Equal(t, jwt.ErrInvalidToken, jsonWebToken.Parse(context.TODO(), pssString, &entity.AccountStandardJWTClaims{}))
+}
+
+func TestJSONWebToken_IssuerAudienceAndLeeway(t *testing.T) {
+	privateKey, publicKey := generateKey(t, jwt.AlgorithmEdDSA)
+	key, _ := jwt.NewSigningKey(jwt.AlgorithmEdDSA, privateKey, publicKey)
+	keyRing := jwt.NewKeyRing(key)
+
+	articleService := jwt.NewJSONWebToken(keyRing, jwt.Options{Issuer: "account-service", Audience: "article-service", Leeway: time.Minute})
+	paymentService := jwt.NewJSONWebToken(keyRing, jwt.Options{Issuer: "account-service", Audience: "payment-service"})
+	otherIssuer := jwt.NewJSONWebToken(keyRing, jwt.Options{Issuer: "other-service", Audience: "article-service"})
+
+	claims := entity.AccountStandardJWTClaims{Email: "johndoe@mail.com"}
+	claims.ExpiresAt = time.Now().Add(-time.Second * 30).Unix()
+	tokenString, err := articleService.Sign(context.TODO(), claims)
+	assert.NoError(t, err)
+
+	var parsedClaims entity.AccountStandardJWTClaims
+	assert.NoError(t, articleService.Parse(context.TODO(), tokenString, &parsedClaims))
+	assert.Equal(t, "account-service", parsedClaims.Issuer)
+	assert.Equal(t, "article-service", parsedClaims.Audience)
+
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+	tokenString, _ = articleService.Sign(context.TODO(), claims)
+	assert.Equal(t, jwt.ErrInvalidAudience, paymentService.Parse(context.TODO(), tokenString, &entity.AccountStandardJWTClaims{}))
+	assert.Equal(t, jwt.ErrInvalidIssuer, otherIssuer.Parse(context.TODO(), tokenString, &entity.AccountStandardJWTClaims{}))
+
+	claims.ExpiresAt = time.Now().Add(-time.Second * 30).Unix()
+	tokenString, _ = paymentService.Sign(context.TODO(), claims)
+	err = paymentService.Parse(context.TODO(), tokenString, &entity.AccountStandardJWTClaims{})
+	assert.Equal(t, jwt.ErrExpiredToken, err)
+	assert.True(t, errors.Is(err, jwt.ErrExpiredOrNotReady))
+}
+
+func TestJSONWebKey_PublicKey(t *testing.T) {
+	for _, algorithm := range []string{jwt.AlgorithmRS256, jwt.AlgorithmES256, jwt.AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			privateKey, publicKey := generateKey(t, algorithm)
+			key, err := jwt.NewSigningKey(algorithm, privateKey, publicKey)
+			assert.NoError(t, err)
+
+			router := mux.NewRouter()
+			jwt.NewJWKSHTTPHandler(router, jwt.NewKeyRing(key))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+			var keySet jwt.JSONWebKeySet
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &keySet))
+			assert.Len(t, keySet.Keys, 1)
+
+			decodedKey, err := keySet.Keys[0].PublicKey()
+			assert.NoError(t, err)
+			assert.Equal(t, publicKey, decodedKey)
+		})
+	}
+
+	_, err := jwt.JSONWebKey{KeyType: "EC", Curve: "P-256", X: "AQ", Y: "AQ"}.PublicKey()
+	assert.Equal(t, jwt.ErrInvalidJSONWebKey, err)
+}
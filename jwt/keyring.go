@@ -0,0 +1,229 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Errors.
+var (
+	ErrNoActiveKey     = fmt.Errorf("no active signing key")
+	ErrUnknownKey      = fmt.Errorf("unknown or retired signing key")
+	ErrKeyPairMismatch = fmt.Errorf("public key does not match the private key")
+)
+
+// KeyRingManifestFilename is the name of the key ring metadata file inside the key directory.
+const KeyRingManifestFilename = "keys.json"
+
+// SigningKey is a key pair of the key ring, pinned to a single algorithm.
+// A key without private key can only verify, a zero activation time means always active.
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  crypto.PrivateKey
+	PublicKey   crypto.PublicKey
+	ActivatedAt time.Time
+	RetiredAt   *time.Time
+}
+
+// NewSigningKey is a constructor of an always active key identified by its RFC 7638 thumbprint.
+func NewSigningKey(algorithm string, privateKey crypto.PrivateKey, publicKey crypto.PublicKey) (key SigningKey, err error) {
+	if err = validateKey(algorithm, publicKey); err != nil {
+		return
+	}
+
+	key = SigningKey{
+		Algorithm:  algorithm,
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+	}
+	key.ID = thumbprint(key)
+
+	return
+}
+
+// LoadSigningKey will load an always active key pair of the algorithm from PEM files.
+func LoadSigningKey(algorithm string, privateKeyFilename string, publicKeyFilename string) (key SigningKey, err error) {
+	privateKey, publicKey, err := loadPrivateKey(algorithm, privateKeyFilename)
+	if err != nil {
+		return
+	}
+
+	if publicKeyFilename != "" {
+		var filePublicKey crypto.PublicKey
+		filePublicKey, err = loadPublicKey(algorithm, publicKeyFilename)
+		if err != nil {
+			return
+		}
+		if !samePublicKey(publicKey, filePublicKey) {
+			err = ErrKeyPairMismatch
+			return
+		}
+	}
+
+	return NewSigningKey(algorithm, privateKey, publicKey)
+}
+
+func (k SigningKey) isRetired(now time.Time) bool {
+	return k.RetiredAt != nil && !k.RetiredAt.After(now)
+}
+
+func (k SigningKey) isActive(now time.Time) bool {
+	return k.PrivateKey != nil && !k.ActivatedAt.After(now) && !k.isRetired(now)
+}
+
+// KeyRing is a collection of behavior of signing key rotation.
+type KeyRing interface {
+	ActiveKey() (key SigningKey, err error)
+	VerificationKey(ID string) (key SigningKey, err error)
+	PublicKeys() (keys []SigningKey)
+}
+
+type keyRing struct {
+	keys []SigningKey
+}
+
+// NewKeyRing is a constructor.
+func NewKeyRing(keys ...SigningKey) KeyRing {
+	sorted := make([]SigningKey, len(keys))
+	copy(sorted, keys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActivatedAt.After(sorted[j].ActivatedAt)
+	})
+
+	return &keyRing{keys: sorted}
+}
+
+// ActiveKey returns the most recently activated key that is not retired yet.
+func (r *keyRing) ActiveKey() (key SigningKey, err error) {
+	now := time.Now()
+	for _, k := range r.keys {
+		if k.isActive(now) {
+			return k, nil
+		}
+	}
+
+	return key, ErrNoActiveKey
+}
+
+// VerificationKey returns the non-retired key of the ID, keys that are not activated yet can already verify.
+func (r *keyRing) VerificationKey(ID string) (key SigningKey, err error) {
+	now := time.Now()
+	for _, k := range r.keys {
+		if k.ID == ID && !k.isRetired(now) {
+			return k, nil
+		}
+	}
+
+	return key, ErrUnknownKey
+}
+
+// PublicKeys returns every non-retired key, so other services learn a key before it signs.
+func (r *keyRing) PublicKeys() (keys []SigningKey) {
+	now := time.Now()
+	for _, k := range r.keys {
+		if !k.isRetired(now) {
+			keys = append(keys, k)
+		}
+	}
+
+	return
+}
+
+type keyRingManifestEntry struct {
+	ID          string     `json:"kid"`
+	Algorithm   string     `json:"alg"`
+	PrivateKey  string     `json:"privateKey"`
+	PublicKey   string     `json:"publicKey"`
+	ActivatedAt time.Time  `json:"activatedAt"`
+	RetiredAt   *time.Time `json:"retiredAt"`
+}
+
+// LoadKeyRing will load the keys listed in the manifest of the directory.
+// Key files are relative to the directory and the private key may be omitted for verification-only keys.
+// The algorithm defaults to RS256.
+//
+//	[{"kid": "2021-10", "alg": "ES256", "privateKey": "2021-10.pem", "publicKey": "2021-10.pub", "activatedAt": "2021-10-01T00:00:00Z", "retiredAt": null}]
+func LoadKeyRing(directory string) (KeyRing, error) {
+	manifest, err := ioutil.ReadFile(filepath.Join(directory, KeyRingManifestFilename))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []keyRingManifestEntry
+	if err = json.Unmarshal(manifest, &entries); err != nil {
+		return nil, err
+	}
+
+	keys := make([]SigningKey, 0, len(entries))
+	for _, entry := range entries {
+		key := SigningKey{
+			ID:          entry.ID,
+			Algorithm:   entry.Algorithm,
+			ActivatedAt: entry.ActivatedAt,
+			RetiredAt:   entry.RetiredAt,
+		}
+		if key.Algorithm == "" {
+			key.Algorithm = AlgorithmRS256
+		}
+
+		if entry.PrivateKey != "" {
+			key.PrivateKey, key.PublicKey, err = loadPrivateKey(key.Algorithm, filepath.Join(directory, entry.PrivateKey))
+			if err != nil {
+				return nil, err
+			}
+		}
+		if entry.PublicKey != "" {
+			var publicKey crypto.PublicKey
+			publicKey, err = loadPublicKey(key.Algorithm, filepath.Join(directory, entry.PublicKey))
+			if err != nil {
+				return nil, err
+			}
+			// a public key file of another pair would publish a key that cannot verify what this one signs.
+			if key.PublicKey != nil && !samePublicKey(key.PublicKey, publicKey) {
+				return nil, fmt.Errorf("key %q: %w", entry.ID, ErrKeyPairMismatch)
+			}
+			key.PublicKey = publicKey
+		}
+		if key.PublicKey == nil {
+			return nil, fmt.Errorf("missing public key of %q", entry.ID)
+		}
+		if err = validateKey(key.Algorithm, key.PublicKey); err != nil {
+			return nil, fmt.Errorf("key %q: %w", entry.ID, err)
+		}
+		if key.ID == "" {
+			key.ID = thumbprint(key)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return NewKeyRing(keys...), nil
+}
+
+// thumbprint returns the RFC 7638 JWK thumbprint of the public key.
+func thumbprint(key SigningKey) string {
+	jwk := jsonWebKey(key)
+
+	var members string
+	switch jwk.KeyType {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, jwk.Curve, jwk.X, jwk.Y)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":"%s","kty":"OKP","x":"%s"}`, jwk.Curve, jwk.X)
+	default:
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
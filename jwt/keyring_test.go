@@ -0,0 +1,103 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+)
+
+func newSigningKey(t *testing.T, ID string, activatedAt time.Time, retiredAt *time.Time) jwt.SigningKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return jwt.SigningKey{
+		ID:          ID,
+		Algorithm:   jwt.AlgorithmRS256,
+		PrivateKey:  privateKey,
+		PublicKey:   &privateKey.PublicKey,
+		ActivatedAt: activatedAt,
+		RetiredAt:   retiredAt,
+	}
+}
+
+func TestJSONWebToken_Rotation(t *testing.T) {
+	now := time.Now()
+	oldKey := newSigningKey(t, "old", now.Add(-time.Hour*48), nil)
+	newKey := newSigningKey(t, "new", now.Add(-time.Hour), nil)
+	nextKey := newSigningKey(t, "next", now.Add(time.Hour), nil)
+
+	claims := entity.AccountStandardJWTClaims{Email: "johndoe@mail.com"}
+	claims.ExpiresAt = now.Add(time.Minute).Unix()
+
+	oldTokenString, err := jwt.NewJSONWebToken(jwt.NewKeyRing(oldKey), jwt.Options{}).Sign(context.TODO(), claims)
+	assert.NoError(t, err)
+
+	jsonWebToken := jwt.NewJSONWebToken(jwt.NewKeyRing(oldKey, newKey, nextKey), jwt.Options{})
+	newTokenString, err := jsonWebToken.Sign(context.TODO(), claims)
+	assert.NoError(t, err)
+
+	activeKey, err := jwt.NewKeyRing(oldKey, newKey, nextKey).ActiveKey()
+	assert.NoError(t, err)
+	assert.Equal(t, "new", activeKey.ID)
+
+	assert.NoError(t, jsonWebToken.Parse(context.TODO(), oldTokenString, &entity.AccountStandardJWTClaims{}))
+	assert.NoError(t, jsonWebToken.Parse(context.TODO(), newTokenString, &entity.AccountStandardJWTClaims{}))
+
+	retiredAt := now.Add(-time.Minute)
+	oldKey.RetiredAt = &retiredAt
+	jsonWebToken = jwt.NewJSONWebToken(jwt.NewKeyRing(oldKey, newKey, nextKey), jwt.Options{})
+
+	assert.Equal(t, jwt.ErrInvalidToken, jsonWebToken.Parse(context.TODO(), oldTokenString, &entity.AccountStandardJWTClaims{}))
+	assert.NoError(t, jsonWebToken.Parse(context.TODO(), newTokenString, &entity.AccountStandardJWTClaims{}))
+}
+
+func writeKeyPair(t *testing.T, directory string, name string) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes})
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, name+".pem"), privateKeyPEM, 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, name+".pub"), publicKeyPEM, 0600))
+}
+
+func TestLoadKeyRing_KeyPairMismatch(t *testing.T) {
+	directory := t.TempDir()
+	writeKeyPair(t, directory, "2021-10")
+	writeKeyPair(t, directory, "2021-11")
+
+	manifest := `[{"kid": "2021-10", "privateKey": "2021-10.pem", "publicKey": "2021-10.pub"}]`
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, jwt.KeyRingManifestFilename), []byte(manifest), 0600))
+
+	_, err := jwt.LoadKeyRing(directory)
+	assert.NoError(t, err)
+
+	manifest = `[{"kid": "2021-10", "privateKey": "2021-10.pem", "publicKey": "2021-11.pub"}]`
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, jwt.KeyRingManifestFilename), []byte(manifest), 0600))
+
+	_, err = jwt.LoadKeyRing(directory)
+	assert.True(t, errors.Is(err, jwt.ErrKeyPairMismatch))
+
+	_, err = jwt.LoadSigningKey(jwt.AlgorithmRS256, filepath.Join(directory, "2021-10.pem"), filepath.Join(directory, "2021-11.pub"))
+	assert.Equal(t, jwt.ErrKeyPairMismatch, err)
+}
@@ -0,0 +1,74 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/session"
+)
+
+// Key formats of the revocation list.
+const (
+	RevokedTokenKeyFormat         = "jwt:revoked:%s"
+	RevokedSubjectBeforeKeyFormat = "jwt:revoked-before:%s"
+)
+
+// TokenRevocation is a collection of behavior of server-side token revocation.
+type TokenRevocation interface {
+	Revoke(ctx context.Context, tokenID string) (err error)
+	RevokeAll(ctx context.Context, subject string, before time.Time) (err error)
+	IsRevoked(ctx context.Context, tokenID string, subject string, issuedAt time.Time) (revoked bool, err error)
+}
+
+type tokenRevocation struct {
+	session session.Session
+}
+
+// NewTokenRevocation is a constructor.
+// The session max age must be at least as long as the longest lived token it guards.
+func NewTokenRevocation(session session.Session) TokenRevocation {
+	return &tokenRevocation{session}
+}
+
+// Revoke will put the token ID in the revocation list.
+func (t *tokenRevocation) Revoke(ctx context.Context, tokenID string) (err error) {
+	return t.session.Set(ctx, fmt.Sprintf(RevokedTokenKeyFormat, tokenID), []byte("1"))
+}
+
+// RevokeAll will revoke every token of the subject issued before the second of the given time. Tokens carry
+// their issue time in whole seconds, those issued within that second are left to their session to revoke.
+func (t *tokenRevocation) RevokeAll(ctx context.Context, subject string, before time.Time) (err error) {
+	value := strconv.FormatInt(before.Unix(), 10)
+	return t.session.Set(ctx, fmt.Sprintf(RevokedSubjectBeforeKeyFormat, subject), []byte(value))
+}
+
+// IsRevoked reports whether the token was revoked by its ID or along with every token of its subject.
+// An empty token ID only checks the subject.
+func (t *tokenRevocation) IsRevoked(ctx context.Context, tokenID string, subject string, issuedAt time.Time) (revoked bool, err error) {
+	if tokenID != "" {
+		_, err = t.session.Get(ctx, fmt.Sprintf(RevokedTokenKeyFormat, tokenID))
+		if err == nil {
+			return true, nil
+		}
+		if err != session.ErrSessionNotFound {
+			return
+		}
+	}
+
+	value, err := t.session.Get(ctx, fmt.Sprintf(RevokedSubjectBeforeKeyFormat, subject))
+	if err == session.ErrSessionNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return
+	}
+
+	before, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return
+	}
+
+	return issuedAt.Unix() < before, nil
+}
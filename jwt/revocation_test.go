@@ -0,0 +1,87 @@
+package jwt_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+	"github.com/sangianpatrick/devoria-article-service/session"
+
+	redismock "github.com/go-redis/redismock/v8"
+)
+
+func TestTokenRevocation_IsRevoked_ByTokenID(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	mock.ExpectGet("jwt:revoked:token-id").SetVal("1")
+
+	revocation := jwt.NewTokenRevocation(session.NewRedisSessionStoreAdapter(rdb, time.Minute))
+	revoked, err := revocation.IsRevoked(context.TODO(), "token-id", "14", time.Now())
+
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTokenRevocation_IsRevoked_BySubject(t *testing.T) {
+	issuedAt := time.Now().Add(-time.Minute)
+	rdb, mock := redismock.NewClientMock()
+	mock.ExpectGet("jwt:revoked:token-id").RedisNil()
+	mock.ExpectGet("jwt:revoked-before:14").SetVal(fmt.Sprintf("%d", time.Now().Unix()))
+
+	revocation := jwt.NewTokenRevocation(session.NewRedisSessionStoreAdapter(rdb, time.Minute))
+	revoked, err := revocation.IsRevoked(context.TODO(), "token-id", "14", issuedAt)
+
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTokenRevocation_IsRevoked_IssuedAfterRevocation(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	mock.ExpectGet("jwt:revoked:token-id").RedisNil()
+	mock.ExpectGet("jwt:revoked-before:14").SetVal(fmt.Sprintf("%d", time.Now().Add(-time.Hour).Unix()))
+
+	revocation := jwt.NewTokenRevocation(session.NewRedisSessionStoreAdapter(rdb, time.Minute))
+	revoked, err := revocation.IsRevoked(context.TODO(), "token-id", "14", time.Now())
+
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTokenRevocation_RevokeAll_SameSecond(t *testing.T) {
+	revokedAt := time.Unix(1700000000, 900000000)
+	rdb, mock := redismock.NewClientMock()
+	mock.ExpectSetEX("jwt:revoked-before:14", []byte("1700000000"), time.Minute).SetVal("OK")
+	mock.ExpectGet("jwt:revoked-before:14").SetVal("1700000000")
+	mock.ExpectGet("jwt:revoked-before:14").SetVal("1700000000")
+
+	revocation := jwt.NewTokenRevocation(session.NewRedisSessionStoreAdapter(rdb, time.Minute))
+	assert.NoError(t, revocation.RevokeAll(context.TODO(), "14", revokedAt))
+
+	// a token signed right after the revocation carries the same whole second.
+	revoked, err := revocation.IsRevoked(context.TODO(), "", "14", time.Unix(1700000000, 0))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = revocation.IsRevoked(context.TODO(), "", "14", time.Unix(1699999999, 0))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
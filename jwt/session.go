@@ -0,0 +1,9 @@
+package jwt
+
+import "context"
+
+// SessionValidator tells whether the session a token was issued for has not been signed out.
+// A token without a session ID is never active.
+type SessionValidator interface {
+	IsSessionActive(ctx context.Context, subject string, sessionID string) (active bool, err error)
+}
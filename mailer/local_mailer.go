@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LocalMailer is a concrete struct of mailer for local development, it never delivers anything.
+type LocalMailer struct {
+	logger *logrus.Logger
+	from   string
+}
+
+// NewLocalMailer is a constructor.
+// Mails are appended to the file as JSON lines, or written to the standard logger when the filename is empty.
+func NewLocalMailer(from string, filename string) (Mailer, error) {
+	logger := logrus.New()
+	if filename != "" {
+		file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, err
+		}
+		logger.SetOutput(file)
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	return &LocalMailer{
+		logger: logger,
+		from:   from,
+	}, nil
+}
+
+// Send will write the mail.
+func (m *LocalMailer) Send(ctx context.Context, mail Mail) (err error) {
+	m.logger.WithFields(logrus.Fields{
+		"from":    m.from,
+		"to":      mail.To,
+		"subject": mail.Subject,
+		"body":    mail.Body,
+	}).Info("mail sent")
+
+	return
+}
@@ -0,0 +1,23 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+)
+
+// Errors
+var (
+	ErrUnexpected = fmt.Errorf("unexpected mailer error")
+)
+
+// Mail is a collection of property of mail.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is collection of behavior of mail delivery.
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) (err error)
+}
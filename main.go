@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -20,12 +21,21 @@ import (
 	"github.com/sangianpatrick/devoria-article-service/crypto"
 	"github.com/sangianpatrick/devoria-article-service/domain/account"
 	"github.com/sangianpatrick/devoria-article-service/domain/article"
+	"github.com/sangianpatrick/devoria-article-service/domain/authz"
+	"github.com/sangianpatrick/devoria-article-service/hasher"
 	"github.com/sangianpatrick/devoria-article-service/jwt"
+	"github.com/sangianpatrick/devoria-article-service/mailer"
 	"github.com/sangianpatrick/devoria-article-service/middleware"
+	"github.com/sangianpatrick/devoria-article-service/oidc"
+	"github.com/sangianpatrick/devoria-article-service/reencryption"
 	"github.com/sangianpatrick/devoria-article-service/session"
+	"github.com/sangianpatrick/devoria-article-service/throttle"
 )
 
 func main() {
+	reencrypt := flag.Bool("reencrypt", false, "re-encrypt the encrypted columns under the active key and exit")
+	flag.Parse()
+
 	location, _ := time.LoadLocation("Asia/Jakarta")
 	cfg := config.New()
 
@@ -46,20 +56,176 @@ func main() {
 
 	vld := validator.New()
 	encryption := crypto.NewAES256CBC(cfg.AES.SecretKey)
-	jsonWebToken := jwt.NewJSONWebToken(jwt.GetRSAPrivateKey("./secret/id_rsa"), jwt.GetRSAPublicKey("./secret/id_rsa.pub"))
+	var cipher crypto.Cipher
+	if len(cfg.Encryption.Keys) > 0 {
+		cipher, err = crypto.NewKeyRingCipher(cfg.Encryption.Version, crypto.KeyRing{ActiveKeyID: cfg.Encryption.ActiveKeyID, Secrets: cfg.Encryption.Keys}, cfg.AES.SecretKey, cfg.GlobalIV)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	// with a key manager new values are envelopes, the key ring still decrypts the former ones.
+	switch cfg.Encryption.KeyManager {
+	case "file":
+		keyManager, err := crypto.NewFileKeyManager(cfg.Encryption.ActiveKeyFileID, cfg.Encryption.KeyFiles)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cipher = crypto.NewEnvelopeCipher(keyManager, cipher, cfg.Encryption.DataKeyCacheTTL, cfg.Encryption.DataKeyCacheSize)
+	case "vault":
+		keyManager := crypto.NewVaultTransitKeyManager(&http.Client{Timeout: time.Second * 10}, cfg.Vault.Address, cfg.Vault.Token, cfg.Vault.TransitMount, cfg.Vault.TransitKey)
+		cipher = crypto.NewEnvelopeCipher(keyManager, cipher, cfg.Encryption.DataKeyCacheTTL, cfg.Encryption.DataKeyCacheSize)
+	}
+	if cipher == nil {
+		log.Fatal("no encryption key configured")
+	}
+	passwordHasher := hasher.NewBcryptHasher(cfg.PasswordHasher.BcryptCost)
+	if cfg.PasswordHasher.Algorithm == "argon2id" {
+		passwordHasher = hasher.NewArgon2idHasher(cfg.PasswordHasher.Argon2Time, cfg.PasswordHasher.Argon2Memory, cfg.PasswordHasher.Argon2Threads)
+	}
+	var keyRing jwt.KeyRing
+	if cfg.JWT.KeyDirectory != "" {
+		keyRing, err = jwt.LoadKeyRing(cfg.JWT.KeyDirectory)
+	} else {
+		var signingKey jwt.SigningKey
+		signingKey, err = jwt.LoadSigningKey(cfg.JWT.Algorithm, cfg.JWT.PrivateKeyFile, cfg.JWT.PublicKeyFile)
+		keyRing = jwt.NewKeyRing(signingKey)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	jsonWebToken := jwt.NewJSONWebToken(keyRing, jwt.Options{
+		Issuer:   cfg.JWT.Issuer,
+		Audience: cfg.JWT.Audience,
+		Leeway:   cfg.JWT.Leeway,
+	})
 	sess := session.NewRedisSessionStoreAdapter(rc, time.Hour*24*1)
+	refreshTokenSess := session.NewRedisSessionStoreAdapter(rc, cfg.JWT.RefreshTokenTTL)
+	passwordResetSess := session.NewRedisSessionStoreAdapter(rc, cfg.Account.PasswordResetTTL)
+	emailVerificationSess := session.NewRedisSessionStoreAdapter(rc, cfg.Account.EmailVerificationTTL)
+	emailResendSess := session.NewRedisSessionStoreAdapter(rc, cfg.Account.EmailVerificationResendInterval)
+	mfaChallengeSess := session.NewRedisSessionStoreAdapter(rc, cfg.Account.MFAChallengeTTL)
+	emailChangeUndoSess := session.NewRedisSessionStoreAdapter(rc, cfg.Account.EmailChangeUndoTTL)
+	oidcStateSess := session.NewRedisSessionStoreAdapter(rc, cfg.OIDC.StateTTL)
+	reencryptionSess := session.NewRedisSessionStoreAdapter(rc, time.Hour*24*7)
+	accountRepository := account.NewAccountRepository(db, "account")
+	encryptedColumns := []reencryption.Column{{Table: "account", Key: "id", Name: "totpSecret"}}
+	if cfg.Account.EncryptPersonalData {
+		blindIndex, err := crypto.NewHMACBlindIndex(cfg.Encryption.BlindIndexKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+		accountRepository = account.NewEncryptedAccountRepository(db, "account", cipher, blindIndex)
+		// the accounts still in plaintext are left to the personal data encryption.
+		for _, name := range []string{"email", "firstName", "lastName"} {
+			encryptedColumns = append(encryptedColumns, reencryption.Column{Table: "account", Key: "id", Name: name, Condition: "personalDataEncrypted = TRUE"})
+		}
+		// new accounts are only checked against the email index, the accounts still in plaintext need it before serving.
+		// every instance has to encrypt, one left in plaintext would neither index new accounts nor read encrypted ones.
+		if err := inBatches(context.Background(), cfg.Encryption.ReencryptionBatchSize, accountRepository.IndexEmails); err != nil {
+			log.Fatal(err)
+		}
+	}
+	reencryptionJob := reencryption.NewJob(db, cipher, reencryptionSess, cfg.Encryption.ReencryptionBatchSize, nil, encryptedColumns...)
+	if *reencrypt {
+		if err := inBatches(context.Background(), cfg.Encryption.ReencryptionBatchSize, accountRepository.IndexLoginLockouts); err != nil {
+			log.Fatal(err)
+		}
+		if err := inBatches(context.Background(), cfg.Encryption.ReencryptionBatchSize, accountRepository.EncryptPersonalData); err != nil {
+			log.Fatal(err)
+		}
+		if _, err := reencryptionJob.Reencrypt(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		db.Close()
+		return
+	}
+	localMailer, err := mailer.NewLocalMailer(cfg.Mailer.From, cfg.Mailer.LocalFile)
+	if err != nil {
+		log.Fatal(err)
+	}
 	basicAuthMiddleware := middleware.NewBasicAuth(cfg.BasicAuth.Username, cfg.BasicAuth.Password)
-	jwtAuthMiddleware := jwt.NewJwtToken(jsonWebToken)
+	tokenRevocation := jwt.NewTokenRevocation(refreshTokenSess)
+	// a client address may be shared by many honest users, so it is allowed proportionally more failures.
+	emailThrottlePolicy := throttle.Policy{
+		FreeAttempts:     cfg.LoginThrottle.FreeAttempts,
+		BaseDelay:        cfg.LoginThrottle.BaseDelay,
+		MaxDelay:         cfg.LoginThrottle.MaxDelay,
+		LockoutThreshold: cfg.LoginThrottle.LockoutThreshold,
+		LockoutDuration:  cfg.LoginThrottle.LockoutDuration,
+		Window:           cfg.LoginThrottle.Window,
+	}
+	clientIPThrottlePolicy := emailThrottlePolicy
+	clientIPThrottlePolicy.FreeAttempts *= cfg.LoginThrottle.ClientIPAttemptsFactor
+	clientIPThrottlePolicy.LockoutThreshold *= cfg.LoginThrottle.ClientIPAttemptsFactor
+	emailThrottle := throttle.NewRedisThrottle(rc, "login-email", emailThrottlePolicy)
+	clientIPThrottle := throttle.NewRedisThrottle(rc, "login-client-ip", clientIPThrottlePolicy)
+	deviceSessionRepository := account.NewDeviceSessionRepository(rc, cfg.JWT.RefreshTokenTTL)
+	jwtAuthMiddleware := jwt.NewJwtToken(jsonWebToken, tokenRevocation, deviceSessionRepository)
 
 	router := mux.NewRouter()
+	jwt.NewJWKSHTTPHandler(router, keyRing)
 
-	accountRepository := account.NewAccountRepository(db, "account")
-	accountUsecase := account.NewAccountUsecase(cfg.GlobalIV, sess, jsonWebToken, encryption, location, accountRepository)
+	articleRepository := article.NewArticleRepository(db, "article", location)
+	authoredArticleRepository := article.NewAuthoredArticleRepository(articleRepository)
+
+	// the default grants are seeded into an empty database only, then they are managed there.
+	authzRepository := authz.NewAuthzRepository(db, "role", "permission")
+	if err = authzRepository.Seed(context.Background(), authz.DefaultRolePermissions, time.Now().In(location)); err != nil {
+		log.Println(err)
+	}
+	authorizer := authz.NewAuthorizer(cfg.Authz.PermissionCacheTTL, authzRepository)
+
+	accountPolicy := account.AccountPolicy{
+		RequireVerifiedEmailToLogin: cfg.Account.RequireVerifiedEmailToLogin,
+		DeletionGracePeriod:         cfg.Account.DeletionGracePeriod,
+	}
+	var oidcProvider oidc.Provider
+	if cfg.OIDC.Issuer != "" {
+		oidcProvider = oidc.NewHTTPProvider(&http.Client{Timeout: time.Second * 10}, oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+			Leeway:       cfg.OIDC.Leeway,
+		})
+	}
+	accountUsecase := account.NewAccountUsecase(account.AccountUsecaseDependencies{
+		GlobalIV:                  cfg.GlobalIV,
+		FrontendURL:               cfg.App.FrontendURL,
+		TOTPIssuer:                cfg.Account.TOTPIssuer,
+		DeviceSessionRepository:   deviceSessionRepository,
+		RefreshTokenSession:       refreshTokenSess,
+		PasswordResetSession:      passwordResetSess,
+		EmailVerificationSession:  emailVerificationSess,
+		EmailResendSession:        emailResendSess,
+		MFAChallengeSession:       mfaChallengeSess,
+		EmailChangeUndoSession:    emailChangeUndoSess,
+		OIDCStateSession:          oidcStateSess,
+		JSONWebToken:              authz.NewJSONWebToken(jsonWebToken, authorizer),
+		TokenRevocation:           tokenRevocation,
+		OIDCProvider:              oidcProvider,
+		EmailThrottle:             emailThrottle,
+		ClientIPThrottle:          clientIPThrottle,
+		AccessTokenTTL:            cfg.JWT.AccessTokenTTL,
+		Policy:                    accountPolicy,
+		Crypto:                    encryption,
+		Cipher:                    cipher,
+		PasswordHasher:            passwordHasher,
+		Mailer:                    localMailer,
+		Location:                  location,
+		AuthoredArticleRepository: authoredArticleRepository,
+		RoleRepository:            authzRepository,
+		Repository:                accountRepository,
+	})
 	account.NewAccountHTTPHandler(router, basicAuthMiddleware, jwtAuthMiddleware, vld, accountUsecase)
+	account.NewAccountAdminHTTPHandler(router, jwtAuthMiddleware, authorizer, vld, accountUsecase)
+	accountPurger := account.NewAccountPurger(cfg.Account.DeletionGracePeriod, location, authoredArticleRepository, accountRepository)
 
-	articleRepository := article.NewArticleRepository(db, "article", location)
-	articleUsecase := article.NewArticleUsecase(sess, location, articleRepository)
-	article.NewAccountHTTPHandler(router, basicAuthMiddleware, jwtAuthMiddleware, vld, articleUsecase)
+	articleUsecase := article.NewArticleUsecase(sess, cfg.Account.RequireVerifiedEmailToPublish, location, accountRepository, articleRepository)
+	// article routes also accept personal api keys, the account routes never do.
+	apiKeyAuthMiddleware := account.NewAPIKeyAuth(jwtAuthMiddleware, location, accountRepository)
+	article.NewAccountHTTPHandler(router, basicAuthMiddleware, apiKeyAuthMiddleware, authorizer, vld, articleUsecase)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%s", cfg.App.Port),
@@ -70,13 +236,42 @@ func main() {
 		log.Fatal(server.ListenAndServe())
 	}()
 
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	go accountPurger.Run(jobCtx, cfg.Account.DeletionPurgeInterval)
+	if cfg.Account.EncryptPersonalData {
+		go func() {
+			if err := inBatches(jobCtx, cfg.Encryption.ReencryptionBatchSize, accountRepository.IndexLoginLockouts); err != nil {
+				log.Printf("login lockout indexing stopped: %v\n", err)
+			}
+			if err := inBatches(jobCtx, cfg.Encryption.ReencryptionBatchSize, accountRepository.EncryptPersonalData); err != nil {
+				log.Printf("personal data encryption stopped: %v\n", err)
+			}
+		}()
+	}
+	if cfg.Encryption.ReencryptionInterval > 0 {
+		go reencryptionJob.Run(jobCtx, cfg.Encryption.ReencryptionInterval)
+	}
+
 	sigterm := make(chan os.Signal, 1)
 	signal.Notify(sigterm, syscall.SIGTERM, syscall.SIGINT)
 	<-sigterm
 
 	fmt.Println("shutting down application ...")
 
+	stopJobs()
 	server.Shutdown(context.Background())
 	db.Close()
 	rc.Close()
 }
+
+// inBatches runs the batch from the first row on, until a batch reads fewer rows than the batch size.
+func inBatches(ctx context.Context, batchSize int, batch func(ctx context.Context, afterID int64, limit int) (lastID int64, scanned int, err error)) (err error) {
+	var lastID int64
+	for {
+		var scanned int
+		lastID, scanned, err = batch(ctx, lastID, batchSize)
+		if err != nil || scanned < batchSize {
+			return
+		}
+	}
+}
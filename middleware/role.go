@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/context"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/response"
+)
+
+// Role is a concrete struct of role verifier. It must run behind the jwt middleware, which binds the claims.
+type Role struct {
+	roles []string
+}
+
+// NewRole is a constructor, the request is let through when the role claim is any of the given roles.
+func NewRole(roles ...string) RouteMiddleware {
+	return &Role{roles}
+}
+
+// Verify will verify the request to ensure its account holds one of the allowed roles.
+func (m *Role) Verify(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var claims entity.AccountStandardJWTClaims
+		bind, ok := context.Get(r, "bind").([]byte)
+		if !ok || json.Unmarshal(bind, &claims) != nil {
+			resp := response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+			resp.JSON(w)
+			return
+		}
+
+		for _, role := range m.roles {
+			if claims.Role == role {
+				next(w, r)
+				return
+			}
+		}
+
+		resp := response.Error(response.StatusForbiddend, nil, exception.ErrForbidden)
+		resp.JSON(w)
+	})
+}
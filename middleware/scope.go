@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/context"
+	"github.com/sangianpatrick/devoria-article-service/entity"
+	"github.com/sangianpatrick/devoria-article-service/exception"
+	"github.com/sangianpatrick/devoria-article-service/response"
+)
+
+// Scope is a concrete struct of scope verifier. It must run behind the jwt middleware, which binds the claims.
+type Scope struct {
+	scope string
+}
+
+// NewScope is a constructor, the request is let through when the claims hold the scope or hold no scope at all.
+func NewScope(scope string) RouteMiddleware {
+	return &Scope{scope}
+}
+
+// Verify will verify the request to ensure its claims are not restricted to other scopes.
+func (m *Scope) Verify(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var claims entity.AccountStandardJWTClaims
+		bind, ok := context.Get(r, "bind").([]byte)
+		if !ok || json.Unmarshal(bind, &claims) != nil {
+			resp := response.Error(response.StatusUnauthorized, nil, exception.ErrUnauthorized)
+			resp.JSON(w)
+			return
+		}
+
+		if len(claims.Scopes) == 0 {
+			next(w, r)
+			return
+		}
+
+		for _, scope := range claims.Scopes {
+			if scope == m.scope {
+				next(w, r)
+				return
+			}
+		}
+
+		resp := response.Error(response.StatusForbiddend, nil, exception.ErrForbidden)
+		resp.JSON(w)
+	})
+}
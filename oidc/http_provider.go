@@ -0,0 +1,332 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	// jwksRefreshInterval keeps tokens with made up key IDs from fetching the key set on every request.
+	jwksRefreshInterval = time.Minute
+	maxResponseSize     = 1 << 20
+)
+
+// discoveryDocument is the part of the provider metadata the authorization code flow needs.
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// HTTPProvider is a concrete struct of an OpenID Connect provider reached over http. The discovery document
+// is fetched on first use and the key set again whenever an id token is signed by an unknown key.
+type HTTPProvider struct {
+	logger     *logrus.Logger
+	config     Config
+	httpClient *http.Client
+
+	mutex         sync.Mutex
+	discovery     *discoveryDocument
+	keys          map[string]jwt.JSONWebKey
+	keysFetchedAt time.Time
+}
+
+// NewHTTPProvider is a constructor.
+func NewHTTPProvider(httpClient *http.Client, config Config) Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &HTTPProvider{
+		logger:     logrus.New(),
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+// Issuer returns the configured issuer, the identities of the provider are scoped by it.
+func (p *HTTPProvider) Issuer() string {
+	return p.config.Issuer
+}
+
+// AuthCodeURL returns the url of the authorization endpoint the user agent is sent to.
+func (p *HTTPProvider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (authorizationURL string, err error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return
+	}
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.config.ClientID)
+	values.Set("redirect_uri", p.config.RedirectURL)
+	values.Set("scope", strings.Join(p.config.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", codeChallenge)
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+// Exchange redeems the authorization code at the token endpoint and returns the id token.
+// The client secret, when configured, is sent with basic authentication.
+func (p *HTTPProvider) Exchange(ctx context.Context, code string, codeVerifier string) (idToken string, err error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return
+	}
+
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", p.config.RedirectURL)
+	values.Set("code_verifier", codeVerifier)
+	if p.config.ClientSecret == "" {
+		values.Set("client_id", p.config.ClientID)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		p.logger.Error(err)
+		return "", ErrUnexpected
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	status, err := p.do(request, &tokenResponse)
+	if err != nil {
+		return
+	}
+	if status != http.StatusOK || tokenResponse.IDToken == "" {
+		return "", ErrExchangeFailed
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+// VerifyIDToken checks the signature of the id token against the key set of the provider, then its issuer,
+// audience, expiry and nonce. Only asymmetric algorithms are accepted, the key pins the one in use.
+func (p *HTTPProvider) VerifyIDToken(ctx context.Context, idToken string, nonce string) (claims IDTokenClaims, err error) {
+	parser := jwtgo.Parser{
+		ValidMethods:         []string{jwt.AlgorithmRS256, jwt.AlgorithmES256},
+		SkipClaimsValidation: true,
+	}
+	token, err := parser.ParseWithClaims(idToken, &claims, func(token *jwtgo.Token) (interface{}, error) {
+		return p.verificationKey(ctx, token)
+	})
+	if err != nil {
+		if ve, ok := err.(*jwtgo.ValidationError); ok && ve.Inner == ErrUnexpected {
+			return claims, ErrUnexpected
+		}
+		return claims, ErrInvalidIDToken
+	}
+	if !token.Valid {
+		return claims, ErrInvalidIDToken
+	}
+
+	if claims.Issuer != p.config.Issuer {
+		return claims, ErrInvalidIssuer
+	}
+	if !claims.Audience.Contains(p.config.ClientID) {
+		return claims, ErrInvalidAudience
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return claims, ErrInvalidAudience
+	}
+	if claims.ExpiresAt == 0 || time.Now().Add(-p.config.Leeway).Unix() > claims.ExpiresAt {
+		return claims, ErrExpiredIDToken
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return claims, ErrInvalidNonce
+	}
+	if claims.Subject == "" {
+		return claims, ErrInvalidIDToken
+	}
+
+	return claims, nil
+}
+
+// verificationKey returns the public key of the kid header. A token without kid is only accepted
+// when the provider publishes a single key.
+func (p *HTTPProvider) verificationKey(ctx context.Context, token *jwtgo.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	jwk, err := p.findKey(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Algorithm != "" && jwk.Algorithm != token.Method.Alg() {
+		return nil, ErrInvalidIDToken
+	}
+	if jwk.Use != "" && jwk.Use != "sig" {
+		return nil, ErrInvalidIDToken
+	}
+
+	publicKey, err := jwk.PublicKey()
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	switch token.Method.Alg() {
+	case jwt.AlgorithmRS256:
+		if _, ok := publicKey.(*rsa.PublicKey); !ok {
+			return nil, ErrInvalidIDToken
+		}
+	case jwt.AlgorithmES256:
+		if _, ok := publicKey.(*ecdsa.PublicKey); !ok {
+			return nil, ErrInvalidIDToken
+		}
+	}
+
+	return publicKey, nil
+}
+
+func (p *HTTPProvider) findKey(ctx context.Context, kid string) (jwk jwt.JSONWebKey, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	jwk, ok := p.lookupKey(kid)
+	if ok {
+		return jwk, nil
+	}
+	if !p.keysFetchedAt.IsZero() && time.Since(p.keysFetchedAt) < jwksRefreshInterval {
+		return jwk, ErrInvalidIDToken
+	}
+
+	if err = p.fetchKeys(ctx); err != nil {
+		return
+	}
+
+	jwk, ok = p.lookupKey(kid)
+	if !ok {
+		return jwk, ErrInvalidIDToken
+	}
+
+	return jwk, nil
+}
+
+func (p *HTTPProvider) lookupKey(kid string) (jwk jwt.JSONWebKey, ok bool) {
+	if kid == "" {
+		for _, jwk = range p.keys {
+			ok = len(p.keys) == 1
+		}
+		return
+	}
+
+	jwk, ok = p.keys[kid]
+	return
+}
+
+// fetchKeys replaces the cached key set, the caller must hold the mutex.
+func (p *HTTPProvider) fetchKeys(ctx context.Context) (err error) {
+	discovery, err := p.discoverLocked(ctx)
+	if err != nil {
+		return
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.JWKSURI, nil)
+	if err != nil {
+		p.logger.Error(err)
+		return ErrUnexpected
+	}
+
+	var keySet jwt.JSONWebKeySet
+	status, err := p.do(request, &keySet)
+	if err != nil {
+		return
+	}
+	if status != http.StatusOK {
+		p.logger.Errorf("unexpected status %d of the key set", status)
+		return ErrUnexpected
+	}
+
+	p.keys = make(map[string]jwt.JSONWebKey, len(keySet.Keys))
+	for _, jwk := range keySet.Keys {
+		p.keys[jwk.KeyID] = jwk
+	}
+	p.keysFetchedAt = time.Now()
+
+	return
+}
+
+func (p *HTTPProvider) discover(ctx context.Context) (discovery discoveryDocument, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.discoverLocked(ctx)
+}
+
+// discoverLocked returns the cached discovery document or fetches it, the caller must hold the mutex.
+// A document announcing another issuer is refused, as the id tokens would never validate.
+func (p *HTTPProvider) discoverLocked(ctx context.Context) (discovery discoveryDocument, err error) {
+	if p.discovery != nil {
+		return *p.discovery, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.config.Issuer, "/")+discoveryPath, nil)
+	if err != nil {
+		p.logger.Error(err)
+		return discovery, ErrUnexpected
+	}
+
+	status, err := p.do(request, &discovery)
+	if err != nil {
+		return
+	}
+	if status != http.StatusOK {
+		p.logger.Errorf("unexpected status %d of the discovery document", status)
+		return discovery, ErrUnexpected
+	}
+	if discovery.Issuer != p.config.Issuer || discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		p.logger.Errorf("invalid discovery document of %s", p.config.Issuer)
+		return discovery, ErrUnexpected
+	}
+
+	p.discovery = &discovery
+
+	return
+}
+
+// do sends the request and decodes a json body into v whatever the status, which is returned for the caller to judge.
+func (p *HTTPProvider) do(request *http.Request, v interface{}) (status int, err error) {
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		p.logger.Error(err)
+		return 0, ErrUnexpected
+	}
+	defer response.Body.Close()
+
+	if err = json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(v); err != nil && response.StatusCode == http.StatusOK {
+		p.logger.Error(err)
+		return response.StatusCode, ErrUnexpected
+	}
+
+	return response.StatusCode, nil
+}
@@ -0,0 +1,119 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/oidc"
+	"github.com/sangianpatrick/devoria-article-service/oidc/oidctest"
+)
+
+func TestHTTPProvider_AuthorizationCodeFlow(t *testing.T) {
+	server := oidctest.NewServer("devoria", "secret")
+	defer server.Close()
+
+	provider := oidc.NewHTTPProvider(http.DefaultClient, server.Config())
+	ctx := context.TODO()
+
+	authorizationURL, err := provider.AuthCodeURL(ctx, "state", "nonce", oidc.CodeChallenge("verifier"))
+	assert.NoError(t, err)
+
+	u, _ := url.Parse(authorizationURL)
+	assert.Equal(t, server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+
+	code, state, err := server.Authorize(authorizationURL, jwtgo.MapClaims{"sub": "42", "email": "johndoe@mail.com", "email_verified": true})
+	assert.NoError(t, err)
+	assert.Equal(t, "state", state)
+
+	_, err = provider.Exchange(ctx, code, "another verifier")
+	assert.Equal(t, oidc.ErrExchangeFailed, err)
+
+	code, _, _ = server.Authorize(authorizationURL, jwtgo.MapClaims{"sub": "42", "email": "johndoe@mail.com", "email_verified": true})
+	idToken, err := provider.Exchange(ctx, code, "verifier")
+	assert.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(ctx, idToken, "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", claims.Subject)
+	assert.Equal(t, "johndoe@mail.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+
+	_, err = provider.VerifyIDToken(ctx, idToken, "another nonce")
+	assert.Equal(t, oidc.ErrInvalidNonce, err)
+}
+
+func TestHTTPProvider_VerifyIDToken(t *testing.T) {
+	server := oidctest.NewServer("devoria", "secret")
+	defer server.Close()
+
+	provider := oidc.NewHTTPProvider(http.DefaultClient, server.Config())
+	now := time.Now()
+
+	testCases := map[string]struct {
+		claims jwtgo.MapClaims
+		err    error
+	}{
+		"valid": {
+			claims: jwtgo.MapClaims{"iss": server.URL, "aud": []string{"devoria", "other"}, "azp": "devoria", "sub": "42", "nonce": "nonce", "exp": now.Add(time.Minute).Unix()},
+		},
+		"another issuer": {
+			claims: jwtgo.MapClaims{"iss": "https://accounts.example.com", "aud": "devoria", "sub": "42", "nonce": "nonce", "exp": now.Add(time.Minute).Unix()},
+			err:    oidc.ErrInvalidIssuer,
+		},
+		"another audience": {
+			claims: jwtgo.MapClaims{"iss": server.URL, "aud": "other", "sub": "42", "nonce": "nonce", "exp": now.Add(time.Minute).Unix()},
+			err:    oidc.ErrInvalidAudience,
+		},
+		"another authorized party": {
+			claims: jwtgo.MapClaims{"iss": server.URL, "aud": []string{"devoria", "other"}, "azp": "other", "sub": "42", "nonce": "nonce", "exp": now.Add(time.Minute).Unix()},
+			err:    oidc.ErrInvalidAudience,
+		},
+		"expired": {
+			claims: jwtgo.MapClaims{"iss": server.URL, "aud": "devoria", "sub": "42", "nonce": "nonce", "exp": now.Add(-time.Minute).Unix()},
+			err:    oidc.ErrExpiredIDToken,
+		},
+		"without subject": {
+			claims: jwtgo.MapClaims{"iss": server.URL, "aud": "devoria", "nonce": "nonce", "exp": now.Add(time.Minute).Unix()},
+			err:    oidc.ErrInvalidIDToken,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			idToken, err := server.SignIDToken(testCase.claims)
+			assert.NoError(t, err)
+
+			_, err = provider.VerifyIDToken(context.TODO(), idToken, "nonce")
+			assert.Equal(t, testCase.err, err)
+		})
+	}
+}
+
+func TestHTTPProvider_VerifyIDToken_AlgorithmConfusion(t *testing.T) {
+	server := oidctest.NewServer("devoria", "secret")
+	defer server.Close()
+
+	provider := oidc.NewHTTPProvider(http.DefaultClient, server.Config())
+	claims := jwtgo.MapClaims{"iss": server.URL, "aud": "devoria", "sub": "42", "nonce": "nonce", "exp": time.Now().Add(time.Minute).Unix()}
+
+	token := jwtgo.NewWithClaims(jwtgo.SigningMethodHS256, claims)
+	token.Header["kid"] = oidctest.KeyID
+	idToken, _ := token.SignedString([]byte("secret"))
+
+	_, err := provider.VerifyIDToken(context.TODO(), idToken, "nonce")
+	assert.Equal(t, oidc.ErrInvalidIDToken, err)
+
+	token = jwtgo.NewWithClaims(jwtgo.SigningMethodRS256, claims)
+	token.Header["kid"] = "unknown"
+	idToken, _ = token.SignedString(server.PrivateKey)
+
+	_, err = provider.VerifyIDToken(context.TODO(), idToken, "nonce")
+	assert.Equal(t, oidc.ErrInvalidIDToken, err)
+}
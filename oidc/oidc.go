@@ -0,0 +1,96 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Errors
+var (
+	ErrUnexpected      = fmt.Errorf("unexpected identity provider error")
+	ErrExchangeFailed  = fmt.Errorf("authorization code exchange failed")
+	ErrInvalidIDToken  = fmt.Errorf("invalid id token")
+	ErrExpiredIDToken  = fmt.Errorf("id token is expired: %w", ErrInvalidIDToken)
+	ErrInvalidNonce    = fmt.Errorf("id token has an invalid nonce: %w", ErrInvalidIDToken)
+	ErrInvalidIssuer   = fmt.Errorf("id token has an invalid issuer: %w", ErrInvalidIDToken)
+	ErrInvalidAudience = fmt.Errorf("id token has an invalid audience: %w", ErrInvalidIDToken)
+)
+
+// Config is a collection of settings of the relying party. The issuer is the base of the discovery
+// document, the leeway tolerates clock skew on the expiry of the id token.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	Leeway       time.Duration
+}
+
+// Audience is the aud claim, which may be a single string or an array of them.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) (err error) {
+	var single string
+	if err = json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return
+	}
+
+	var multiple []string
+	if err = json.Unmarshal(data, &multiple); err != nil {
+		return
+	}
+	*a = Audience(multiple)
+
+	return
+}
+
+// Contains tells whether the audience holds the client ID.
+func (a Audience) Contains(clientID string) bool {
+	for _, audience := range a {
+		if audience == clientID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IDTokenClaims is a model of the claims of an id token this service relies on.
+type IDTokenClaims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        Audience `json:"aud"`
+	AuthorizedParty string   `json:"azp,omitempty"`
+	ExpiresAt       int64    `json:"exp"`
+	IssuedAt        int64    `json:"iat"`
+	Nonce           string   `json:"nonce"`
+	Email           string   `json:"email"`
+	EmailVerified   bool     `json:"email_verified"`
+	GivenName       string   `json:"given_name"`
+	FamilyName      string   `json:"family_name"`
+}
+
+// Valid satisfies jwt.Claims, the claims are checked by Provider.VerifyIDToken against the configuration instead.
+func (c *IDTokenClaims) Valid() error {
+	return nil
+}
+
+// Provider is collection of behavior of an OpenID Connect provider used for the authorization code flow with PKCE.
+type Provider interface {
+	Issuer() string
+	AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (authorizationURL string, err error)
+	Exchange(ctx context.Context, code string, codeVerifier string) (idToken string, err error)
+	VerifyIDToken(ctx context.Context, idToken string, nonce string) (claims IDTokenClaims, err error)
+}
+
+// CodeChallenge derives the S256 PKCE code challenge of the code verifier.
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,183 @@
+// Package oidctest provides a local OpenID Connect provider for tests, no network access is needed.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+
+	"github.com/sangianpatrick/devoria-article-service/jwt"
+	"github.com/sangianpatrick/devoria-article-service/oidc"
+)
+
+// KeyID is the kid of the signing key of the server.
+const KeyID = "oidctest"
+
+type grant struct {
+	codeChallenge string
+	idToken       string
+}
+
+// Server is a stub provider serving the discovery document, the key set and the token endpoint.
+// The authorization endpoint is never served, Authorize plays the user consenting instead.
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+	PrivateKey   *rsa.PrivateKey
+
+	mutex  sync.Mutex
+	grants map[string]grant
+}
+
+// NewServer starts the stub provider, it must be closed by the caller.
+func NewServer(clientID string, clientSecret string) *Server {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		PrivateKey:   privateKey,
+		grants:       map[string]grant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Config returns the relying party configuration matching the server.
+func (s *Server) Config() oidc.Config {
+	return oidc.Config{
+		Issuer:       s.URL,
+		ClientID:     s.ClientID,
+		ClientSecret: s.ClientSecret,
+		RedirectURL:  "http://localhost/callback",
+	}
+}
+
+// Authorize plays the user consenting at the authorization url. The claims complete the issuer, audience,
+// nonce and lifetime of the id token unless they are set, the returned code is redeemable once.
+func (s *Server) Authorize(authorizationURL string, claims jwtgo.MapClaims) (code string, state string, err error) {
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		return
+	}
+	query := u.Query()
+	if query.Get("client_id") != s.ClientID || query.Get("code_challenge_method") != "S256" {
+		return "", "", fmt.Errorf("invalid authorization request")
+	}
+
+	fields := jwtgo.MapClaims{
+		"iss":   s.URL,
+		"aud":   s.ClientID,
+		"nonce": query.Get("nonce"),
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}
+	for key, value := range claims {
+		fields[key] = value
+	}
+
+	idToken, err := s.SignIDToken(fields)
+	if err != nil {
+		return
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	code = hex.EncodeToString(b)
+
+	s.mutex.Lock()
+	s.grants[code] = grant{codeChallenge: query.Get("code_challenge"), idToken: idToken}
+	s.mutex.Unlock()
+
+	return code, query.Get("state"), nil
+}
+
+// SignIDToken signs the claims as they are with the key of the server.
+func (s *Server) SignIDToken(claims jwtgo.MapClaims) (idToken string, err error) {
+	token := jwtgo.NewWithClaims(jwtgo.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID
+	return token.SignedString(s.PrivateKey)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	publicKey := s.PrivateKey.PublicKey
+	keySet := jwt.JSONWebKeySet{Keys: []jwt.JSONWebKey{{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: jwt.AlgorithmRS256,
+		KeyID:     KeyID,
+		N:         base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	}}}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keySet)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostFormValue("client_id")
+	}
+	if r.Method != http.MethodPost || r.PostFormValue("grant_type") != "authorization_code" ||
+		clientID != s.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.ClientSecret)) != 1 {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	s.mutex.Lock()
+	code := r.PostFormValue("code")
+	grant, ok := s.grants[code]
+	delete(s.grants, code)
+	s.mutex.Unlock()
+
+	if !ok || oidc.CodeChallenge(r.PostFormValue("code_verifier")) != grant.codeChallenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "oidctest",
+		"token_type":   "Bearer",
+		"id_token":     grant.idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
@@ -0,0 +1,234 @@
+package reencryption
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/sangianpatrick/devoria-article-service/crypto"
+	"github.com/sangianpatrick/devoria-article-service/session"
+)
+
+// CheckpointKeyFormat is the session key of the last id rewritten in a column, a run resumes after it.
+const CheckpointKeyFormat = "reencryption:checkpoint:%s.%s"
+
+// Column is an encrypted column, rows are walked in the order of their integer key.
+// The condition, when set, restricts the rows to those holding an encrypted value.
+type Column struct {
+	Table     string
+	Key       string
+	Name      string
+	Condition string
+}
+
+func (c Column) String() string {
+	return c.Table + "." + c.Name
+}
+
+// Progress is the state of the re-encryption of a column.
+type Progress struct {
+	Column    string
+	Total     int64
+	Scanned   int64
+	Rewritten int64
+	Failed    int64
+	LastID    int64
+	Done      bool
+}
+
+// Job rewrites the encrypted columns under the active version and key of the cipher.
+type Job interface {
+	Reencrypt(ctx context.Context) (progress []Progress, err error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type jobImpl struct {
+	db         *sql.DB
+	cipher     crypto.Cipher
+	checkpoint session.Session
+	batchSize  int
+	report     func(Progress)
+	columns    []Column
+}
+
+// NewJob is a constructor, the progress is reported after every batch of every column.
+// A nil report logs it.
+func NewJob(db *sql.DB, cipher crypto.Cipher, checkpoint session.Session, batchSize int, report func(Progress), columns ...Column) Job {
+	if report == nil {
+		report = logProgress
+	}
+
+	return &jobImpl{
+		db:         db,
+		cipher:     cipher,
+		checkpoint: checkpoint,
+		batchSize:  batchSize,
+		report:     report,
+		columns:    columns,
+	}
+}
+
+func logProgress(progress Progress) {
+	log.Printf("re-encryption of %s: %d/%d scanned, %d rewritten, %d failed, last id %d\n",
+		progress.Column, progress.Scanned, progress.Total, progress.Rewritten, progress.Failed, progress.LastID)
+}
+
+// Reencrypt walks every column to its end. An interrupted column resumes after its checkpoint on the next call,
+// a column walked to its end starts over on the next call. A value that fails is left as it is and counted.
+func (j *jobImpl) Reencrypt(ctx context.Context) (progress []Progress, err error) {
+	for _, column := range j.columns {
+		columnProgress, err := j.reencryptColumn(ctx, column)
+		progress = append(progress, columnProgress)
+		if err != nil {
+			return progress, err
+		}
+	}
+
+	return
+}
+
+func (j *jobImpl) reencryptColumn(ctx context.Context, column Column) (progress Progress, err error) {
+	progress = Progress{Column: column.String()}
+	checkpointKey := fmt.Sprintf(CheckpointKeyFormat, column.Table, column.Name)
+
+	value, err := j.checkpoint.Get(ctx, checkpointKey)
+	if err != nil && err != session.ErrSessionNotFound {
+		return
+	}
+	if err == nil {
+		progress.LastID, _ = strconv.ParseInt(string(value), 10, 64)
+	}
+
+	if progress.Total, err = j.count(ctx, column); err != nil {
+		return
+	}
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		var rows []row
+		rows, err = j.findBatch(ctx, column, progress.LastID)
+		if err != nil {
+			return
+		}
+
+		for _, r := range rows {
+			progress.Scanned++
+			progress.LastID = r.id
+
+			rewritten, rewriteErr := j.rewrite(ctx, column, r)
+			if rewriteErr != nil {
+				log.Printf("failed to re-encrypt %s of id %d: %v\n", column, r.id, rewriteErr)
+				progress.Failed++
+				continue
+			}
+			if rewritten {
+				progress.Rewritten++
+			}
+		}
+
+		if len(rows) < j.batchSize {
+			progress.Done = true
+			j.report(progress)
+			err = j.checkpoint.Delete(ctx, checkpointKey)
+			if err == session.ErrSessionNotFound {
+				err = nil
+			}
+			return
+		}
+
+		if err = j.checkpoint.Set(ctx, checkpointKey, []byte(strconv.FormatInt(progress.LastID, 10))); err != nil {
+			return
+		}
+		j.report(progress)
+	}
+}
+
+type row struct {
+	id    int64
+	value string
+}
+
+// where returns the conditions of the rows holding a value of the column.
+func (c Column) where() string {
+	if c.Condition == "" {
+		return c.Name + " IS NOT NULL"
+	}
+	return fmt.Sprintf("%s IS NOT NULL AND (%s)", c.Name, c.Condition)
+}
+
+func (j *jobImpl) count(ctx context.Context, column Column) (total int64, err error) {
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, column.Table, column.where())
+	err = j.db.QueryRowContext(ctx, query).Scan(&total)
+
+	return
+}
+
+func (j *jobImpl) findBatch(ctx context.Context, column Column, afterID int64) (rows []row, err error) {
+	query := fmt.Sprintf(`SELECT %s, %s FROM %s WHERE %s > ? AND %s ORDER BY %s ASC LIMIT ?`,
+		column.Key, column.Name, column.Table, column.Key, column.where(), column.Key)
+	result, err := j.db.QueryContext(ctx, query, afterID, j.batchSize)
+	if err != nil {
+		return
+	}
+	defer result.Close()
+
+	for result.Next() {
+		var r row
+		if err = result.Scan(&r.id, &r.value); err != nil {
+			return
+		}
+		rows = append(rows, r)
+	}
+
+	return rows, result.Err()
+}
+
+// rewrite re-encrypts a value that needs a rotation. The update only applies to the value that was read,
+// a value changed meanwhile is already encrypted with the active key.
+func (j *jobImpl) rewrite(ctx context.Context, column Column, r row) (rewritten bool, err error) {
+	if !j.cipher.NeedsRotation(r.value) {
+		return false, nil
+	}
+
+	plaintext, err := j.cipher.Decrypt(ctx, r.value)
+	if err != nil {
+		return
+	}
+	encrypted, err := j.cipher.Encrypt(ctx, plaintext)
+	if err != nil {
+		return
+	}
+
+	command := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ? AND %s = ?`, column.Table, column.Name, column.Key, column.Name)
+	result, err := j.db.ExecContext(ctx, command, encrypted, r.id, r.value)
+	if err != nil {
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+
+	return rowsAffected > 0, nil
+}
+
+// Run re-encrypts the columns every interval until the context is done.
+func (j *jobImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := j.Reencrypt(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("re-encryption stopped: %v\n", err)
+			}
+		}
+	}
+}
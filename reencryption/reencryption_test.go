@@ -0,0 +1,197 @@
+package reencryption_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/crypto"
+	"github.com/sangianpatrick/devoria-article-service/reencryption"
+	"github.com/sangianpatrick/devoria-article-service/session"
+)
+
+const (
+	previousSecret = "abcdefghijklmnopqrstuvwxyz123456"
+	activeSecret   = "zyxwvutsrqponmlkjihgfedcba654321"
+)
+
+type memorySession map[string][]byte
+
+func (s memorySession) Set(ctx context.Context, key string, value []byte) error {
+	s[key] = value
+	return nil
+}
+
+func (s memorySession) Get(ctx context.Context, key string) ([]byte, error) {
+	value, ok := s[key]
+	if !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return value, nil
+}
+
+func (s memorySession) Update(ctx context.Context, key string, value []byte) error {
+	return s.Set(ctx, key, value)
+}
+
+func (s memorySession) Delete(ctx context.Context, key string) error {
+	if _, ok := s[key]; !ok {
+		return session.ErrSessionNotFound
+	}
+	delete(s, key)
+	return nil
+}
+
+func (s memorySession) CompareAndSwap(ctx context.Context, key string, old []byte, new []byte) (bool, error) {
+	if value, ok := s[key]; !ok || string(value) != string(old) {
+		return false, nil
+	}
+	s[key] = new
+	return true, nil
+}
+
+func (s memorySession) Consume(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.Get(ctx, key)
+	if err == nil {
+		delete(s, key)
+	}
+	return value, err
+}
+
+func (s memorySession) SetIfGreater(ctx context.Context, key string, value int64) (bool, error) {
+	if current, ok := s[key]; ok {
+		if number, _ := strconv.ParseInt(string(current), 10, 64); number >= value {
+			return false, nil
+		}
+	}
+	s[key] = []byte(strconv.FormatInt(value, 10))
+	return true, nil
+}
+
+func (s memorySession) Increment(ctx context.Context, key string) (int64, error) {
+	count, _ := strconv.ParseInt(string(s[key]), 10, 64)
+	count++
+	s[key] = []byte(strconv.FormatInt(count, 10))
+	return count, nil
+}
+
+func newCiphers(t *testing.T) (previous crypto.Cipher, active crypto.Cipher) {
+	previous, err := crypto.NewKeyRingCipher(crypto.VersionAES256GCM, crypto.KeyRing{
+		ActiveKeyID: "1",
+		Secrets:     map[string]string{"1": previousSecret},
+	}, "", "")
+	assert.NoError(t, err)
+	active, err = crypto.NewKeyRingCipher(crypto.VersionAES256GCM, crypto.KeyRing{
+		ActiveKeyID: "2",
+		Secrets:     map[string]string{"1": previousSecret, "2": activeSecret},
+	}, "", "")
+	assert.NoError(t, err)
+
+	return
+}
+
+func TestJob_Reencrypt(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	previous, active := newCiphers(t)
+	rotated, _ := previous.Encrypt(context.Background(), "secret-1")
+	current, _ := active.Encrypt(context.Background(), "secret-2")
+	changed, _ := previous.Encrypt(context.Background(), "secret-3")
+
+	checkpoint := memorySession{}
+	checkpointKey := fmt.Sprintf(reencryption.CheckpointKeyFormat, "account", "totpSecret")
+
+	dbMock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM account WHERE totpSecret IS NOT NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	dbMock.ExpectQuery("SELECT id, totpSecret FROM account WHERE id > \\? AND totpSecret IS NOT NULL ORDER BY id ASC LIMIT \\?").
+		WithArgs(int64(0), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "totpSecret"}).AddRow(1, rotated).AddRow(2, current))
+	dbMock.ExpectExec("UPDATE account SET totpSecret = \\? WHERE id = \\? AND totpSecret = \\?").
+		WithArgs(sqlmock.AnyArg(), int64(1), rotated).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectQuery("SELECT id, totpSecret FROM account").
+		WithArgs(int64(2), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "totpSecret"}).AddRow(3, changed))
+	// the value was changed meanwhile, it is left to the one who changed it.
+	dbMock.ExpectExec("UPDATE account SET totpSecret").
+		WithArgs(sqlmock.AnyArg(), int64(3), changed).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	var reported []reencryption.Progress
+	job := reencryption.NewJob(db, active, checkpoint, 2, func(progress reencryption.Progress) {
+		reported = append(reported, progress)
+	}, reencryption.Column{Table: "account", Key: "id", Name: "totpSecret"})
+
+	progress, err := job.Reencrypt(context.Background())
+
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+	assert.Equal(t, []reencryption.Progress{
+		{Column: "account.totpSecret", Total: 3, Scanned: 3, Rewritten: 1, LastID: 3, Done: true},
+	}, progress)
+	assert.Equal(t, []reencryption.Progress{
+		{Column: "account.totpSecret", Total: 3, Scanned: 2, Rewritten: 1, LastID: 2},
+		{Column: "account.totpSecret", Total: 3, Scanned: 3, Rewritten: 1, LastID: 3, Done: true},
+	}, reported)
+	assert.NotContains(t, checkpoint, checkpointKey)
+}
+
+func TestJob_Reencrypt_ResumesAfterCheckpoint(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, active := newCiphers(t)
+	checkpoint := memorySession{fmt.Sprintf(reencryption.CheckpointKeyFormat, "account", "totpSecret"): []byte("40")}
+
+	dbMock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(41))
+	dbMock.ExpectQuery("SELECT id, totpSecret FROM account").
+		WithArgs(int64(40), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "totpSecret"}).AddRow(41, "v1:1:broken"))
+
+	job := reencryption.NewJob(db, active, checkpoint, 10, func(reencryption.Progress) {}, reencryption.Column{Table: "account", Key: "id", Name: "totpSecret"})
+
+	progress, err := job.Reencrypt(context.Background())
+
+	assert.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+	assert.Equal(t, []reencryption.Progress{
+		{Column: "account.totpSecret", Total: 41, Scanned: 1, Failed: 1, LastID: 41, Done: true},
+	}, progress)
+}
+
+func TestJob_Reencrypt_Interrupted(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	previous, active := newCiphers(t)
+	rotated, _ := previous.Encrypt(context.Background(), "secret")
+	checkpoint := memorySession{}
+	checkpointKey := fmt.Sprintf(reencryption.CheckpointKeyFormat, "account", "totpSecret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dbMock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	dbMock.ExpectQuery("SELECT id, totpSecret FROM account").
+		WithArgs(int64(0), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "totpSecret"}).AddRow(7, rotated))
+	dbMock.ExpectExec("UPDATE account SET totpSecret").
+		WithArgs(sqlmock.AnyArg(), int64(7), rotated).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	job := reencryption.NewJob(db, active, checkpoint, 1, func(reencryption.Progress) {
+		cancel()
+	}, reencryption.Column{Table: "account", Key: "id", Name: "totpSecret"})
+
+	_, err = job.Reencrypt(ctx)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+	assert.Equal(t, []byte("7"), checkpoint[checkpointKey])
+}
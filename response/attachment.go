@@ -0,0 +1,34 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type attachmentImpl struct {
+	filename    string
+	contentType string
+	content     []byte
+}
+
+// Attachment is a successful response downloaded as a file instead of the usual json envelope.
+func Attachment(filename string, contentType string, content []byte) (resp Response) {
+	return &attachmentImpl{
+		filename:    filename,
+		contentType: contentType,
+		content:     content,
+	}
+}
+
+func (r *attachmentImpl) Err() (err error) {
+	return nil
+}
+
+// JSON writes the attachment as it is, the name is kept so handlers treat every response the same way.
+func (r *attachmentImpl) JSON(w http.ResponseWriter) (err error) {
+	w.Header().Set("Content-Type", r.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", r.filename))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(r.content)
+	return
+}
@@ -2,7 +2,10 @@ package response
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type Response interface {
@@ -11,9 +14,17 @@ type Response interface {
 }
 
 type responseImpl struct {
-	err    error
-	Status string      `json:"status"`
-	Data   interface{} `json:"data"`
+	err        error
+	retryAfter time.Duration
+	Status     string      `json:"status"`
+	Data       interface{} `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination is a cursor based pagination metadata.
+type Pagination struct {
+	NextCursor string `json:"nextCursor"`
+	HasMore    bool   `json:"hasMore"`
 }
 
 func Success(status string, data interface{}) (resp Response) {
@@ -24,6 +35,15 @@ func Success(status string, data interface{}) (resp Response) {
 	}
 }
 
+func SuccessWithPagination(status string, data interface{}, pagination Pagination) (resp Response) {
+	return &responseImpl{
+		err:        nil,
+		Status:     status,
+		Data:       data,
+		Pagination: &pagination,
+	}
+}
+
 func Error(status string, data interface{}, err error) (resp Response) {
 	return &responseImpl{
 		err:    err,
@@ -32,6 +52,16 @@ func Error(status string, data interface{}, err error) (resp Response) {
 	}
 }
 
+// ErrorWithRetryAfter is an error response telling the client how long to wait before trying again.
+func ErrorWithRetryAfter(status string, data interface{}, err error, retryAfter time.Duration) (resp Response) {
+	return &responseImpl{
+		err:        err,
+		retryAfter: retryAfter,
+		Status:     status,
+		Data:       data,
+	}
+}
+
 func (r *responseImpl) getStatusCode(status string) (statusCode int) {
 	switch status {
 	case StatusOK:
@@ -42,6 +72,12 @@ func (r *responseImpl) getStatusCode(status string) (statusCode int) {
 		return http.StatusConflict
 	case StatusForbiddend:
 		return http.StatusForbidden
+	case StatusNotFound:
+		return http.StatusNotFound
+	case StatusUnauthorized:
+		return http.StatusUnauthorized
+	case StatusTooManyRequests:
+		return http.StatusTooManyRequests
 	case StatusUnprocessabelEntity:
 		return http.StatusUnprocessableEntity
 	case StatusInvalidPayload:
@@ -60,6 +96,9 @@ func (r *responseImpl) Err() (err error) {
 func (r *responseImpl) JSON(w http.ResponseWriter) (err error) {
 	statusCode := r.getStatusCode(r.Status)
 	w.Header().Set("Content-Type", "application/json")
+	if r.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(r.retryAfter.Seconds())), 10))
+	}
 	w.WriteHeader(statusCode)
 	return json.NewEncoder(w).Encode(r)
 }
@@ -10,4 +10,5 @@ const (
 	StatusInvalidPayload      = "INVALID_PAYLOAD"
 	StatusUnprocessabelEntity = "UNPROCESSABLE_ENTITY"
 	StatusUnauthorized        = "Unauthorized"
+	StatusTooManyRequests     = "TOO_MANY_REQUESTS"
 )
@@ -9,7 +9,47 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-const ()
+// compareAndSwapScript replaces the value only when it still is the expected one, keeping its time to live.
+const compareAndSwapScript = `
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ttl)
+else
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1
+`
+
+// consumeScript returns the value and deletes it, so only one caller ever gets it.
+const consumeScript = `
+local value = redis.call("GET", KEYS[1])
+if value then
+	redis.call("DEL", KEYS[1])
+end
+return value
+`
+
+// incrementScript counts up the key, the first count starts its time to live.
+const incrementScript = `
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`
+
+// setIfGreaterScript stores the number only when the key is missing or holds a smaller one.
+const setIfGreaterScript = `
+local current = redis.call("GET", KEYS[1])
+if current and tonumber(current) >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`
 
 // RedisSessionStoreAdapter is a concrete struct of redis session store adapter.
 type RedisSessionStoreAdapter struct {
@@ -115,3 +155,56 @@ func (s RedisSessionStoreAdapter) Delete(ctx context.Context, key string) (err e
 
 	return
 }
+
+// CompareAndSwap will update the session only if it still holds the old value, in a single step.
+// A session that was changed or removed meanwhile is left as it is and reported as not swapped.
+func (s RedisSessionStoreAdapter) CompareAndSwap(ctx context.Context, key string, old []byte, new []byte) (swapped bool, err error) {
+	result, err := s.c.Eval(ctx, compareAndSwapScript, []string{key}, old, new).Int()
+	if err != nil {
+		s.logger.Error(err)
+		return false, ErrUnexpected
+	}
+
+	return result == 1, nil
+}
+
+// Consume will get the session and delete it in a single step, which makes a token stored as session single-use:
+// of concurrent callers with the same key only one gets the value, the others get ErrSessionNotFound.
+func (s RedisSessionStoreAdapter) Consume(ctx context.Context, key string) (value []byte, err error) {
+	result, err := s.c.Eval(ctx, consumeScript, []string{key}).Text()
+	if err != nil {
+		if err == rv8.Nil {
+			return nil, ErrSessionNotFound
+		}
+
+		s.logger.Error(err)
+		return nil, ErrUnexpected
+	}
+
+	return []byte(result), nil
+}
+
+// Increment will count up the session in a single step and return the new count. The session expires
+// after the max age from its first count on, later counts do not extend it.
+func (s RedisSessionStoreAdapter) Increment(ctx context.Context, key string) (count int64, err error) {
+	count, err = s.c.Eval(ctx, incrementScript, []string{key}, s.maxAge.Milliseconds()).Int64()
+	if err != nil {
+		s.logger.Error(err)
+		return 0, ErrUnexpected
+	}
+
+	return
+}
+
+// SetIfGreater will store the number in a single step when the session is missing or holds a smaller one, which
+// makes a sequence stored as session only ever move forward. Of concurrent callers with the same number only one
+// gets it set. The session expires after the max age.
+func (s RedisSessionStoreAdapter) SetIfGreater(ctx context.Context, key string, value int64) (set bool, err error) {
+	result, err := s.c.Eval(ctx, setIfGreaterScript, []string{key}, value, s.maxAge.Milliseconds()).Int()
+	if err != nil {
+		s.logger.Error(err)
+		return false, ErrUnexpected
+	}
+
+	return result == 1, nil
+}
@@ -110,3 +110,101 @@ func TestRedisSessionAdapter_Update_Success(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// anyScript matches an eval by its keys and arguments, leaving the script text to the adapter.
+func anyScript(expected, actual []interface{}) error {
+	for i := range expected {
+		if i == 1 {
+			continue
+		}
+		if fmt.Sprint(expected[i]) != fmt.Sprint(actual[i]) {
+			return fmt.Errorf("eval argument %d: expected %v, got %v", i, expected[i], actual[i])
+		}
+	}
+	return nil
+}
+
+func TestRedisSessionAdapter_CompareAndSwap(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	mock.CustomMatch(anyScript).ExpectEval("", []string{"test"}, []byte("old"), []byte("new")).SetVal(int64(1))
+	mock.CustomMatch(anyScript).ExpectEval("", []string{"test"}, []byte("old"), []byte("new")).SetVal(int64(0))
+
+	sess := session.NewRedisSessionStoreAdapter(rdb, time.Second*5)
+
+	swapped, err := sess.CompareAndSwap(context.TODO(), "test", []byte("old"), []byte("new"))
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+
+	// the first swap already replaced the old value.
+	swapped, err = sess.CompareAndSwap(context.TODO(), "test", []byte("old"), []byte("new"))
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisSessionAdapter_Consume(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	mock.CustomMatch(anyScript).ExpectEval("", []string{"test"}).SetVal("test data")
+	mock.CustomMatch(anyScript).ExpectEval("", []string{"test"}).RedisNil()
+
+	sess := session.NewRedisSessionStoreAdapter(rdb, time.Second*5)
+
+	data, err := sess.Consume(context.TODO(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "test data", string(data))
+
+	// a concurrent caller with the same key gets nothing.
+	_, err = sess.Consume(context.TODO(), "test")
+	assert.Equal(t, session.ErrSessionNotFound, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisSessionAdapter_Increment(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	mock.CustomMatch(anyScript).ExpectEval("", []string{"test"}, int64(5000)).SetVal(int64(1))
+	mock.CustomMatch(anyScript).ExpectEval("", []string{"test"}, int64(5000)).SetErr(fmt.Errorf("unexpected"))
+
+	sess := session.NewRedisSessionStoreAdapter(rdb, time.Second*5)
+
+	count, err := sess.Increment(context.TODO(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	_, err = sess.Increment(context.TODO(), "test")
+	assert.Equal(t, session.ErrUnexpected, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisSessionAdapter_SetIfGreater(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	mock.CustomMatch(anyScript).ExpectEval("", []string{"test"}, int64(7), int64(5000)).SetVal(int64(1))
+	mock.CustomMatch(anyScript).ExpectEval("", []string{"test"}, int64(7), int64(5000)).SetVal(int64(0))
+	mock.CustomMatch(anyScript).ExpectEval("", []string{"test"}, int64(8), int64(5000)).SetErr(fmt.Errorf("unexpected"))
+
+	sess := session.NewRedisSessionStoreAdapter(rdb, time.Second*5)
+
+	set, err := sess.SetIfGreater(context.TODO(), "test", 7)
+	assert.NoError(t, err)
+	assert.True(t, set)
+
+	// a concurrent caller with the same number is refused.
+	set, err = sess.SetIfGreater(context.TODO(), "test", 7)
+	assert.NoError(t, err)
+	assert.False(t, set)
+
+	_, err = sess.SetIfGreater(context.TODO(), "test", 8)
+	assert.Equal(t, session.ErrUnexpected, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -17,4 +17,8 @@ type Session interface {
 	Get(ctx context.Context, key string) (value []byte, err error)
 	Update(ctx context.Context, key string, value []byte) (err error)
 	Delete(ctx context.Context, key string) (err error)
+	CompareAndSwap(ctx context.Context, key string, old []byte, new []byte) (swapped bool, err error)
+	Consume(ctx context.Context, key string) (value []byte, err error)
+	Increment(ctx context.Context, key string) (count int64, err error)
+	SetIfGreater(ctx context.Context, key string, value int64) (set bool, err error)
 }
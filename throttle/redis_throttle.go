@@ -0,0 +1,125 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	rv8 "github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// Key formats of the failure counter and of the block of a subject.
+const (
+	FailuresKeyFormat = "throttle:%s:failures:%s"
+	BlockedKeyFormat  = "throttle:%s:blocked:%s"
+)
+
+// attemptScript refuses a blocked subject, otherwise it counts the attempt as failed, slides the window
+// and blocks the subject for the delay of its failures, all in a single step. ARGV holds the window and
+// then the schedule of the policy.
+const attemptScript = `
+local blocked = redis.call("PTTL", KEYS[2])
+if blocked > 0 then
+	return {1, tonumber(redis.call("GET", KEYS[1]) or "0"), blocked}
+end
+
+local failures = redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[1])
+
+local delay = tonumber(ARGV[1 + math.min(failures, #ARGV - 1)])
+if delay > 0 then
+	redis.call("SET", KEYS[2], failures, "PX", delay)
+end
+return {0, failures, delay}
+`
+
+// succeedScript takes a counted attempt back and lifts the block it caused, a block of a later attempt stays.
+const succeedScript = `
+local failures = tonumber(redis.call("GET", KEYS[1]) or "0")
+if failures > 0 then
+	redis.call("DECR", KEYS[1])
+end
+if redis.call("GET", KEYS[2]) == ARGV[1] then
+	redis.call("DEL", KEYS[2])
+end
+return failures
+`
+
+// RedisThrottle is a concrete struct of redis backed throttle, its counters are shared by every instance of the service.
+type RedisThrottle struct {
+	logger *logrus.Logger
+	name   string
+	policy Policy
+	args   []interface{}
+	c      rv8.UniversalClient
+}
+
+// NewRedisThrottle is a constructor, the name separates the counters of different throttles.
+func NewRedisThrottle(rdb rv8.UniversalClient, name string, policy Policy) Throttle {
+	return RedisThrottle{
+		logger: logrus.New(),
+		name:   name,
+		policy: policy,
+		args:   append([]interface{}{policy.Window.Milliseconds()}, policy.schedule()...),
+		c:      rdb,
+	}
+}
+
+// Attempt counts an attempt of the subject as failed and blocks it for the delay of the policy,
+// unless the subject is still blocked, then the attempt is refused without being counted.
+func (t RedisThrottle) Attempt(ctx context.Context, subject string) (result Result, err error) {
+	keys := []string{fmt.Sprintf(FailuresKeyFormat, t.name, subject), fmt.Sprintf(BlockedKeyFormat, t.name, subject)}
+	values, err := t.c.Eval(ctx, attemptScript, keys, t.args...).Slice()
+	if err != nil {
+		t.logger.Error(err)
+		return result, ErrUnexpected
+	}
+
+	var replies [3]int64
+	for i := range replies {
+		if i >= len(values) {
+			return result, ErrUnexpected
+		}
+		reply, ok := values[i].(int64)
+		if !ok {
+			return result, ErrUnexpected
+		}
+		replies[i] = reply
+	}
+
+	result.Refused = replies[0] == 1
+	result.Failures = replies[1]
+	result.RetryAfter = time.Duration(replies[2]) * time.Millisecond
+	if !result.Refused {
+		_, result.Locked = t.policy.delay(result.Failures)
+	}
+
+	return
+}
+
+// Succeed takes back an attempt that turned out right, the failures before it are kept.
+func (t RedisThrottle) Succeed(ctx context.Context, subject string, attempt Result) (err error) {
+	if attempt.Refused {
+		return
+	}
+
+	keys := []string{fmt.Sprintf(FailuresKeyFormat, t.name, subject), fmt.Sprintf(BlockedKeyFormat, t.name, subject)}
+	if err = t.c.Eval(ctx, succeedScript, keys, attempt.Failures).Err(); err != nil {
+		t.logger.Error(err)
+		return ErrUnexpected
+	}
+
+	return
+}
+
+// Reset forgets the failures and lifts the block of the subject.
+func (t RedisThrottle) Reset(ctx context.Context, subject string) (err error) {
+	err = t.c.Del(ctx, fmt.Sprintf(FailuresKeyFormat, t.name, subject), fmt.Sprintf(BlockedKeyFormat, t.name, subject)).Err()
+	if err != nil {
+		t.logger.Error(err)
+		return ErrUnexpected
+	}
+
+	return
+}
@@ -0,0 +1,158 @@
+package throttle_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/throttle"
+
+	redismock "github.com/go-redis/redismock/v8"
+)
+
+var policy = throttle.Policy{
+	FreeAttempts:     3,
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Second * 30,
+	LockoutThreshold: 10,
+	LockoutDuration:  time.Minute * 15,
+	Window:           time.Minute * 15,
+}
+
+// anyScript matches an eval by its keys and arguments, leaving the script text to the throttle.
+func anyScript(expected, actual []interface{}) error {
+	for i := range expected {
+		if i == 1 {
+			continue
+		}
+		if fmt.Sprint(expected[i]) != fmt.Sprint(actual[i]) {
+			return fmt.Errorf("eval argument %d: expected %v, got %v", i, expected[i], actual[i])
+		}
+	}
+	return nil
+}
+
+// expectAttempt expects an attempt of the subject with the window and the schedule of the policy.
+func expectAttempt(mock redismock.ClientMock, subject string) *redismock.ExpectedCmd {
+	keys := []string{"throttle:login:failures:" + subject, "throttle:login:blocked:" + subject}
+	// three free attempts, doubling from a second up to thirty, locked out at the tenth.
+	return mock.CustomMatch(anyScript).ExpectEval("", keys, int64(900000), int64(0), int64(0), int64(0), int64(1000), int64(2000), int64(4000), int64(8000), int64(16000), int64(30000), int64(900000))
+}
+
+func TestRedisThrottle_Attempt_FreeAttempt(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	expectAttempt(mock, "johndoe@mail.com").SetVal([]interface{}{int64(0), int64(2), int64(0)})
+
+	th := throttle.NewRedisThrottle(rdb, "login", policy)
+	result, err := th.Attempt(context.TODO(), "johndoe@mail.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, throttle.Result{Failures: 2}, result)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisThrottle_Attempt_BackOff(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	expectAttempt(mock, "johndoe@mail.com").SetVal([]interface{}{int64(0), int64(6), int64(4000)})
+
+	th := throttle.NewRedisThrottle(rdb, "login", policy)
+	result, err := th.Attempt(context.TODO(), "johndoe@mail.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, throttle.Result{Failures: 6, RetryAfter: time.Second * 4}, result)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisThrottle_Attempt_Lockout(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	expectAttempt(mock, "johndoe@mail.com").SetVal([]interface{}{int64(0), int64(10), int64(900000)})
+
+	th := throttle.NewRedisThrottle(rdb, "login", policy)
+	result, err := th.Attempt(context.TODO(), "johndoe@mail.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, throttle.Result{Failures: 10, RetryAfter: policy.LockoutDuration, Locked: true}, result)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisThrottle_Attempt_Refused(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	expectAttempt(mock, "johndoe@mail.com").SetVal([]interface{}{int64(1), int64(10), int64(3000)})
+	expectAttempt(mock, "10.0.0.1").SetErr(fmt.Errorf("unexpected"))
+
+	th := throttle.NewRedisThrottle(rdb, "login", policy)
+
+	result, err := th.Attempt(context.TODO(), "johndoe@mail.com")
+	assert.NoError(t, err)
+	assert.Equal(t, throttle.Result{Failures: 10, RetryAfter: time.Second * 3, Refused: true}, result)
+
+	_, err = th.Attempt(context.TODO(), "10.0.0.1")
+	assert.Equal(t, throttle.ErrUnexpected, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisThrottle_Attempt_UncappedSchedule(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	uncapped := throttle.Policy{FreeAttempts: 1, BaseDelay: time.Second, MaxDelay: time.Second * 3, Window: time.Minute}
+	keys := []string{"throttle:login:failures:10.0.0.1", "throttle:login:blocked:10.0.0.1"}
+	// the schedule ends at the first capped delay, any further failure waits as long.
+	mock.CustomMatch(anyScript).ExpectEval("", keys, int64(60000), int64(0), int64(1000), int64(2000), int64(3000)).SetVal([]interface{}{int64(0), int64(7), int64(3000)})
+
+	th := throttle.NewRedisThrottle(rdb, "login", uncapped)
+	result, err := th.Attempt(context.TODO(), "10.0.0.1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, throttle.Result{Failures: 7, RetryAfter: time.Second * 3}, result)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisThrottle_Succeed(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	keys := []string{"throttle:login:failures:johndoe@mail.com", "throttle:login:blocked:johndoe@mail.com"}
+	mock.CustomMatch(anyScript).ExpectEval("", keys, int64(6)).SetVal(int64(6))
+
+	th := throttle.NewRedisThrottle(rdb, "login", policy)
+
+	err := th.Succeed(context.TODO(), "johndoe@mail.com", throttle.Result{Failures: 6, RetryAfter: time.Second * 4})
+	assert.NoError(t, err)
+
+	// a refused attempt was never counted.
+	err = th.Succeed(context.TODO(), "johndoe@mail.com", throttle.Result{Failures: 6, Refused: true})
+	assert.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisThrottle_Reset(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	mock.ExpectDel("throttle:login:failures:johndoe@mail.com", "throttle:login:blocked:johndoe@mail.com").SetVal(2)
+
+	th := throttle.NewRedisThrottle(rdb, "login", policy)
+	err := th.Reset(context.TODO(), "johndoe@mail.com")
+
+	assert.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,78 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Errors
+var (
+	ErrUnexpected = fmt.Errorf("unexpected throttle error")
+)
+
+// Policy is a collection of rules of failed attempts. The first FreeAttempts failures are not delayed,
+// every further one doubles the delay from BaseDelay up to MaxDelay, and reaching LockoutThreshold
+// locks the subject out for LockoutDuration. Failures are forgotten Window after the last one.
+type Policy struct {
+	FreeAttempts     int64
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int64
+	LockoutDuration  time.Duration
+	Window           time.Duration
+}
+
+// Result is the outcome of an attempt. A refused attempt is not counted, the subject still has to wait RetryAfter.
+type Result struct {
+	Failures   int64
+	RetryAfter time.Duration
+	Locked     bool
+	Refused    bool
+}
+
+// Throttle is collection of behavior of failed attempt throttling, a subject is whatever is being
+// throttled, for instance an email or a client address. An attempt is counted as failed before it is
+// verified, so concurrent attempts cannot all slip through, and one that turns out right is taken back.
+type Throttle interface {
+	Attempt(ctx context.Context, subject string) (result Result, err error)
+	Succeed(ctx context.Context, subject string, attempt Result) (err error)
+	Reset(ctx context.Context, subject string) (err error)
+}
+
+// delay returns how long the subject must wait after the given number of failures.
+func (p Policy) delay(failures int64) (retryAfter time.Duration, locked bool) {
+	if p.LockoutThreshold > 0 && failures >= p.LockoutThreshold {
+		return p.LockoutDuration, true
+	}
+	if failures <= p.FreeAttempts {
+		return 0, false
+	}
+
+	retryAfter = p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && (p.MaxDelay <= 0 || retryAfter < p.MaxDelay); i++ {
+		retryAfter *= 2
+	}
+	if p.MaxDelay > 0 && retryAfter > p.MaxDelay {
+		retryAfter = p.MaxDelay
+	}
+
+	return retryAfter, false
+}
+
+// maxScheduleLength bounds the schedule of a policy that neither caps its delay nor locks out.
+const maxScheduleLength = 32
+
+// schedule lists the delay after every number of failures in milliseconds, from one failure on,
+// until the delay stops changing. Any further failure waits as long as the last one.
+func (p Policy) schedule() (delays []interface{}) {
+	for failures := int64(1); ; failures++ {
+		retryAfter, locked := p.delay(failures)
+		delays = append(delays, retryAfter.Milliseconds())
+
+		capped := p.LockoutThreshold <= 0 && p.MaxDelay > 0 && retryAfter >= p.MaxDelay
+		if locked || capped || len(delays) == maxScheduleLength {
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Parameters of the generated codes, the defaults of RFC 6238 that every authenticator app supports.
+const (
+	Digits     = 6
+	Period     = 30 * time.Second
+	SecretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Errors of totp.
+var (
+	ErrInvalidSecret = fmt.Errorf("invalid totp secret")
+	ErrInvalidCode   = fmt.Errorf("invalid totp code")
+)
+
+// GenerateSecret returns a random base32 encoded secret.
+func GenerateSecret() (secret string, err error) {
+	b := make([]byte, SecretSize)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// URI returns the otpauth:// key URI of the secret, usually rendered as a QR code.
+func URI(issuer string, accountName string, secret string) string {
+	label := url.PathEscape(accountName)
+	if issuer != "" {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	if issuer != "" {
+		query.Set("issuer", issuer)
+	}
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprintf("%d", Digits))
+	query.Set("period", fmt.Sprintf("%d", int64(Period/time.Second)))
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, query.Encode())
+}
+
+// Code returns the code of the secret at the given time.
+func Code(secret string, t time.Time) (code string, err error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return
+	}
+
+	return generate(key, Step(t)), nil
+}
+
+// Step returns the time step counter of the given time.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Validate checks the code against the steps around the given time, skew steps each way to tolerate
+// clock drift, and returns the matching step so the caller can refuse to accept it twice.
+func Validate(secret string, code string, t time.Time, skew int64) (step int64, err error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, ErrInvalidCode
+	}
+
+	current := Step(t)
+	for i := -skew; i <= skew; i++ {
+		if subtle.ConstantTimeCompare([]byte(generate(key, current+i)), []byte(code)) == 1 {
+			return current + i, nil
+		}
+	}
+
+	return 0, ErrInvalidCode
+}
+
+func decodeSecret(secret string) (key []byte, err error) {
+	key, err = encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+
+	return
+}
+
+// generate is the HOTP value of RFC 4226 at the given counter.
+func generate(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%modulo)
+}
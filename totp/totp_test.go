@@ -0,0 +1,56 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sangianpatrick/devoria-article-service/totp"
+)
+
+// rfc6238Secret is the SHA1 seed of the RFC 6238 test vectors.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := totp.Code(rfc6238Secret, time.Unix(unix, 0))
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	now := time.Unix(1111111111, 0)
+	previous, _ := totp.Code(secret, now.Add(-totp.Period))
+
+	step, err := totp.Validate(secret, previous, now, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, totp.Step(now)-1, step)
+
+	_, err = totp.Validate(secret, previous, now, 0)
+	assert.Equal(t, totp.ErrInvalidCode, err)
+
+	_, err = totp.Validate("not base32!", previous, now, 1)
+	assert.Equal(t, totp.ErrInvalidSecret, err)
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("Devoria", "johndoe@mail.com", "JBSWY3DPEHPK3PXP")
+
+	assert.Equal(t, "otpauth://totp/Devoria:johndoe@mail.com?algorithm=SHA1&digits=6&issuer=Devoria&period=30&secret=JBSWY3DPEHPK3PXP", uri)
+}